@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/alert"
+	"github.com/kyleishie/logfind/pkg/logfind/exporter"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// alertSummary is shown in the usage of lf and lf alert.
+const alertSummary = "Follow logs and notify when alert rules start or stop firing."
+
+var alertCommand = &command{
+	name:    "alert",
+	summary: alertSummary,
+	run:     runAlert,
+}
+
+func runAlert(args []string) error {
+	fs := newFlagSet("alert", "filepath...", alertSummary)
+	var in inputFlags
+	in.register(fs)
+	rulesPath := fs.String("rules", "", "The JSON file of alert rules.")
+	webhook := fs.String("webhook", "", "The URL to post alerts to as JSON.")
+	output := fs.String("output", "", "The file to append alerts to as JSON lines. Alerts are written to stdout unless --output or --webhook is given.")
+	fs.StringVar(output, "o", "", "Shorthand for --output.")
+	follow := fs.Bool("follow", true, "Keep reading the logs as they grow. With --follow=false the logs are read once.")
+	poll := fs.Duration("poll", time.Second, "How often to check the logs for new events and resolve quiet rules.")
+	onError := fs.String("on-error", string(logfind.Strict), "Changes how malformed records are handled. Values are strict, skip, skip-and-report.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *rulesPath == "" {
+		return usageError{err: errors.New("missing --rules")}
+	}
+	if len(paths) == 0 {
+		return usageError{err: errors.New("missing filepath")}
+	}
+
+	rulesFile, err := os.Open(*rulesPath)
+	if err != nil {
+		return err
+	}
+	rules, err := alert.ParseRules(rulesFile)
+	rulesFile.Close()
+	if err != nil {
+		return usageError{err: fmt.Errorf("%s: %w", *rulesPath, err)}
+	}
+
+	var notifiers []alert.Notifier
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		notifiers = append(notifiers, alert.NewWriterNotifier(f))
+	}
+	if *webhook != "" {
+		notifiers = append(notifiers, alert.NewWebhookNotifier(*webhook))
+	}
+	if len(notifiers) == 0 {
+		notifiers = append(notifiers, alert.NewWriterNotifier(os.Stdout))
+	}
+	// A failed delivery is reported without stopping the evaluation of later events.
+	multi := alert.MultiNotifier(notifiers...)
+	notifier := alert.NotifierFunc(func(a alert.Alert) error {
+		if err := multi.Notify(a); err != nil {
+			fmt.Fprintf(os.Stderr, "lf: %s\n", err)
+		}
+		return nil
+	})
+
+	engine, err := alert.NewEngine(rules, notifier)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(&query.Query{OnError: *onError}, &diagnostics)
+	if err != nil {
+		return err
+	}
+	if _, err = logfind.NewFilterReader(nil, opts...); err != nil {
+		return usageError{err: err}
+	}
+
+	if !*follow {
+		r, closeAll, err := in.open(paths)
+		if err != nil {
+			return err
+		}
+		defer closeAll()
+		fr, _ := logfind.NewFilterReader(r, opts...)
+		if err = engine.Run(fr); err != nil {
+			return err
+		}
+		reportDiagnostics(&diagnostics)
+		return nil
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Each file is read by its own goroutine, so each has its own Diagnostics, which only counts the skipped records
+	// since the logs are followed indefinitely.
+	fileDiagnostics := make([]*logfind.Diagnostics, len(files))
+	var wg sync.WaitGroup
+	failed := make(chan error, len(files))
+	for i, f := range files {
+		r, err := in.newReader(exporter.Follow(ctx, f, *poll))
+		if err != nil {
+			return err
+		}
+		fileDiagnostics[i] = &logfind.Diagnostics{CountOnly: true}
+		fileOpts, _ := queryOptions(&query.Query{OnError: *onError}, fileDiagnostics[i])
+		fr, _ := logfind.NewFilterReader(r, fileOpts...)
+		wg.Add(1)
+		go func(name string, r reader.Reader) {
+			defer wg.Done()
+			if err := engine.Run(r); err != nil {
+				failed <- fmt.Errorf("%s: %w", name, err)
+			}
+		}(f.Name(), fr)
+	}
+
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The followed logs end once ctx is done, after which the Diagnostics are no longer written.
+			wg.Wait()
+			for i, d := range fileDiagnostics {
+				if d.Skipped > 0 {
+					fmt.Fprintf(os.Stderr, "%s: ", files[i].Name())
+				}
+				reportDiagnostics(d)
+			}
+			return nil
+		case err = <-failed:
+			return err
+		case <-ticker.C:
+			engine.Tick()
+		}
+	}
+}
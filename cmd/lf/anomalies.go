@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/stats"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// anomaliesSummary is shown in the usage of lf and lf anomalies.
+const anomaliesSummary = "Report users whose activity in a time bucket departs from their usual activity."
+
+var anomaliesCommand = &command{
+	name:    "anomalies",
+	summary: anomaliesSummary,
+	run:     runAnomalies,
+}
+
+func runAnomalies(args []string) error {
+	fs := newFlagSet("anomalies", "filepath...", anomaliesSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	bucketFlag := fs.String("bucket", "1h", "The time buckets over which the activity of each user is totalled, e.g., 1h or 1d.")
+	threshold := fs.Float64("threshold", 4, "The score, in robust standard deviations above a user's median, at which a bucket is reported.")
+	minHistory := fs.Int("min-history", 5, "The number of active buckets a user needs before their activity is scored.")
+	window := fs.Int("window", 0, "Limits the baseline to the latest n active buckets of a user. 0 uses every earlier bucket.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	bucket, err := query.ParseDuration(*bucketFlag)
+	if err != nil {
+		return usageError{err: fmt.Errorf("invalid bucket %q", *bucketFlag)}
+	}
+	loc, err := time.LoadLocation(q.TZ)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+	anomalies, err := stats.Anomalies(fr,
+		stats.WithBucket(bucket),
+		stats.WithThreshold(*threshold),
+		stats.WithMinHistory(*minHistory),
+		stats.WithWindow(*window),
+		stats.WithLocation(loc),
+	)
+	if errors.Is(err, stats.ErrBucketInvalid) || errors.Is(err, stats.ErrThresholdInvalid) {
+		return usageError{err: err}
+	}
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "bucket\tuser\tscore\tanomaly\n")
+	for _, a := range anomalies {
+		score := "-"
+		if a.Metric != stats.MetricHour {
+			score = fmt.Sprintf("%.1f", a.Score)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", a.Bucket.Format(time.RFC3339), a.User, score, a)
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+)
+
+// convertSummary is shown in the usage of lf and lf convert.
+const convertSummary = "Rewrite the events of a log in another format."
+
+var convertCommand = &command{
+	name:    "convert",
+	summary: convertSummary,
+	run:     runConvert,
+}
+
+func runConvert(args []string) error {
+	fs := newFlagSet("convert", "filepath...", convertSummary)
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs)
+	fs.StringVar(&out.format, "to", out.format, "Alias of --format.")
+	onError := fs.String("on-error", string(logfind.Strict), "Changes how malformed records are handled. Values are strict, skip, skip-and-report.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics logfind.Diagnostics
+	q := &query.Query{OnError: *onError}
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	// Converting is finding without a filter, which keeps the handling of malformed records consistent.
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	if err = out.copy(fr); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
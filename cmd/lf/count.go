@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+)
+
+// countSummary is shown in the usage of lf and lf count.
+const countSummary = "Count the events that match a query."
+
+var countCommand = &command{
+	name:    "count",
+	summary: countSummary,
+	run:     runCount,
+}
+
+func runCount(args []string) error {
+	fs := newFlagSet("count", "filepath...", countSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	countConcern := fs.String("count", string(logfind.Event), "Changes how lf counts events. Values are event, operation, user.")
+	fs.StringVar(countConcern, "c", string(logfind.Event), "Shorthand for --count.")
+	verbose := fs.Bool("verbose", false, "Use this flag to print more info about the matched events.")
+	fs.BoolVar(verbose, "v", false, "Shorthand for --verbose.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, logfind.WithCountConcern(logfind.CountConcern(*countConcern)))
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	count, events, err := logfind.NewFinder(r).Find(opts...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("count: %d\n", count)
+	reportDiagnostics(&diagnostics)
+
+	if *verbose {
+		for _, event := range events {
+			fmt.Println(event)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/writer"
+	"github.com/kyleishie/logfind/pkg/logfind/writer/sqlite"
+	"os"
+)
+
+// exportSummary is shown in the usage of lf and lf export.
+const exportSummary = "Export the events that match a query into a SQLite database, e.g., lf export --sqlite out.db log.csv."
+
+var exportCommand = &command{
+	name:    "export",
+	summary: exportSummary,
+	run:     runExport,
+}
+
+func runExport(args []string) error {
+	fs := newFlagSet("export", "filepath...", exportSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	db := fs.String("sqlite", "", "The SQLite database to export to. It is created if it does not exist. Requires the sqlite3 command-line tool on the PATH, see --sql otherwise.")
+	script := fs.String("sql", "", "The file to write the SQL script to instead of running it with sqlite3, - for stdout.")
+	table := fs.String("table", "events", "The table to insert the events into. It is created if it does not exist.")
+	timestamp := fs.String("timestamp", string(sqlite.TimestampISO), "How timestamps are stored. Values are iso (text), unix (integer seconds).")
+	index := fs.Bool("index", false, "Create indexes on username and timestamp.")
+	batchSize := fs.Int("batch-size", sqlite.DefaultBatchSize, "The number of events inserted per transaction.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*db == "") == (*script == "") {
+		return usageError{err: errors.New("expected exactly one of --sqlite and --sql")}
+	}
+	switch sqlite.TimestampFormat(*timestamp) {
+	case sqlite.TimestampISO, sqlite.TimestampUnix:
+	default:
+		return usageError{err: fmt.Errorf("invalid timestamp %q, expected iso or unix", *timestamp)}
+	}
+	if *batchSize <= 0 {
+		return usageError{err: fmt.Errorf("invalid batch size %d", *batchSize)}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	writerOpts := []sqlite.WriterOptionFunc{
+		sqlite.WithTable(*table),
+		sqlite.WithTimestampFormat(sqlite.TimestampFormat(*timestamp)),
+		sqlite.WithBatchSize(*batchSize),
+	}
+	if *index {
+		writerOpts = append(writerOpts, sqlite.WithIndexes())
+	}
+
+	var n int
+	if *script != "" {
+		n, err = exportScript(*script, fr, writerOpts)
+	} else {
+		n, err = sqlite.Export(*db, fr, writerOpts...)
+	}
+	if errors.Is(err, sqlite.ErrSQLite3Missing) {
+		return errors.New("--sqlite requires the sqlite3 command-line tool on the PATH, install it or use --sql to write a script to run with sqlite3 later")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d events\n", n)
+	reportDiagnostics(&diagnostics)
+	return nil
+}
+
+// exportScript writes the SQL script inserting the events of r to path.
+func exportScript(path string, r reader.Reader, opts []sqlite.WriterOptionFunc) (n int, err error) {
+	if path == "-" {
+		return writer.Copy(sqlite.NewWriter(os.Stdout, opts...), r)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return writer.Copy(sqlite.NewWriter(file, opts...), r)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/exporter"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// exporterSummary is shown in the usage of lf and lf exporter.
+const exporterSummary = "Follow logs and expose their event counts as Prometheus metrics on /metrics."
+
+var exporterCommand = &command{
+	name:    "exporter",
+	summary: exporterSummary,
+	run:     runExporter,
+}
+
+func runExporter(args []string) error {
+	fs := newFlagSet("exporter", "filepath...", exporterSummary)
+	var in inputFlags
+	in.register(fs)
+	addr := fs.String("addr", ":9100", "The address to listen on.")
+	poll := fs.Duration("poll", time.Second, "How often to check the logs for new events.")
+	maxUsernames := fs.Int("max-usernames", 0, "Also export metrics by username, for at most this many usernames. Further users are counted as \"other\". 0 disables the username metrics.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return usageError{err: errors.New("missing filepath")}
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	exp := exporter.New(exporter.WithUsernames(*maxUsernames))
+	failed := make(chan error, len(files))
+	for _, f := range files {
+		r, err := in.newReader(exporter.Follow(ctx, f, *poll))
+		if err != nil {
+			return err
+		}
+		go func(name string) {
+			if err := exp.Collect(r); err != nil {
+				failed <- fmt.Errorf("%s: %w", name, err)
+			}
+		}(f.Name())
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp)
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// stopped reports why the server was shut down: nil on an interrupt, otherwise the error of a failed collector.
+	stopped := make(chan error, 1)
+	go func() {
+		var err error
+		select {
+		case <-ctx.Done():
+		case err = <-failed:
+		}
+		srv.Shutdown(context.Background())
+		stopped <- err
+	}()
+
+	fmt.Fprintf(os.Stderr, "lf: listening on %s\n", *addr)
+	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-stopped
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/writer"
+	"github.com/kyleishie/logfind/pkg/logfind/writer/csv"
+	"github.com/kyleishie/logfind/pkg/logfind/writer/jsonl"
+	"io"
+	"os"
+)
+
+// findSummary is shown in the usage of lf and lf find.
+const findSummary = "Print the events that match a query."
+
+var findCommand = &command{
+	name:    "find",
+	summary: findSummary,
+	run:     runFind,
+}
+
+func runFind(args []string) error {
+	fs := newFlagSet("find", "filepath...", findSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs)
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	if err = out.copy(fr); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
+
+// outputFlags holds the flags that describe where and how events are written.
+type outputFlags struct {
+	format string
+	path   string
+}
+
+func (out *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&out.format, "format", "text", "The output format. Values are text, csv, jsonl.")
+	fs.StringVar(&out.format, "f", "text", "Shorthand for --format.")
+	fs.StringVar(&out.path, "output", "", "The file to write events to. Events are written to stdout by default.")
+	fs.StringVar(&out.path, "o", "", "Shorthand for --output.")
+}
+
+// copy writes every event of r to the chosen output in the chosen format.
+func (out *outputFlags) copy(r reader.Reader) (err error) {
+	w, closeFn, err := out.open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeFn(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = writer.Copy(w, r)
+	return
+}
+
+// open returns a writer.Writer for the chosen output in the chosen format. The returned func closes the output.
+func (out *outputFlags) open() (w writer.Writer, closeFn func() error, err error) {
+	newWriter, ok := writerFormats[out.format]
+	if !ok {
+		return nil, nil, usageError{err: fmt.Errorf("unknown format %q", out.format)}
+	}
+
+	if out.path == "" {
+		return newWriter(os.Stdout), func() error { return nil }, nil
+	}
+	file, err := os.Create(out.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newWriter(file), file.Close, nil
+}
+
+// writerFormats maps the values of --format to a constructor of the matching writer.Writer.
+var writerFormats = map[string]func(io.Writer) writer.Writer{
+	"text": writer.NewTextWriter,
+	"csv": func(w io.Writer) writer.Writer {
+		return csv.NewWriter(w)
+	},
+	"jsonl": jsonl.NewWriter,
+}
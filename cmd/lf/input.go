@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/config"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/access"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/jsonl"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/logfmt"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/otlp"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/syslog"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/w3c"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// autoFormat is the value of --input-format that detects the format of each log.
+const autoFormat = "auto"
+
+// inputFlags holds the flags that describe how log files are read.
+type inputFlags struct {
+	sizeUnit        string
+	format          string
+	timestampLayout string
+	columns         string
+	logFormat       string
+	operationRules  string
+	pattern         string
+	year            int
+	delimiter       string
+	comment         string
+	lazyQuotes      bool
+	encoding        string
+
+	// fs is consulted for --tz, which also sets the time zone of timestamps without one.
+	fs *flag.FlagSet
+}
+
+func (in *inputFlags) register(fs *flag.FlagSet) {
+	in.fs = fs
+	fs.StringVar(&in.sizeUnit, "size-unit", "", "The unit of sizes without one in the log, e.g., B, kB or KiB. Defaults to kB for csv and tsv, and B for the other formats.")
+	fs.StringVar(&in.format, "input-format", autoFormat, fmt.Sprintf("The format of the log. Values are %s or %s, which detects it from the file extension and content.", strings.Join(reader.Formats(), ", "), autoFormat))
+	fs.StringVar(&in.timestampLayout, "timestamp-layout", "", "The layout of the timestamps in the log, written as Go's reference time, e.g., \"2006-01-02 15:04:05\". Defaults to that of the format, e.g., Unix date for csv.")
+	fs.StringVar(&in.columns, "columns", "", "Maps event fields to the columns of a log with a header, e.g., username=user,size=bytes. Fields default to the column of the same name.")
+	fs.StringVar(&in.logFormat, "log-format", "", "The format of access logs as an nginx log_format or Apache LogFormat string, or combined or common. Lines in either are read by default.")
+	fs.StringVar(&in.operationRules, "operation-rules", "", "The rules deriving the operations of access and w3c log requests from their method and path, e.g., \"PUT,POST=upload; GET /files/=download\". The first matching rule wins, and the operation of other requests is their method. Defaults to \"PUT,POST=upload; GET=download\" for access logs, with the FTP STOR, STOU, APPE and RETR added for w3c logs.")
+	fs.StringVar(&in.pattern, "pattern", "", "A regular expression whose named groups extract fields from the text of syslog messages, e.g., '(?P<username>\\S+) (?P<operation>upload|download) (?P<size>\\d+)'.")
+	fs.IntVar(&in.year, "year", 0, "The year of the first syslog timestamp without one. Defaults to the latest year that does not put it in the future.")
+	fs.StringVar(&in.delimiter, "delimiter", "", "The field separator of csv and tsv logs, e.g., ';' or \\t. Defaults to a comma for csv and a tab for tsv.")
+	fs.StringVar(&in.comment, "comment", "", "The character starting comment lines of csv and tsv logs, e.g., #, which are skipped.")
+	fs.BoolVar(&in.lazyQuotes, "lazy-quotes", false, "Tolerates stray quotes in the fields of csv and tsv logs.")
+	fs.StringVar(&in.encoding, "encoding", "", "The text encoding of csv and tsv logs, utf-8 or latin1. Defaults to utf-8.")
+}
+
+// open opens every path, "-" meaning stdin, and returns a reader over their concatenated events.
+// The returned func closes the files.
+func (in *inputFlags) open(paths []string) (r reader.Reader, closeAll func(), err error) {
+	if len(paths) == 0 {
+		return nil, nil, usageError{err: errors.New("missing filepath")}
+	}
+
+	if _, _, err = in.options(); err != nil {
+		return nil, nil, err
+	}
+
+	var files []*os.File
+	closeAll = func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	readers := make([]reader.Reader, 0, len(paths))
+	for _, path := range paths {
+		var file *os.File
+		if path == "-" {
+			file = os.Stdin
+		} else if file, err = os.Open(path); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, file)
+		rdr, err := in.newReader(file)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		readers = append(readers, rdr)
+	}
+	return reader.MultiReader(readers...), closeAll, nil
+}
+
+// newReader returns a reader over the events of r.
+func (in *inputFlags) newReader(r io.Reader) (reader.Reader, error) {
+	formatName, opts, err := in.options()
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := reader.NewFormatReader(r, formatName, opts)
+	if err != nil {
+		return nil, usageError{err: err}
+	}
+	return undetectedHint{rdr}, nil
+}
+
+// undetectedHint suggests --input-format when the format of a log cannot be detected.
+type undetectedHint struct {
+	reader.Reader
+}
+
+func (h undetectedHint) Read() (reader.Event, error) {
+	e, err := h.Reader.Read()
+	if errors.Is(err, reader.ErrFormatUndetected) {
+		return nil, fmt.Errorf("%w, use --input-format to name it", err)
+	}
+	return e, err
+}
+
+func (h undetectedHint) Location() reader.Location {
+	if l, ok := h.Reader.(reader.Locator); ok {
+		return l.Location()
+	}
+	return reader.Location{}
+}
+
+// options validates the flags and translates them into the name of the format, empty to detect it, and its options.
+func (in *inputFlags) options() (string, reader.FormatOptions, error) {
+	var opts reader.FormatOptions
+	formatName := in.format
+	if formatName == autoFormat {
+		formatName = ""
+	} else if !contains(reader.Formats(), formatName) {
+		return "", opts, usageError{err: fmt.Errorf("unknown input format %q, expected one of %s or %s", in.format, strings.Join(reader.Formats(), ", "), autoFormat)}
+	}
+
+	sizeUnit, err := in.unit()
+	if err != nil {
+		return "", opts, err
+	}
+	opts.SizeUnit = sizeUnit
+	opts.TimestampLayout = in.timestampLayout
+	if tz := in.fs.Lookup("tz"); tz != nil && tz.Value.String() != "UTC" {
+		if opts.Location, err = time.LoadLocation(tz.Value.String()); err != nil {
+			return "", opts, usageError{err: err}
+		}
+	}
+	if in.columns != "" {
+		if opts.Columns, err = config.ParseColumns(in.columns); err != nil {
+			return "", opts, usageError{err: err}
+		}
+	}
+	if in.logFormat != "" {
+		if _, err = access.ParseLogFormat(in.logFormat); err != nil {
+			return "", opts, usageError{err: err}
+		}
+		opts.LogFormat = in.logFormat
+	}
+	if in.operationRules != "" {
+		if _, err = access.ParseRules(in.operationRules); err != nil {
+			return "", opts, usageError{err: err}
+		}
+		opts.OperationRules = in.operationRules
+	}
+	if in.pattern != "" {
+		if _, err = regexp.Compile(in.pattern); err != nil {
+			return "", opts, usageError{err: fmt.Errorf("invalid pattern: %w", err)}
+		}
+		opts.Pattern = in.pattern
+	}
+	if in.year < 0 {
+		return "", opts, usageError{err: fmt.Errorf("invalid year %d", in.year)}
+	}
+	opts.Year = in.year
+	if in.delimiter != "" {
+		if opts.Delimiter, err = csv.ParseDelimiter(in.delimiter); err != nil {
+			return "", opts, usageError{err: err}
+		}
+	}
+	if in.comment != "" {
+		if opts.Comment, err = csv.ParseDelimiter(in.comment); err != nil {
+			return "", opts, usageError{err: fmt.Errorf("invalid comment character: %w", err)}
+		}
+	}
+	opts.LazyQuotes = in.lazyQuotes
+	if in.encoding != "" {
+		if _, err = csv.ParseEncoding(in.encoding); err != nil {
+			return "", opts, usageError{err: err}
+		}
+		opts.Encoding = in.encoding
+	}
+	return formatName, opts, nil
+}
+
+// unit parses the --size-unit flag. It returns zero, meaning the format's default, when the flag is not set.
+func (in *inputFlags) unit() (reader.Size, error) {
+	if in.sizeUnit == "" {
+		return 0, nil
+	}
+	sizeUnit, err := reader.ParseSize("1"+in.sizeUnit, reader.Byte)
+	if err != nil {
+		return 0, usageError{err: fmt.Errorf("invalid size unit %q", in.sizeUnit)}
+	}
+	return sizeUnit, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// queryOptions translates q into logfind.FinderOptionFuncs that also collect diagnostics into d.
+func queryOptions(q *query.Query, d *logfind.Diagnostics) ([]logfind.FinderOptionFunc, error) {
+	opts, err := q.Options()
+	if err != nil {
+		return nil, usageError{err: err}
+	}
+	return append(opts, logfind.WithDiagnostics(d)), nil
+}
+
+// reportDiagnostics prints the records skipped under the skip-and-report policy to stderr.
+func reportDiagnostics(d *logfind.Diagnostics) {
+	if d.Skipped == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "skipped %d malformed records\n", d.Skipped)
+	for _, recErr := range d.Errors {
+		fmt.Fprintf(os.Stderr, "  %s\n", recErr)
+	}
+}
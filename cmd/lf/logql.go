@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/logql"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// logqlSummary is shown in the usage of lf and lf logql.
+const logqlSummary = "Run a LogQL query, e.g., 'sum by (username) (count_over_time({operation=\"upload\"}[1h]))', over logs."
+
+var logqlCommand = &command{
+	name:    "logql",
+	summary: logqlSummary,
+	run:     runLogQL,
+}
+
+func runLogQL(args []string) error {
+	fs := newFlagSet("logql", "query filepath...", logqlSummary)
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs)
+	start := fs.String("start", "", "The time of the first point of a metric query. Defaults to the first matching event.")
+	end := fs.String("end", "", "The time of the last point of a metric query. Defaults to the last matching event.")
+	step := fs.Duration("step", time.Hour, "The time between the points of a metric query.")
+	onError := fs.String("on-error", string(logfind.Strict), "Changes how malformed records are handled. Values are strict, skip, skip-and-report.")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError{err: errors.New("missing query")}
+	}
+	q, err := logql.Parse(args[0])
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(&query.Query{OnError: *onError}, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(args[1:])
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, append(opts, q.Options...)...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	if q.Metric == nil {
+		if err = out.copy(fr); err != nil {
+			return err
+		}
+		reportDiagnostics(&diagnostics)
+		return nil
+	}
+
+	records, err := reader.ReadAll(fr)
+	if err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	if len(records) == 0 {
+		return nil
+	}
+
+	from, to := records[0].Time, records[0].Time
+	for _, rec := range records {
+		if rec.Time.Before(from) {
+			from = rec.Time
+		}
+		if rec.Time.After(to) {
+			to = rec.Time
+		}
+	}
+	if from, err = parseTimeFlag("start", *start, from.Truncate(*step)); err != nil {
+		return err
+	}
+	if to, err = parseTimeFlag("end", *end, to); err != nil {
+		return err
+	}
+
+	// The records have already been filtered, so the series are computed without the query's predicates.
+	metric := &logql.Query{Metric: q.Metric}
+	series, err := metric.Evaluate(reader.NewSliceReader(records), from, to, *step)
+	if err != nil {
+		return usageError{err: err}
+	}
+	for _, s := range series {
+		labels := formatLabels(s.Labels)
+		for _, p := range s.Points {
+			fmt.Fprintf(os.Stdout, "%s %s %g\n", labels, p.Time.Format(time.RFC3339), p.Value)
+		}
+	}
+	return nil
+}
+
+// parseTimeFlag parses the value of the named time flag, returning def when it is empty.
+func parseTimeFlag(name, value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	t, err := query.ParseTimestamp(value, time.UTC)
+	if err != nil {
+		return t, usageError{err: fmt.Errorf("invalid --%s: %w", name, err)}
+	}
+	return t, nil
+}
+
+// formatLabels formats labels the way Prometheus and Loki print them, e.g., {operation="upload"}.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
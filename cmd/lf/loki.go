@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/logql"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/loki"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// lokiSummary is shown in the usage of lf and lf loki.
+const lokiSummary = "Receive events pushed with Loki's push API, e.g., by Promtail, and write them out."
+
+var lokiCommand = &command{
+	name:    "loki",
+	summary: lokiSummary,
+	run:     runLoki,
+}
+
+func runLoki(args []string) error {
+	fs := newFlagSet("loki", "", lokiSummary)
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs)
+	addr := fs.String("addr", ":3100", "The address to listen on.")
+	selector := fs.String("query", "", "A LogQL log query, e.g., '{operation=\"upload\"}', selecting the events to write. Every event is written by default.")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{err: fmt.Errorf("unexpected argument %q", args[0])}
+	}
+	sizeUnit, err := in.unit()
+	if err != nil {
+		return err
+	}
+
+	// Malformed events are skipped, since one bad push should not stop the receiver.
+	opts := []logfind.FinderOptionFunc{logfind.WithErrorPolicy(logfind.Skip)}
+	if *selector != "" {
+		q, err := logql.Parse(*selector)
+		if err != nil {
+			return usageError{err: err}
+		}
+		if q.Metric != nil {
+			return usageError{err: errors.New("--query must be a log query")}
+		}
+		opts = append(opts, q.Options...)
+	}
+
+	w, closeFn, err := out.open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	var receiverOpts []loki.ReaderOptionFunc
+	if sizeUnit != 0 {
+		receiverOpts = append(receiverOpts, loki.WithSizeUnit(sizeUnit))
+	}
+	receiver := loki.NewReceiver(receiverOpts...)
+	fr, err := logfind.NewFilterReader(receiver, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(loki.PushPath, receiver)
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ready")
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+		receiver.Close()
+	}()
+	served := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "lf: listening on %s\n", *addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			served <- err
+			stop()
+			return
+		}
+		served <- nil
+	}()
+
+	// Events are flushed as they are written so that the output can be followed.
+	for {
+		e, err := fr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = w.Write(e); err != nil {
+			return err
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+	return <-served
+}
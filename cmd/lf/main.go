@@ -1,88 +1,180 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"github.com/kyleishie/logfind/pkg/logfind"
-	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
 	"os"
-	"time"
+	"strings"
 )
 
-func main() {
+// Exit codes shared by every subcommand.
+const (
+	// exitOK means the command succeeded.
+	exitOK = 0
+	// exitFailure means the command failed while running, e.g., a log could not be read or contained a malformed record.
+	exitFailure = 1
+	// exitUsage means the command line was invalid, e.g., an unknown flag or a malformed filter value.
+	exitUsage = 2
+)
 
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] filepath\n\nOptions:\n", os.Args[0])
-		flag.PrintDefaults()
-	}
+// command is a subcommand of lf.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	countConcernPtr := flag.String("count", "event", "Changes how lf counts events. Values are event, operation, user.  Event is default.")
-	minTimestampPtr := flag.String("minTimestamp", "", "The minimum date to match.")
-	maxTimestampPtr := flag.String("maxTimestamp", "", "The maximum date to match. Note this is exclusive.")
-	usernamePtr := flag.String("username", "", "The usernamePtr to match.")
-	operationPtr := flag.String("operation", "", "The operationPtr to match.")
-	minSizePtr := flag.Int("minSize", -1, "The minimum size to match.")
-	maxSizePtr := flag.Int("maxSize", -1, "The maximum size to match. Note this in inclusive.")
-	verbosePtr := flag.Bool("verbose", false, "Use this flag to print more info about the matched events.")
-	flag.Parse()
-
-	args := flag.Args()
-	if len(args) == 0 {
-		fmt.Println("missing filepath")
-		flag.Usage()
-		os.Exit(1)
-	}
+var commands = []*command{
+	countCommand,
+	findCommand,
+	statsCommand,
+	sessionsCommand,
+	anomaliesCommand,
+	schemaCommand,
+	convertCommand,
+	shellCommand,
+	serveCommand,
+	exporterCommand,
+	alertCommand,
+	lokiCommand,
+	logqlCommand,
+	sqlCommand,
+	exportCommand,
+	runCommand,
+}
 
-	filepath := args[len(args)-1]
+// usageError marks errors caused by an invalid command line.
+type usageError struct {
+	err error
+	// reported is set when the error has already been printed, e.g., by the flag package.
+	reported bool
+}
 
-	csvFile, err := os.Open(filepath)
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
-	}
-	r := csv.NewReader(csvFile)
-	f := logfind.NewFinder(r)
+func (e usageError) Error() string {
+	return e.err.Error()
+}
 
-	var opts []logfind.FinderOptionFunc
+func (e usageError) Unwrap() error {
+	return e.err
+}
 
-	if countConcernPtr != nil {
-		opts = append(opts, logfind.WithCountConcern(logfind.CountConcern(*countConcernPtr)))
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
 	}
 
-	if *minTimestampPtr != "" && *maxTimestampPtr != "" {
-		minTimestamp, err := time.Parse(time.RFC3339, *minTimestampPtr)
-		maxTimestamp, err := time.Parse(time.RFC3339, *maxTimestampPtr)
-		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(2)
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd := lookup(args[1]); cmd != nil {
+				return exitCode(cmd.run([]string{"-h"}))
+			}
 		}
-
-		opts = append(opts, logfind.WhereTimestampIsBetween(minTimestamp, maxTimestamp))
+		usage()
+		return exitOK
 	}
 
-	if *usernamePtr != "" {
-		opts = append(opts, logfind.WhereUsernameEquals(*usernamePtr))
+	if err := loadConfig(); err != nil {
+		return exitCode(usageError{err: err})
 	}
 
-	if *operationPtr != "" {
-		opts = append(opts, logfind.WhereOperationEquals(*operationPtr))
+	cmd := lookup(args[0])
+	if cmd == nil {
+		// lf predates its subcommands, so anything else is treated as the original counting command line.
+		return exitCode(countCommand.run(args))
 	}
+	return exitCode(cmd.run(args[1:]))
+}
 
-	if *minSizePtr != -1 {
-		opts = append(opts, logfind.WhereSizeGreaterThanOrEqual(*minSizePtr))
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
+	return nil
+}
 
-	if *maxSizePtr != -1 {
-		opts = append(opts, logfind.WhereSizeGreaterThanOrEqual(*maxSizePtr))
+// exitCode reports err, if any, and maps it to one of the exit codes.
+func exitCode(err error) int {
+	var uErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &uErr):
+		if !uErr.reported {
+			fmt.Fprintf(os.Stderr, "lf: %s\n", err)
+		}
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "lf: %s\n", err)
+		return exitFailure
 	}
+}
 
-	count, events, err := f.Find(opts...)
+func usage() {
+	var b strings.Builder
+	b.WriteString("Usage: lf <command> [options] filepath...\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	b.WriteString("\nRun \"lf help <command>\" for the options of a command.\n")
+	b.WriteString("Without a command lf behaves like \"lf count\".\n")
+	fmt.Fprint(os.Stderr, b.String())
+}
 
-	fmt.Printf("count: %d\n", count)
+// newFlagSet returns a flag.FlagSet for the named subcommand whose usage lists args and summary.
+// Parse errors are returned rather than exiting so they can be mapped to exitUsage.
+func newFlagSet(name, args, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lf %s [options] %s\n\n%s\n\nOptions:\n", name, args, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-	if *verbosePtr {
-		for _, event := range events {
-			fmt.Println(event)
+// parseFlags parses args with fs, wrapping errors other than flag.ErrHelp in a usageError.
+// Flags may appear before or after the positional arguments. Flags default to the values of the config file, if any,
+// and the positional arguments to those of the saved query being run, if any.
+func parseFlags(fs *flag.FlagSet, args []string) (positional []string, err error) {
+	if cfg != nil {
+		for name, value := range cfg.Defaults() {
+			if fs.Lookup(name) == nil {
+				continue
+			}
+			if err = fs.Set(name, value); err != nil {
+				return nil, usageError{err: fmt.Errorf("%s: %s: %w", cfgPath, name, err)}
+			}
+		}
+	}
+	defer func() {
+		if err == nil && len(positional) == 0 {
+			positional = savedArgs
+		}
+	}()
+
+	for {
+		if err = fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			// The flag package has already printed the error along with the usage.
+			return nil, usageError{err: err, reported: true}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return
 		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/config"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// runSummary is shown in the usage of lf and lf run.
+const runSummary = "Run a query saved in lf.yaml or .lfrc, overriding its flags with any given, or list the saved queries."
+
+var runCommand = &command{
+	name:    "run",
+	summary: runSummary,
+}
+
+func init() {
+	// runRun looks up the command of a saved query in commands, which lists runCommand.
+	runCommand.run = runRun
+}
+
+// configEnv names the environment variable that sets the path of the config file, or disables it when set to none.
+const configEnv = "LF_CONFIG"
+
+var (
+	// cfg is the config file that applies, nil when there is none, and cfgPath its path.
+	cfg     *config.Config
+	cfgPath string
+	// savedArgs are the positional arguments of the saved query being run.
+	savedArgs []string
+)
+
+// loadConfig reads the config file named by configEnv or else found by config.Find from the working directory.
+func loadConfig() (err error) {
+	cfgPath = os.Getenv(configEnv)
+	switch cfgPath {
+	case "none":
+		cfgPath = ""
+		return nil
+	case "":
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		home, _ := os.UserHomeDir()
+		if cfgPath, err = config.Find(dir, home); err != nil || cfgPath == "" {
+			return err
+		}
+	}
+	cfg, err = config.Load(cfgPath)
+	return
+}
+
+func runRun(args []string) error {
+	fs := newFlagSet("run", "[name [options] [args...]]", runSummary)
+	// Only -h is parsed here; everything after the name belongs to the saved command.
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err: err, reported: true}
+	}
+	if cfg == nil {
+		return usageError{err: fmt.Errorf("no config file, create lf.yaml or .lfrc or set %s", configEnv)}
+	}
+
+	if fs.NArg() == 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "name\tcommand\tdescription\n")
+		for _, name := range cfg.QueryNames() {
+			q, _ := cfg.Query(name)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", name, q.Command, q.Description)
+		}
+		return tw.Flush()
+	}
+
+	q, err := cfg.Query(fs.Arg(0))
+	if err != nil {
+		return usageError{err: err}
+	}
+	cmd := lookup(q.Command)
+	if cmd == nil || cmd == runCommand {
+		return usageError{err: fmt.Errorf("%s: query %q: unknown command %q", cfgPath, fs.Arg(0), q.Command)}
+	}
+	// The paths of the query are relative to the config file, which may be in a parent directory or $HOME.
+	q = q.Resolve(filepath.Dir(cfgPath))
+	savedArgs = q.Args
+	return cmd.run(append(q.FlagArgs(), fs.Args()[1:]...))
+}
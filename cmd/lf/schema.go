@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"os"
+	"text/tabwriter"
+)
+
+// schemaSummary is shown in the usage of lf and lf schema.
+const schemaSummary = "Print the columns of a log along with their inferred types."
+
+var schemaCommand = &command{
+	name:    "schema",
+	summary: schemaSummary,
+	run:     runSchema,
+}
+
+func runSchema(args []string) error {
+	fs := newFlagSet("schema", "filepath", schemaSummary)
+	sample := fs.Int("sample", 100, "The number of records to sample.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return usageError{err: errors.New("expected exactly one filepath")}
+	}
+
+	file, err := os.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	columns, err := csv.InferSchema(file, *sample)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "column\ttype\texample\n")
+	for _, c := range columns {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Type, c.Example)
+	}
+	return tw.Flush()
+}
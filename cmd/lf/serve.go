@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/server"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// serveSummary is shown in the usage of lf and lf serve.
+const serveSummary = "Serve the count, find and aggregate queries over logs as a JSON HTTP API and a Grafana datasource."
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: serveSummary,
+	run:     runServe,
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "filepath...", serveSummary)
+	var in inputFlags
+	in.register(fs)
+	addr := fs.String("addr", ":8080", "The address to listen on.")
+	timeout := fs.Duration("timeout", server.DefaultTimeout, "The time a request may take before it is abandoned.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if path == "-" {
+			return usageError{err: errors.New("lf serve reads its logs on every request and cannot serve stdin")}
+		}
+	}
+
+	// Open the logs once up front so that missing files and invalid flags are reported before listening.
+	_, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	closeAll()
+
+	source := func() (reader.Reader, func(), error) {
+		return in.open(paths)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", server.NewHandler(source, server.WithTimeout(*timeout)))
+	mux.Handle("/grafana/", http.StripPrefix("/grafana", server.NewGrafanaHandler(source, server.WithTimeout(*timeout))))
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "lf: listening on %s\n", *addr)
+	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/stats"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// sessionsSummary is shown in the usage of lf and lf sessions.
+const sessionsSummary = "Split the activity of each user into sessions ending after a gap of inactivity."
+
+var sessionsCommand = &command{
+	name:    "sessions",
+	summary: sessionsSummary,
+	run:     runSessions,
+}
+
+func runSessions(args []string) error {
+	fs := newFlagSet("sessions", "filepath...", sessionsSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	gapFlag := fs.String("gap", "30m", "The inactivity after which a session ends, e.g., 30m or 1h.")
+	summaryOnly := fs.Bool("summary", false, "Print only the summary of the sessions.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	gap, err := query.ParseDuration(*gapFlag)
+	if err != nil || gap <= 0 {
+		return usageError{err: fmt.Errorf("invalid gap %q", *gapFlag)}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+	sessions, err := stats.Sessions(fr, gap)
+	if errors.Is(err, stats.ErrGapInvalid) {
+		return usageError{err: err}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !*summaryOnly {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "user\tstart\tend\tduration\tevents\tuploaded\tdownloaded\n")
+		for _, s := range sessions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", s.User, s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339), s.Duration(), s.Events, s.Uploaded, s.Downloaded)
+		}
+		fmt.Fprintln(tw)
+		if err = tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if err = stats.SummarizeSessions(sessions).Fprint(os.Stdout); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
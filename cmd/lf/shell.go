@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/shell"
+	"os"
+	"path/filepath"
+)
+
+// shellSummary is shown in the usage of lf and lf shell.
+const shellSummary = "Load a log, optionally filtered by a query, and explore it interactively."
+
+var shellCommand = &command{
+	name:    "shell",
+	summary: shellSummary,
+	run:     runShell,
+}
+
+func runShell(args []string) error {
+	fs := newFlagSet("shell", "filepath...", shellSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+	records, err := reader.ReadAll(fr)
+	if err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	fmt.Printf("loaded %d events, type help for a list of commands\n", len(records))
+
+	e := shell.NewEditor(os.Stdin, os.Stdout)
+	if restore, err := shell.MakeRaw(int(os.Stdin.Fd())); err == nil {
+		defer restore()
+		e.Interactive = true
+	}
+
+	var historyPath string
+	if home, err := os.UserHomeDir(); err == nil {
+		historyPath = filepath.Join(home, ".lf_history")
+		if err = e.LoadHistory(historyPath); err != nil {
+			fmt.Fprintf(os.Stderr, "lf: %s\n", err)
+		}
+	}
+
+	s := shell.New(records, os.Stdout)
+	err = s.Run(e)
+	if historyPath != "" {
+		if saveErr := e.SaveHistory(historyPath); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "lf: %s\n", saveErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/sql"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// sqlSummary is shown in the usage of lf and lf sql.
+const sqlSummary = "Run a SQL SELECT statement, e.g., 'SELECT username, COUNT(*) FROM log GROUP BY username', over logs."
+
+var sqlCommand = &command{
+	name:    "sql",
+	summary: sqlSummary,
+	run:     runSQL,
+}
+
+func runSQL(args []string) error {
+	fs := newFlagSet("sql", "query filepath...", sqlSummary)
+	var in inputFlags
+	in.register(fs)
+	onError := fs.String("on-error", string(logfind.Strict), "Changes how malformed records are handled. Values are strict, skip, skip-and-report.")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError{err: errors.New("missing query")}
+	}
+	stmt, err := sql.Parse(args[0])
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(&query.Query{OnError: *onError}, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(args[1:])
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+	result, err := stmt.Execute(fr)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			values[i] = sql.FormatValue(v)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
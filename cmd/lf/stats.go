@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/stats"
+	"os"
+)
+
+// statsSummary is shown in the usage of lf and lf stats.
+const statsSummary = "Summarize the events of a log, optionally filtered by a query."
+
+var statsCommand = &command{
+	name:    "stats",
+	summary: statsSummary,
+	run:     runStats,
+}
+
+func runStats(args []string) error {
+	fs := newFlagSet("stats", "filepath...", statsSummary)
+	q := query.New()
+	q.RegisterFlags(fs)
+	var in inputFlags
+	in.register(fs)
+	top := fs.Int("top", 10, "The number of most active users to list.")
+
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := queryOptions(q, &diagnostics)
+	if err != nil {
+		return err
+	}
+
+	r, closeAll, err := in.open(paths)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	fr, err := logfind.NewFilterReader(r, opts...)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	summary, err := stats.Summarize(fr)
+	if err != nil {
+		return err
+	}
+
+	if err = summary.Fprint(os.Stdout, *top); err != nil {
+		return err
+	}
+	reportDiagnostics(&diagnostics)
+	return nil
+}
@@ -0,0 +1,145 @@
+package alert
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rules = `{"rules": [
+	{"name": "upload-burst", "query": "-op upload", "window": "10m", "above": 2, "by": "user"},
+	{"name": "busy", "window": "1h", "above": 4}
+]}`
+
+func TestParseRules(t *testing.T) {
+	t.Run("parses rules", func(t *testing.T) {
+		got, err := ParseRules(strings.NewReader(rules))
+		assert.NoError(t, err)
+		assert.Equal(t, []Rule{
+			{Name: "upload-burst", Query: "-op upload", Window: "10m", Above: 2, By: ByUser},
+			{Name: "busy", Window: "1h", Above: 4},
+		}, got)
+	})
+
+	t.Run("parses the documented query", func(t *testing.T) {
+		got, err := ParseRules(strings.NewReader(`{"rules": [{"name": "big-uploads", "query": "-op upload --minSize 50kB", "window": "10m"}]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "-op upload --minSize 50kB", got[0].Query)
+	})
+
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{name: "unknown field", rules: `{"rules": [{"name": "a", "window": "1m", "below": 1}]}`, want: `unknown field "below"`},
+		{name: "missing name", rules: `{"rules": [{"window": "1m"}]}`, want: "missing name"},
+		{name: "invalid window", rules: `{"rules": [{"name": "a", "window": "soon"}]}`, want: `rule "a": invalid window "soon"`},
+		{name: "invalid by", rules: `{"rules": [{"name": "a", "window": "1m", "by": "day"}]}`, want: `rule "a": invalid by "day"`},
+		{name: "invalid query", rules: `{"rules": [{"name": "a", "window": "1m", "query": "--minSize big"}]}`, want: `rule "a": invalid size "big"`},
+		{name: "duplicate name", rules: `{"rules": [{"name": "a", "window": "1m"}, {"name": "a", "window": "1m"}]}`, want: `rule "a": duplicate name`},
+	}
+	for _, tt := range tests {
+		t.Run("fails on "+tt.name, func(t *testing.T) {
+			_, err := ParseRules(strings.NewReader(tt.rules))
+			assert.ErrorIs(t, err, ErrRuleInvalid)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func at(min, sec int) time.Time {
+	return time.Date(2020, 4, 15, 12, min, sec, 0, time.UTC)
+}
+
+func upload(user string, t time.Time) reader.Record {
+	return reader.Record{Time: t, User: user, Op: "upload", Bytes: reader.KB}
+}
+
+// recorder collects the alerts it is notified of.
+type recorder struct {
+	alerts []Alert
+}
+
+func (r *recorder) Notify(alert Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func newEngine(t *testing.T, opts ...EngineOptionFunc) (*Engine, *recorder) {
+	rs, err := ParseRules(strings.NewReader(rules))
+	assert.NoError(t, err)
+	rec := &recorder{}
+	e, err := NewEngine(rs, rec, opts...)
+	assert.NoError(t, err)
+	return e, rec
+}
+
+func TestEngine(t *testing.T) {
+	t.Run("fires once and resolves", func(t *testing.T) {
+		e, rec := newEngine(t)
+		err := e.Run(reader.NewSliceReader([]reader.Record{
+			upload("jeff22", at(0, 0)),
+			upload("sarah94", at(1, 0)),
+			upload("jeff22", at(2, 0)),
+			upload("jeff22", at(3, 0)),
+			upload("jeff22", at(4, 0)),
+			upload("sarah94", at(12, 30)),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, []Alert{
+			{Rule: "upload-burst", Group: "jeff22", State: Firing, Count: 3, Above: 2, Window: "10m", Since: at(3, 0), At: at(3, 0)},
+			{Rule: "busy", State: Firing, Count: 5, Above: 4, Window: "1h", Since: at(4, 0), At: at(4, 0)},
+			{Rule: "upload-burst", Group: "jeff22", State: Resolved, Count: 2, Above: 2, Window: "10m", Since: at(3, 0), At: at(12, 30)},
+		}, rec.alerts)
+	})
+
+	t.Run("resolves while idle on tick", func(t *testing.T) {
+		wall := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		e, rec := newEngine(t, WithWallClock(func() time.Time { return wall }))
+		assert.NoError(t, e.Tick())
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, e.Observe(upload("jeff22", at(0, i))))
+		}
+		assert.Len(t, rec.alerts, 1)
+
+		wall = wall.Add(5 * time.Minute)
+		assert.NoError(t, e.Tick())
+		assert.Len(t, rec.alerts, 1)
+
+		wall = wall.Add(5 * time.Minute)
+		assert.NoError(t, e.Tick())
+		assert.Len(t, rec.alerts, 2)
+		assert.Equal(t, Resolved, rec.alerts[1].State)
+		assert.Equal(t, at(10, 2), rec.alerts[1].At)
+	})
+
+	t.Run("counts events arriving out of order", func(t *testing.T) {
+		e, rec := newEngine(t)
+		assert.NoError(t, e.Observe(upload("jeff22", at(5, 0))))
+		assert.NoError(t, e.Observe(upload("jeff22", at(4, 0))))
+		// Older than the window of the latest event, so it is not counted.
+		assert.NoError(t, e.Observe(upload("jeff22", at(-6, 0))))
+		assert.Empty(t, rec.alerts)
+		assert.NoError(t, e.Observe(upload("jeff22", at(3, 0))))
+		assert.Len(t, rec.alerts, 1)
+	})
+
+	t.Run("fails on malformed event", func(t *testing.T) {
+		e, _ := newEngine(t)
+		err := e.Observe(malformedEvent{upload("jeff22", at(0, 0))})
+		assert.ErrorIs(t, err, errMalformed)
+	})
+}
+
+type malformedEvent struct {
+	reader.Record
+}
+
+const errMalformed = ErrRuleInvalid + " size"
+
+func (malformedEvent) Size() (reader.Size, error) {
+	return 0, errMalformed
+}
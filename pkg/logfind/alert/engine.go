@@ -0,0 +1,225 @@
+package alert
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is whether an Alert started or stopped firing.
+type State string
+
+const (
+	Firing   = State("firing")
+	Resolved = State("resolved")
+)
+
+// Alert reports that a rule started or stopped firing for a group of events.
+type Alert struct {
+	Rule string `json:"rule"`
+	// Group is the username or operation counted, empty when the rule counts every matching event together.
+	Group string `json:"group,omitempty"`
+	State State  `json:"state"`
+	// Count is the number of matching events in the window when the state changed.
+	Count  int    `json:"count"`
+	Above  int    `json:"above"`
+	Window string `json:"window"`
+	// Since is when the rule started firing and At when the state changed, both by the clock of the events.
+	Since time.Time `json:"since"`
+	At    time.Time `json:"at"`
+}
+
+// group is the sliding window of a rule for one group of events.
+type group struct {
+	// times are the timestamps of the matching events in the window, oldest first.
+	times  []time.Time
+	firing bool
+	since  time.Time
+}
+
+// Engine evaluates rules against events as they arrive. Its clock is the latest event timestamp, so a log can be
+// replayed as well as followed. It is safe for concurrent use, so several logs may be observed by one Engine.
+type Engine struct {
+	mu       sync.Mutex
+	rules    []*compiled
+	notifier Notifier
+
+	// now is the clock of the engine, latest the latest event timestamp and observed the wall time it was observed.
+	now      time.Time
+	latest   time.Time
+	observed time.Time
+	wall     func() time.Time
+}
+
+// EngineOptionFunc customizes an Engine created by NewEngine.
+type EngineOptionFunc func(*Engine)
+
+// WithWallClock sets the clock Tick measures idle time with. The default is time.Now.
+func WithWallClock(wall func() time.Time) EngineOptionFunc {
+	return func(e *Engine) {
+		e.wall = wall
+	}
+}
+
+// NewEngine returns an Engine evaluating rules and sending state changes to notifier. A rule is notified once when
+// it starts firing for a group, and once more when it resolves.
+func NewEngine(rules []Rule, notifier Notifier, opts ...EngineOptionFunc) (*Engine, error) {
+	e := &Engine{
+		notifier: notifier,
+		wall:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	for _, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, c)
+	}
+	return e, nil
+}
+
+// Run observes every event of r until io.EOF. It stops at the first error, including errors of the notifier, so wrap
+// r with logfind.NewFilterReader to skip malformed records.
+func (e *Engine) Run(r reader.Reader) error {
+	for {
+		ev, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = e.Observe(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// Observe counts ev in the windows of the rules it matches and notifies the rules whose state changed. The engine's
+// state is updated even when the notifier fails, so each change is sent only once.
+func (e *Engine) Observe(ev reader.Event) error {
+	rec, err := reader.NewRecord(ev)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if rec.Time.After(e.latest) {
+		e.latest = rec.Time
+	}
+	e.observed = e.wall()
+	if e.latest.After(e.now) {
+		e.now = e.latest
+	}
+
+	for _, rule := range e.rules {
+		ok, err := rule.match(rec)
+		if err != nil {
+			return err
+		}
+		if !ok || !rec.Time.After(e.now.Add(-rule.window)) {
+			continue
+		}
+		key := ""
+		switch rule.By {
+		case ByUser:
+			key = rec.User
+		case ByOperation:
+			key = rec.Op
+		}
+		g := rule.groups[key]
+		if g == nil {
+			g = &group{}
+			rule.groups[key] = g
+		}
+		g.insert(rec.Time)
+	}
+	return e.evaluate()
+}
+
+// Tick advances the clock by the wall time that passed since the latest event was observed, so that rules resolve
+// while a followed log is quiet. It does nothing before the first event.
+func (e *Engine) Tick() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.latest.IsZero() {
+		return nil
+	}
+	if now := e.latest.Add(e.wall().Sub(e.observed)); now.After(e.now) {
+		e.now = now
+	}
+	return e.evaluate()
+}
+
+// evaluate slides every window to the clock and notifies the groups whose state changed. It returns the first error of
+// the notifier.
+func (e *Engine) evaluate() (err error) {
+	for _, rule := range e.rules {
+		keys := make([]string, 0, len(rule.groups))
+		for key := range rule.groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			g := rule.groups[key]
+			g.expire(e.now.Add(-rule.window))
+
+			count := len(g.times)
+			var state State
+			switch {
+			case !g.firing && count > rule.Above:
+				g.firing, g.since = true, e.now
+				state = Firing
+			case g.firing && count <= rule.Above:
+				g.firing = false
+				state = Resolved
+			}
+			if state != "" {
+				alert := Alert{
+					Rule:   rule.Name,
+					Group:  key,
+					State:  state,
+					Count:  count,
+					Above:  rule.Above,
+					Window: rule.Window,
+					Since:  g.since,
+					At:     e.now,
+				}
+				if notifyErr := e.notifier.Notify(alert); notifyErr != nil && err == nil {
+					err = notifyErr
+				}
+			}
+			if count == 0 && !g.firing {
+				delete(rule.groups, key)
+			}
+		}
+	}
+	return
+}
+
+// insert adds t to the window, keeping it ordered when events arrive slightly out of order.
+func (g *group) insert(t time.Time) {
+	i := sort.Search(len(g.times), func(i int) bool {
+		return g.times[i].After(t)
+	})
+	g.times = append(g.times, time.Time{})
+	copy(g.times[i+1:], g.times[i:])
+	g.times[i] = t
+}
+
+// expire drops the timestamps at or before start.
+func (g *group) expire(start time.Time) {
+	i := sort.Search(len(g.times), func(i int) bool {
+		return g.times[i].After(start)
+	})
+	g.times = g.times[i:]
+}
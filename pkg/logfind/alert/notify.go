@@ -0,0 +1,103 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// NotifierFunc adapts a func to a Notifier.
+type NotifierFunc func(alert Alert) error
+
+func (f NotifierFunc) Notify(alert Alert) error {
+	return f(alert)
+}
+
+type multiNotifier []Notifier
+
+// MultiNotifier returns a Notifier delivering every alert to each of notifiers, even when one fails.
+// It returns the first error.
+func MultiNotifier(notifiers ...Notifier) Notifier {
+	return multiNotifier(notifiers)
+}
+
+func (m multiNotifier) Notify(alert Alert) (err error) {
+	for _, n := range m {
+		if notifyErr := n.Notify(alert); notifyErr != nil && err == nil {
+			err = notifyErr
+		}
+	}
+	return
+}
+
+type writerNotifier struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterNotifier returns a Notifier writing each alert to w as a line of JSON.
+func NewWriterNotifier(w io.Writer) Notifier {
+	return &writerNotifier{enc: json.NewEncoder(w)}
+}
+
+func (n *writerNotifier) Notify(alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.enc.Encode(alert)
+}
+
+// DefaultWebhookTimeout is the time a webhook may take to answer unless changed with WithClient.
+const DefaultWebhookTimeout = 10 * time.Second
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// WebhookOptionFunc customizes a Notifier created by NewWebhookNotifier.
+type WebhookOptionFunc func(*webhookNotifier)
+
+// WithClient sets the http.Client the webhook is called with.
+func WithClient(client *http.Client) WebhookOptionFunc {
+	return func(n *webhookNotifier) {
+		n.client = client
+	}
+}
+
+// NewWebhookNotifier returns a Notifier posting each alert as JSON to url. Any status other than 2xx is an error.
+func NewWebhookNotifier(url string, opts ...WebhookOptionFunc) Notifier {
+	n := &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: DefaultWebhookTimeout},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+func (n *webhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %s", n.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var firing = Alert{Rule: "upload-burst", Group: "jeff22", State: Firing, Count: 3, Above: 2, Window: "10m", Since: at(3, 0), At: at(3, 0)}
+
+func TestNewWriterNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, NewWriterNotifier(&buf).Notify(firing))
+	assert.Equal(t, `{"rule":"upload-burst","group":"jeff22","state":"firing","count":3,"above":2,"window":"10m","since":"2020-04-15T12:03:00Z","at":"2020-04-15T12:03:00Z"}`+"\n", buf.String())
+}
+
+func TestNewWebhookNotifier(t *testing.T) {
+	t.Run("posts alerts", func(t *testing.T) {
+		var got Alert
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		assert.NoError(t, NewWebhookNotifier(srv.URL, WithClient(srv.Client())).Notify(firing))
+		assert.Equal(t, firing, got)
+	})
+
+	t.Run("fails on error status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		err := NewWebhookNotifier(srv.URL).Notify(firing)
+		assert.ErrorContains(t, err, "unexpected status 500")
+	})
+}
+
+func TestMultiNotifier(t *testing.T) {
+	errFailed := errors.New("failed")
+	first, second := &recorder{}, &recorder{}
+	n := MultiNotifier(first, NotifierFunc(func(Alert) error { return errFailed }), second)
+
+	assert.ErrorIs(t, n.Notify(firing), errFailed)
+	assert.Equal(t, []Alert{firing}, first.alerts)
+	assert.Equal(t, []Alert{firing}, second.alerts)
+}
@@ -0,0 +1,123 @@
+// Package alert evaluates threshold rules, e.g., more than 100 uploads by one user in 10 minutes, against a stream of
+// events and notifies when a rule starts and stops firing.
+//
+// Rules are written as JSON:
+//
+//	{"rules": [{"name": "upload-burst", "query": "-op upload", "window": "10m", "above": 100, "by": "user"}]}
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"io"
+	"time"
+)
+
+const (
+	ErrRuleInvalid = logfind.Error("invalid rule")
+)
+
+// Groupings of Rule.By.
+const (
+	// ByNone counts every matching event together.
+	ByNone = ""
+	// ByUser counts the matching events of each username separately.
+	ByUser = "user"
+	// ByOperation counts the matching events of each operation separately.
+	ByOperation = "operation"
+)
+
+// Rule fires while more than Above events matching Query occurred within Window of the latest event.
+type Rule struct {
+	// Name identifies the rule in alerts. It must be unique.
+	Name string `json:"name"`
+	// Query selects the events the rule counts, written as lf flags, e.g., `-op upload --minSize 50kB`.
+	// Every event is counted when it is empty.
+	Query string `json:"query,omitempty"`
+	// Window is the duration of the sliding window, e.g., 10m or 1d.
+	Window string `json:"window"`
+	// Above is the number of events in the window the rule tolerates.
+	Above int `json:"above"`
+	// By is one of ByNone, ByUser or ByOperation.
+	By string `json:"by,omitempty"`
+}
+
+// ruleFile is the format read by ParseRules.
+type ruleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// ParseRules reads and validates a JSON rules file.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var f ruleFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRuleInvalid, err)
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range f.Rules {
+		if _, err := compile(rule); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%w %q: duplicate name", ErrRuleInvalid, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return f.Rules, nil
+}
+
+// compiled is a Rule ready to be evaluated.
+type compiled struct {
+	Rule
+	window time.Duration
+	match  logfind.Predicate
+	groups map[string]*group
+}
+
+// compile validates rule and parses its window and query.
+func compile(rule Rule) (*compiled, error) {
+	invalid := func(reason string, args ...interface{}) error {
+		return fmt.Errorf("%w %q: %s", ErrRuleInvalid, rule.Name, fmt.Sprintf(reason, args...))
+	}
+
+	if rule.Name == "" {
+		return nil, fmt.Errorf("%w: missing name", ErrRuleInvalid)
+	}
+	window, err := query.ParseDuration(rule.Window)
+	if err != nil || window <= 0 {
+		return nil, invalid("invalid window %q", rule.Window)
+	}
+	if rule.Above < 0 {
+		return nil, invalid("invalid above %d", rule.Above)
+	}
+	switch rule.By {
+	case ByNone, ByUser, ByOperation:
+	default:
+		return nil, invalid("invalid by %q, expected user or operation", rule.By)
+	}
+
+	q, err := query.ParseExpression(rule.Query)
+	if err != nil {
+		return nil, invalid("%s", err)
+	}
+	opts, err := q.Options()
+	if err != nil {
+		return nil, invalid("%s", err)
+	}
+	match, err := logfind.NewPredicate(opts...)
+	if err != nil {
+		return nil, invalid("%s", err)
+	}
+
+	return &compiled{
+		Rule:   rule,
+		window: window,
+		match:  match,
+		groups: make(map[string]*group),
+	}, nil
+}
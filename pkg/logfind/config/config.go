@@ -0,0 +1,285 @@
+// Package config reads the project configuration of lf, a YAML file named lf.yaml or .lfrc, e.g.,
+//
+//	tz: America/New_York
+//	timestamp-layout: "2006-01-02 15:04:05"
+//	size-unit: B
+//	input-format: csv
+//	columns:
+//	  username: user
+//	  operation: action
+//	queries:
+//	  uploads:
+//	    command: count
+//	    description: Uploads by people rather than service accounts.
+//	    flags:
+//	      op: upload
+//	      exclude-username-file: service-accounts.txt
+//	    args: [logs/server_log.csv]
+//
+// The top level settings are defaults for the flags of the same name, and each query saves flags and arguments of
+// a command to be run by name.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ErrConfigInvalid = logfind.Error("invalid config")
+	ErrQueryUnknown  = logfind.Error("unknown saved query")
+)
+
+// FileNames are the names of config files, in order of precedence within a directory.
+var FileNames = []string{"lf.yaml", ".lfrc"}
+
+// Config is the content of a config file.
+type Config struct {
+	// TZ is the default of --tz.
+	TZ string `yaml:"tz"`
+	// TimestampLayout is the default of --timestamp-layout, see time.Parse.
+	TimestampLayout string `yaml:"timestamp-layout"`
+	// SizeUnit is the default of --size-unit.
+	SizeUnit string `yaml:"size-unit"`
+	// InputFormat is the default of --input-format.
+	InputFormat string `yaml:"input-format"`
+	// Columns maps event fields to the columns of the log holding them, the default of --columns.
+	Columns map[string]string `yaml:"columns"`
+	// LogFormat is the default of --log-format, the format of access logs.
+	LogFormat string `yaml:"log-format"`
+	// OperationRules is the default of --operation-rules, which derive the operations of access log requests.
+	OperationRules string `yaml:"operation-rules"`
+	// Pattern is the default of --pattern, which extracts fields from syslog messages.
+	Pattern string `yaml:"pattern"`
+	// Delimiter is the default of --delimiter, the field separator of csv and tsv logs.
+	Delimiter string `yaml:"delimiter"`
+	// Encoding is the default of --encoding, the text encoding of csv and tsv logs.
+	Encoding string `yaml:"encoding"`
+
+	Queries map[string]Query `yaml:"queries"`
+}
+
+// Query is a saved command line.
+type Query struct {
+	// Command is the lf command to run, count unless given.
+	Command     string `yaml:"command"`
+	Description string `yaml:"description"`
+	// Flags are given to the command before any flags on the command line, so the latter override them.
+	Flags map[string]string `yaml:"flags"`
+	// Args are the positional arguments, e.g., file paths, used when none are given on the command line.
+	Args []string `yaml:"args"`
+}
+
+// PathFlags are the flags whose values are file paths, which Query.Resolve resolves.
+var PathFlags = []string{"username-file", "exclude-username-file", "rules", "output", "o", "sqlite", "sql"}
+
+// Parse reads a config from r. Unknown settings are errors, so misspellings do not go unnoticed.
+func Parse(r io.Reader) (*Config, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var c Config
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %s", ErrConfigInvalid, err)
+	}
+
+	if _, err := ParseColumns(FormatColumns(c.Columns)); err != nil {
+		return nil, fmt.Errorf("%w: columns: %s", ErrConfigInvalid, err)
+	}
+	for name, q := range c.Queries {
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%w: invalid query name %q", ErrConfigInvalid, name)
+		}
+		for flag := range q.Flags {
+			if flag == "" || strings.HasPrefix(flag, "-") {
+				return nil, fmt.Errorf("%w: query %q: invalid flag %q, expected a flag name without dashes", ErrConfigInvalid, name, flag)
+			}
+		}
+	}
+	return &c, nil
+}
+
+// Load reads the config file at path.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Find returns the path of the config file that applies in dir: the first of FileNames found in dir or its parents,
+// or else in home. It returns "" when there is none.
+func Find(dir, home string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if path, err := findIn(dir); path != "" || err != nil {
+			return path, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if home == "" {
+		return "", nil
+	}
+	return findIn(home)
+}
+
+func findIn(dir string) (string, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// Defaults returns the values the config gives flags, by flag name.
+func (c *Config) Defaults() map[string]string {
+	defaults := make(map[string]string)
+	set := func(flag, value string) {
+		if value != "" {
+			defaults[flag] = value
+		}
+	}
+	set("tz", c.TZ)
+	set("timestamp-layout", c.TimestampLayout)
+	set("size-unit", c.SizeUnit)
+	set("input-format", c.InputFormat)
+	set("columns", FormatColumns(c.Columns))
+	set("log-format", c.LogFormat)
+	set("operation-rules", c.OperationRules)
+	set("pattern", c.Pattern)
+	set("delimiter", c.Delimiter)
+	set("encoding", c.Encoding)
+	return defaults
+}
+
+// Query returns the saved query called name.
+func (c *Config) Query(name string) (Query, error) {
+	q, ok := c.Queries[name]
+	if !ok {
+		return Query{}, fmt.Errorf("%w %q", ErrQueryUnknown, name)
+	}
+	if q.Command == "" {
+		q.Command = "count"
+	}
+	return q, nil
+}
+
+// QueryNames returns the names of the saved queries in order.
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns q with its relative Args and PathFlags joined to dir, e.g., the directory of the config file, so
+// that the query reads the same files wherever it is run from. "-", which means stdin or stdout, is kept.
+func (q Query) Resolve(dir string) Query {
+	resolve := func(path string) string {
+		if path == "" || path == "-" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	if q.Args != nil {
+		args := make([]string, len(q.Args))
+		for i, arg := range q.Args {
+			args[i] = resolve(arg)
+		}
+		q.Args = args
+	}
+	if q.Flags != nil {
+		flags := make(map[string]string, len(q.Flags))
+		for name, value := range q.Flags {
+			flags[name] = value
+		}
+		for _, name := range PathFlags {
+			if value, ok := flags[name]; ok {
+				flags[name] = resolve(value)
+			}
+		}
+		q.Flags = flags
+	}
+	return q
+}
+
+// FlagArgs returns the flags of q as command line arguments, e.g., --op=upload, ordered by flag name.
+func (q Query) FlagArgs() []string {
+	names := make([]string, 0, len(q.Flags))
+	for name := range q.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = "--" + name + "=" + q.Flags[name]
+	}
+	return args
+}
+
+// ParseColumns parses a column mapping written as field=column pairs separated by commas, e.g.,
+// `username=user,operation=action`. The fields are those of reader.Event.
+func ParseColumns(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	columns := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=column", pair)
+		}
+		switch field {
+		case reader.FieldTimestamp, reader.FieldUsername, reader.FieldOperation, reader.FieldSize:
+		default:
+			return nil, fmt.Errorf("unknown field %q, expected timestamp, username, operation or size", field)
+		}
+		columns[field] = column
+	}
+	return columns, nil
+}
+
+// FormatColumns formats a column mapping as ParseColumns expects it, ordered by field.
+func FormatColumns(columns map[string]string) string {
+	pairs := make([]string, 0, len(columns))
+	for field, column := range columns {
+		pairs = append(pairs, field+"="+column)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const input = `
+tz: America/New_York
+timestamp-layout: "2006-01-02 15:04:05"
+size-unit: B
+columns:
+  username: user
+  operation: action
+operation-rules: PUT=upload; GET=download
+delimiter: ;
+encoding: latin1
+queries:
+  uploads:
+    description: Uploads by people.
+    flags:
+      op: upload
+      exclude-username-file: service-accounts.txt
+    args: [server_log.csv]
+  big:
+    command: find
+    flags:
+      minSize: 50kB
+`
+
+func TestParse(t *testing.T) {
+	t.Run("parses config", func(t *testing.T) {
+		c, err := Parse(strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"tz":               "America/New_York",
+			"timestamp-layout": "2006-01-02 15:04:05",
+			"size-unit":        "B",
+			"columns":          "operation=action,username=user",
+			"operation-rules":  "PUT=upload; GET=download",
+			"delimiter":        ";",
+			"encoding":         "latin1",
+		}, c.Defaults())
+		assert.Equal(t, []string{"big", "uploads"}, c.QueryNames())
+
+		q, err := c.Query("uploads")
+		assert.NoError(t, err)
+		assert.Equal(t, "count", q.Command)
+		assert.Equal(t, []string{"--exclude-username-file=service-accounts.txt", "--op=upload"}, q.FlagArgs())
+		assert.Equal(t, []string{"server_log.csv"}, q.Args)
+
+		_, err = c.Query("small")
+		assert.ErrorIs(t, err, ErrQueryUnknown)
+	})
+
+	t.Run("accepts empty config", func(t *testing.T) {
+		c, err := Parse(strings.NewReader(""))
+		assert.NoError(t, err)
+		assert.Empty(t, c.Defaults())
+	})
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "unknown setting", config: "timezone: UTC\n", want: "field timezone not found"},
+		{name: "unknown column field", config: "columns:\n  host: h\n", want: `unknown field "host"`},
+		{name: "flag with dashes", config: "queries:\n  a:\n    flags:\n      --op: upload\n", want: `invalid flag "--op"`},
+		{name: "query name with spaces", config: "queries:\n  a b:\n    command: count\n", want: `invalid query name "a b"`},
+	}
+	for _, tt := range tests {
+		t.Run("fails on "+tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.config))
+			assert.ErrorIs(t, err, ErrConfigInvalid)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	home := t.TempDir()
+	project := filepath.Join(root, "project")
+	sub := filepath.Join(project, "scripts")
+	assert.NoError(t, os.MkdirAll(sub, 0o755))
+
+	t.Run("returns nothing without config", func(t *testing.T) {
+		path, err := Find(sub, home)
+		assert.NoError(t, err)
+		assert.Empty(t, path)
+	})
+
+	t.Run("falls back to home", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(home, ".lfrc"), nil, 0o644))
+		path, err := Find(sub, home)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, ".lfrc"), path)
+	})
+
+	t.Run("prefers the nearest directory and lf.yaml", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(project, ".lfrc"), nil, 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(project, "lf.yaml"), nil, 0o644))
+		path, err := Find(sub, home)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(project, "lf.yaml"), path)
+	})
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("username=user, size=bytes")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "user", "size": "bytes"}, columns)
+	assert.Equal(t, "size=bytes,username=user", FormatColumns(columns))
+
+	_, err = ParseColumns("username")
+	assert.ErrorContains(t, err, "expected field=column")
+}
+
+func TestQuery_Resolve(t *testing.T) {
+	dir := filepath.Join("home", "sarah", "project")
+	q := Query{
+		Flags: map[string]string{"op": "upload", "exclude-username-file": "service-accounts.txt", "output": "-"},
+		Args:  []string{"logs/server_log.csv", "/var/log/lf.csv", "-"},
+	}
+	resolved := q.Resolve(dir)
+	assert.Equal(t, map[string]string{
+		"op":                    "upload",
+		"exclude-username-file": filepath.Join(dir, "service-accounts.txt"),
+		"output":                "-",
+	}, resolved.Flags)
+	assert.Equal(t, []string{filepath.Join(dir, "logs/server_log.csv"), "/var/log/lf.csv", "-"}, resolved.Args)
+	assert.Equal(t, "service-accounts.txt", q.Flags["exclude-username-file"], "q is left unchanged")
+}
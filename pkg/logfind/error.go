@@ -1,14 +1,45 @@
 package logfind
 
-type Error string
-
-var _ error = (*Error)(nil)
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+)
 
-func (e Error) Error() string {
-	return string(e)
-}
+// Error is the type of the sentinel errors of logfind and of the reader packages.
+type Error = reader.Error
 
 const (
-	ErrTimeRangeInvalid = Error("time range invalid")
-	ErrSizeRangeInvalid = Error("size range invalid")
+	ErrTimeRangeInvalid   = Error("time range invalid")
+	ErrSizeRangeInvalid   = Error("size range invalid")
+	ErrErrorPolicyInvalid = Error("error policy invalid")
+	ErrGlobInvalid        = Error("glob pattern invalid")
+	ErrBoundsInvalid      = Error("bounds invalid")
 )
+
+// RecordError describes a log record that could not be read or interpreted. It is defined by package reader, so that
+// readers need not depend on logfind.
+type RecordError = reader.RecordError
+
+// Diagnostics summarizes the malformed records that were skipped under the SkipAndReport ErrorPolicy.
+type Diagnostics struct {
+	// Skipped is the total number of records that were skipped.
+	Skipped int
+	// ByField tallies skipped records by the name of the field that failed.
+	// Records that were malformed as a whole are tallied under the empty string.
+	ByField map[string]int
+	// Errors holds the error for each skipped record in the order they were encountered.
+	Errors []*RecordError
+	// CountOnly leaves Errors empty, which bounds the memory used by Diagnostics that are filled indefinitely, e.g.,
+	// while following a log.
+	CountOnly bool
+}
+
+func (d *Diagnostics) add(err *RecordError) {
+	if d.ByField == nil {
+		d.ByField = make(map[string]int)
+	}
+	d.Skipped++
+	d.ByField[err.Field]++
+	if !d.CountOnly {
+		d.Errors = append(d.Errors, err)
+	}
+}
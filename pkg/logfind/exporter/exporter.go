@@ -0,0 +1,200 @@
+// Package exporter counts the events of log streams and exposes the counts as Prometheus metrics.
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OtherUsername is the username label of the events of users beyond the limit given to WithUsernames.
+const OtherUsername = "other"
+
+// counter totals the events and bytes of one label set.
+type counter struct {
+	events int64
+	size   reader.Size
+}
+
+func (c *counter) add(size reader.Size) {
+	c.events++
+	c.size += size
+}
+
+type userKey struct {
+	username  string
+	operation string
+}
+
+// Exporter counts events by operation and, optionally, by username. It is safe for concurrent use,
+// so several logs may be collected into one Exporter.
+type Exporter struct {
+	mu sync.Mutex
+
+	// maxUsernames caps the number of distinct username labels. Zero disables the username metrics.
+	maxUsernames int
+
+	operations map[string]*counter
+	users      map[userKey]*counter
+	usernames  map[string]bool
+	malformed  int64
+}
+
+// ExporterOptionFunc customizes an Exporter created by New.
+type ExporterOptionFunc func(*Exporter)
+
+// WithUsernames also counts events by username, keeping at most limit usernames as labels so that a log with
+// many users cannot overwhelm Prometheus. Events of further users are counted under OtherUsername.
+func WithUsernames(limit int) ExporterOptionFunc {
+	return func(e *Exporter) {
+		e.maxUsernames = limit
+	}
+}
+
+// New returns an Exporter that has not counted any events yet.
+func New(opts ...ExporterOptionFunc) *Exporter {
+	e := &Exporter{
+		operations: make(map[string]*counter),
+		users:      make(map[userKey]*counter),
+		usernames:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Observe counts ev.
+func (e *Exporter) Observe(ev reader.Event) error {
+	rec, err := reader.NewRecord(ev)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.operations[rec.Op] == nil {
+		e.operations[rec.Op] = &counter{}
+	}
+	e.operations[rec.Op].add(rec.Bytes)
+
+	if e.maxUsernames <= 0 {
+		return nil
+	}
+	username := rec.User
+	if !e.usernames[username] {
+		if len(e.usernames) >= e.maxUsernames {
+			username = OtherUsername
+		} else {
+			e.usernames[username] = true
+		}
+	}
+	key := userKey{username: username, operation: rec.Op}
+	if e.users[key] == nil {
+		e.users[key] = &counter{}
+	}
+	e.users[key].add(rec.Bytes)
+	return nil
+}
+
+// Collect counts every event of r until it returns io.EOF. Malformed records are counted and skipped rather than
+// stopping the collection, since an exporter is expected to keep running; any other error is returned.
+func (e *Exporter) Collect(r reader.Reader) error {
+	var recErr *logfind.RecordError
+	for {
+		ev, err := r.Read()
+		if err == nil {
+			err = e.Observe(ev)
+		}
+		switch {
+		case err == nil:
+		case err == io.EOF:
+			return nil
+		case errors.As(err, &recErr):
+			e.mu.Lock()
+			e.malformed++
+			e.mu.Unlock()
+		default:
+			return err
+		}
+	}
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b strings.Builder
+
+	ops := make([]string, 0, len(e.operations))
+	for op := range e.operations {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	writeHeader(&b, "lf_events_total", "Events read from the logs, by operation.")
+	for _, op := range ops {
+		fmt.Fprintf(&b, "lf_events_total{operation=%s} %d\n", quote(op), e.operations[op].events)
+	}
+	writeHeader(&b, "lf_event_size_kilobytes_total", "Combined size in kB of the events read from the logs, by operation.")
+	for _, op := range ops {
+		fmt.Fprintf(&b, "lf_event_size_kilobytes_total{operation=%s} %s\n", quote(op), kilobytes(e.operations[op].size))
+	}
+
+	if e.maxUsernames > 0 {
+		keys := make([]userKey, 0, len(e.users))
+		for key := range e.users {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].username != keys[j].username {
+				return keys[i].username < keys[j].username
+			}
+			return keys[i].operation < keys[j].operation
+		})
+
+		writeHeader(&b, "lf_user_events_total", "Events read from the logs, by username and operation.")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "lf_user_events_total{username=%s,operation=%s} %d\n", quote(key.username), quote(key.operation), e.users[key].events)
+		}
+		writeHeader(&b, "lf_user_event_size_kilobytes_total", "Combined size in kB of the events read from the logs, by username and operation.")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "lf_user_event_size_kilobytes_total{username=%s,operation=%s} %s\n", quote(key.username), quote(key.operation), kilobytes(e.users[key].size))
+		}
+	}
+
+	writeHeader(&b, "lf_malformed_records_total", "Records that could not be parsed and were skipped.")
+	fmt.Fprintf(&b, "lf_malformed_records_total %d\n", e.malformed)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics, e.g., on /metrics.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteTo(w)
+}
+
+func writeHeader(b *strings.Builder, name, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+}
+
+// quote quotes a label value, escaping backslashes, double quotes and line feeds.
+func quote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+func kilobytes(size reader.Size) string {
+	return strconv.FormatFloat(float64(size)/float64(reader.KB), 'f', -1, 64)
+}
@@ -0,0 +1,94 @@
+package exporter
+
+import (
+	"errors"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+Sun Apr 12 22:49:47 UTC 2020,Maia86,upload,9
+Sun Apr 12 23:00:00 UTC 2020,Maia86,upload,big
+Sun Apr 12 21:23:52 UTC 2020,jeff22,upload,-1.5
+Sun Apr 12 21:23:52 UTC 2020,jeff22,upload,25
+`
+
+func TestExporter(t *testing.T) {
+	t.Run("counts by operation", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.Collect(csv.NewReader(strings.NewReader(input))))
+
+		var b strings.Builder
+		_, err := e.WriteTo(&b)
+		assert.NoError(t, err)
+		assert.Equal(t, `# HELP lf_events_total Events read from the logs, by operation.
+# TYPE lf_events_total counter
+lf_events_total{operation="download"} 2
+lf_events_total{operation="upload"} 2
+# HELP lf_event_size_kilobytes_total Combined size in kB of the events read from the logs, by operation.
+# TYPE lf_event_size_kilobytes_total counter
+lf_event_size_kilobytes_total{operation="download"} 109
+lf_event_size_kilobytes_total{operation="upload"} 34
+# HELP lf_malformed_records_total Records that could not be parsed and were skipped.
+# TYPE lf_malformed_records_total counter
+lf_malformed_records_total 2
+`, b.String())
+	})
+
+	t.Run("caps usernames", func(t *testing.T) {
+		e := New(WithUsernames(2))
+		assert.NoError(t, e.Collect(csv.NewReader(strings.NewReader(input))))
+
+		var b strings.Builder
+		_, err := e.WriteTo(&b)
+		assert.NoError(t, err)
+		assert.Contains(t, b.String(), `# TYPE lf_user_events_total counter
+lf_user_events_total{username="Maia86",operation="download"} 1
+lf_user_events_total{username="Maia86",operation="upload"} 1
+lf_user_events_total{username="other",operation="upload"} 1
+lf_user_events_total{username="sarah94",operation="download"} 1
+`)
+		assert.Contains(t, b.String(), `lf_user_event_size_kilobytes_total{username="other",operation="upload"} 25
+`)
+	})
+
+	t.Run("escapes label values", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.Observe(reader.Record{Op: "up\"load\\\n"}))
+		var b strings.Builder
+		_, err := e.WriteTo(&b)
+		assert.NoError(t, err)
+		assert.Contains(t, b.String(), `lf_events_total{operation="up\"load\\\n"} 1`)
+	})
+
+	t.Run("fails on read error", func(t *testing.T) {
+		errRead := errors.New("read failed")
+		err := New().Collect(failingReader{err: errRead})
+		assert.ErrorIs(t, err, errRead)
+	})
+
+	t.Run("serves metrics", func(t *testing.T) {
+		e := New()
+		assert.NoError(t, e.Observe(reader.Record{Op: "upload", Bytes: 1500}))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `lf_event_size_kilobytes_total{operation="upload"} 1.5`)
+	})
+}
+
+type failingReader struct {
+	err error
+}
+
+func (f failingReader) Read() (reader.Event, error) {
+	return nil, f.err
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+type follower struct {
+	ctx  context.Context
+	f    *os.File
+	poll time.Duration
+}
+
+// Follow returns an io.Reader that reads f like `tail -f`: at the end of the file it waits for more to be written,
+// checking every poll, instead of returning io.EOF. Once ctx is done it returns io.EOF.
+// When f is truncated, e.g., by log rotation with copytruncate, reading starts over from the beginning.
+func Follow(ctx context.Context, f *os.File, poll time.Duration) io.Reader {
+	return &follower{ctx: ctx, f: f, poll: poll}
+}
+
+func (fl *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := fl.f.Read(p)
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+
+		if err = fl.rewindIfTruncated(); err != nil {
+			return 0, err
+		}
+
+		select {
+		case <-fl.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(fl.poll):
+		}
+	}
+}
+
+// Name returns the name of the followed file, which lets its format be detected from its extension.
+func (fl *follower) Name() string {
+	return fl.f.Name()
+}
+
+func (fl *follower) rewindIfTruncated() error {
+	offset, err := fl.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	info, err := fl.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < offset {
+		_, err = fl.f.Seek(0, io.SeekStart)
+	}
+	return err
+}
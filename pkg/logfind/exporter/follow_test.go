@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server_log.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := Follow(ctx, f, time.Millisecond)
+
+	p := make([]byte, 64)
+	n, err := r.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", string(p[:n]))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		w.WriteString("second\n")
+		w.Close()
+	}()
+	n, err = r.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(p[:n]))
+
+	// Truncation starts over from the beginning.
+	assert.NoError(t, os.WriteFile(path, []byte("new\n"), 0o600))
+	n, err = r.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "new\n", string(p[:n]))
+
+	cancel()
+	_, err = r.Read(p)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,203 @@
+package logfind
+
+import (
+	"errors"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"strings"
+	"time"
+)
+
+// NewFilterReader returns a reader.Reader that only yields the events of r that satisfy opts.
+// Malformed records are handled according to the ErrorPolicy given in opts, while options that only
+// concern counting, e.g., WithCountConcern, are ignored.
+//
+// This lets the finder's predicates be reused wherever the matching events themselves are needed,
+// e.g., to print or convert them.
+func NewFilterReader(r reader.Reader, opts ...FinderOptionFunc) (reader.Reader, error) {
+	options, err := newFindOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &filterReader{r: r, options: options}, nil
+}
+
+// Predicate reports whether a single event satisfies the requirements it was created with.
+// A malformed event fails with a *RecordError naming the field that could not be parsed.
+type Predicate func(e reader.Event) (bool, error)
+
+// NewPredicate returns a Predicate testing events against opts one at a time, e.g., as they are appended to a
+// followed log. The ErrorPolicy in opts is left to the caller.
+func NewPredicate(opts ...FinderOptionFunc) (Predicate, error) {
+	options, err := newFindOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return func(e reader.Event) (bool, error) {
+		rec, field, err := readRecord(e)
+		if err != nil {
+			return false, &RecordError{Field: field, Err: err}
+		}
+		return options.match(rec), nil
+	}, nil
+}
+
+type filterReader struct {
+	r       reader.Reader
+	options *finderOptions
+}
+
+var _ reader.Locator = (*filterReader)(nil)
+
+func (f *filterReader) Read() (event reader.Event, err error) {
+	event, _, err = f.next()
+	return
+}
+
+// Location reports the location of the most recently read event when the underlying reader is a reader.Locator.
+func (f *filterReader) Location() reader.Location {
+	if l, ok := f.r.(reader.Locator); ok {
+		return l.Location()
+	}
+	return reader.Location{}
+}
+
+// next reads events until one matches and returns it along with its parsed fields.
+func (f *filterReader) next() (event reader.Event, rec record, err error) {
+	for {
+		var readErr error
+		event, readErr = f.r.Read()
+		if readErr == io.EOF {
+			err = io.EOF
+			return
+		}
+		if readErr != nil {
+			if err = f.handleMalformed("", readErr); err != nil {
+				return
+			}
+			continue
+		}
+
+		var field string
+		var recErr error
+		rec, field, recErr = readRecord(event)
+		if recErr != nil {
+			if err = f.handleMalformed(field, recErr); err != nil {
+				return
+			}
+			continue
+		}
+
+		if f.options.match(rec) {
+			return
+		}
+	}
+}
+
+// handleMalformed applies the ErrorPolicy of options to err, which was encountered while reading field of the most
+// recent record. A nil result means the record should be skipped.
+func (f *filterReader) handleMalformed(field string, err error) error {
+	var recErr *RecordError
+	if !errors.As(err, &recErr) {
+		if field == "" {
+			// The reader itself failed, e.g., an I/O error. There is no record to skip.
+			return err
+		}
+		recErr = &RecordError{Err: err}
+		if l, ok := f.r.(reader.Locator); ok {
+			loc := l.Location()
+			recErr.Source = loc.Source
+			recErr.Line = loc.Line
+			recErr.Offset = loc.Offset
+		}
+	}
+	if recErr.Field == "" {
+		recErr.Field = field
+	}
+
+	switch f.options.policy {
+	case Skip:
+		return nil
+	case SkipAndReport:
+		if f.options.diagnostics != nil {
+			f.options.diagnostics.add(recErr)
+		}
+		return nil
+	default:
+		return recErr
+	}
+}
+
+// match reports whether rec satisfies every requirement of opt.
+func (opt *finderOptions) match(rec record) bool {
+	timestampMatch := true
+	usernameMatch := true
+	operationMatch := true
+	minSizeMatch := true
+	maxSizeMatch := true
+
+	if opt.minTime != nil {
+		timestampMatch = rec.timestamp.After(*opt.minTime) ||
+			opt.minTimeInclusive && rec.timestamp.Equal(*opt.minTime)
+	}
+
+	if opt.maxTime != nil {
+		timestampMatch = timestampMatch && (rec.timestamp.Before(*opt.maxTime) ||
+			opt.maxTimeInclusive && rec.timestamp.Equal(*opt.maxTime))
+	}
+
+	if opt.username != nil {
+		usernameMatch = strings.EqualFold(rec.username, *opt.username)
+	}
+	usernameMatch = usernameMatch && matchAll(opt.usernameMatchers, rec.username)
+
+	if opt.operation != nil {
+		operationMatch = strings.EqualFold(rec.operation, *opt.operation)
+	}
+	operationMatch = operationMatch && matchAll(opt.operationMatchers, rec.operation)
+
+	if opt.minSize != nil {
+		minSizeMatch = rec.size >= *opt.minSize
+	}
+
+	if opt.maxSize != nil {
+		maxSizeMatch = rec.size <= *opt.maxSize
+	}
+
+	return timestampMatch && usernameMatch && operationMatch && minSizeMatch && maxSizeMatch
+}
+
+// matchAll reports whether value satisfies every one of matchers.
+func matchAll(matchers []StringMatcher, value string) bool {
+	for _, m := range matchers {
+		if !m(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// record holds the parsed fields of a reader.Event.
+type record struct {
+	timestamp time.Time
+	username  string
+	operation string
+	size      reader.Size
+}
+
+// readRecord parses every field of e. When a field fails to parse its name is returned along with the error.
+func readRecord(e reader.Event) (rec record, field string, err error) {
+	if rec.timestamp, err = e.Timestamp(); err != nil {
+		return rec, reader.FieldTimestamp, err
+	}
+	if rec.username, err = e.Username(); err != nil {
+		return rec, reader.FieldUsername, err
+	}
+	if rec.operation, err = e.Operation(); err != nil {
+		return rec, reader.FieldOperation, err
+	}
+	if rec.size, err = e.Size(); err != nil {
+		return rec, reader.FieldSize, err
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package logfind
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestNewFilterReader(t *testing.T) {
+	t.Run("yields matching events", func(t *testing.T) {
+		r := newMockReader()
+		fr, err := NewFilterReader(r, WhereUsernameEquals("dex456"))
+		assert.NoError(t, err)
+
+		var got []reader.Event
+		for {
+			e, err := fr.Read()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			got = append(got, e)
+		}
+		assert.Equal(t, []reader.Event{r.events[2], r.events[3]}, got)
+	})
+
+	t.Run("respects error policy", func(t *testing.T) {
+		r := newMalformedMockReader()
+		fr, err := NewFilterReader(r, WhereUsernameEquals("dex456"))
+		assert.NoError(t, err)
+
+		_, err = fr.Read()
+		assert.ErrorIs(t, err, errMock)
+		assert.Equal(t, reader.Location{Source: "mock.log", Line: 3}, fr.(reader.Locator).Location())
+	})
+
+	t.Run("fails on invalid options", func(t *testing.T) {
+		fr, err := NewFilterReader(newMockReader(), WithErrorPolicy("ignore"))
+		assert.ErrorIs(t, err, ErrErrorPolicyInvalid)
+		assert.Nil(t, fr)
+	})
+}
+
+func TestNewPredicate(t *testing.T) {
+	t.Run("matches events", func(t *testing.T) {
+		r := newMockReader()
+		match, err := NewPredicate(WhereUsernameEquals("dex456"), WhereOperationEquals("upload"))
+		assert.NoError(t, err)
+
+		var got []bool
+		for _, e := range r.events {
+			ok, err := match(e)
+			assert.NoError(t, err)
+			got = append(got, ok)
+		}
+		assert.Equal(t, []bool{false, false, true, false, false}, got)
+	})
+
+	t.Run("fails on malformed event", func(t *testing.T) {
+		match, err := NewPredicate()
+		assert.NoError(t, err)
+
+		_, err = match(newMalformedMockReader().events[2])
+		var recErr *RecordError
+		assert.ErrorAs(t, err, &recErr)
+		assert.Equal(t, reader.FieldTimestamp, recErr.Field)
+		assert.ErrorIs(t, err, errMock)
+	})
+
+	t.Run("fails on invalid options", func(t *testing.T) {
+		match, err := NewPredicate(WithErrorPolicy("ignore"))
+		assert.ErrorIs(t, err, ErrErrorPolicyInvalid)
+		assert.Nil(t, match)
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/kyleishie/logfind/pkg/logfind/reader"
 	"io"
-	"strings"
 	"time"
 )
 
@@ -51,69 +50,30 @@ func (f *defaultFinder) Find(opts ...FinderOptionFunc) (count int, events []stri
 		}()
 	}
 
+	fr := &filterReader{r: f.r, options: options}
 	for {
-		event, err := f.r.Read()
-		if err != nil {
-			break
-		}
-
-		timestampMatch := true
-		usernameMatch := true
-		operationMatch := true
-		minSizeMatch := true
-		maxSizeMatch := true
-
-		timestamp, err := event.Timestamp()
-		if err != nil {
-			break
-		}
-		if options.minTime != nil && options.maxTime != nil {
-			timestampMatch = timestamp.After(*options.minTime) && timestamp.Before(*options.maxTime)
-		}
-
-		username, err := event.Username()
-		if err != nil {
-			break
-		}
-		if options.username != nil {
-			usernameMatch = strings.EqualFold(username, *options.username)
-		}
-
-		operation, err := event.Operation()
-		if err != nil {
+		var rec record
+		_, rec, err = fr.next()
+		if err == io.EOF {
+			err = nil
 			break
 		}
-		if options.operation != nil {
-			operationMatch = strings.EqualFold(operation, *options.operation)
-		}
-
-		size, err := event.Size()
 		if err != nil {
-			break
-		}
-		if options.minSize != nil {
-			minSizeMatch = size >= *options.minSize
-		}
-
-		if options.maxSize != nil {
-			maxSizeMatch = size <= *options.maxSize
+			return
 		}
 
-		if timestampMatch && usernameMatch && operationMatch && minSizeMatch && maxSizeMatch {
-			/// Match Found
-			events = append(events, fmt.Sprintf("%s %s %s %d", timestamp.Format(time.UnixDate), username, operation, size))
-
-			/// Count the uniqueness of the match based on countConcern
-			switch options.cc {
-			case Event:
-				count++
-			case Operation:
-				countMap[operation] = true
-			case User:
-				countMap[username] = true
-			}
+		/// Match Found
+		events = append(events, fmt.Sprintf("%s %s %s %s", rec.timestamp.Format(time.UnixDate), rec.username, rec.operation, rec.size))
+
+		/// Count the uniqueness of the match based on countConcern
+		switch options.cc {
+		case Event:
+			count++
+		case Operation:
+			countMap[rec.operation] = true
+		case User:
+			countMap[rec.username] = true
 		}
-
 	}
 
 	return
@@ -1,10 +1,12 @@
 package logfind
 
 import (
+	"errors"
 	"fmt"
 	"github.com/kyleishie/logfind/pkg/logfind/reader"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -13,11 +15,13 @@ type mockEvent struct {
 	timestamp time.Time
 	username  string
 	operation string
-	size      int
+	size      reader.Size
+
+	timestampErr error
 }
 
 func (m mockEvent) Timestamp() (time.Time, error) {
-	return m.timestamp, nil
+	return m.timestamp, m.timestampErr
 }
 
 func (m mockEvent) Username() (string, error) {
@@ -28,7 +32,7 @@ func (m mockEvent) Operation() (string, error) {
 	return m.operation, nil
 }
 
-func (m mockEvent) Size() (int, error) {
+func (m mockEvent) Size() (reader.Size, error) {
 	return m.size, nil
 }
 
@@ -47,6 +51,24 @@ func (m *mockReader) Read() (e reader.Event, err error) {
 	return
 }
 
+// Location reports the line of the last event as if the mock events were a headerless file named mock.log.
+func (m *mockReader) Location() reader.Location {
+	return reader.Location{Source: "mock.log", Line: m.i}
+}
+
+// newMalformedMockReader returns a mockReader whose third event has an unparsable timestamp.
+func newMalformedMockReader() *mockReader {
+	r := newMockReader()
+	r.events[2].timestampErr = errMock
+	return r
+}
+
+type failingReader struct{}
+
+func (failingReader) Read() (reader.Event, error) {
+	return nil, errMock
+}
+
 func newMockReader() *mockReader {
 	return &mockReader{
 		events: []mockEvent{
@@ -54,31 +76,31 @@ func newMockReader() *mockReader {
 				timestamp: time.Date(2020, 03, 12, 22, 10, 38, 0, time.UTC),
 				username:  "kyle123",
 				operation: "upload",
-				size:      10,
+				size:      10 * reader.KB,
 			},
 			{
 				timestamp: time.Date(2020, 03, 12, 22, 10, 38, 0, time.UTC),
 				username:  "kyle123",
 				operation: "download",
-				size:      20,
+				size:      20 * reader.KB,
 			},
 			{
 				timestamp: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC),
 				username:  "dex456",
 				operation: "upload",
-				size:      66,
+				size:      66 * reader.KB,
 			},
 			{
 				timestamp: time.Date(2020, 05, 12, 22, 10, 38, 0, time.UTC),
 				username:  "dex456",
 				operation: "download",
-				size:      1,
+				size:      1 * reader.KB,
 			},
 			{
 				timestamp: time.Date(2020, 05, 13, 22, 10, 38, 0, time.UTC),
 				username:  "kait789",
 				operation: "download",
-				size:      1024,
+				size:      1024 * reader.KB,
 			},
 		},
 	}
@@ -94,11 +116,11 @@ func Test_defaultFinder_Find(t *testing.T) {
 		/// This should be 2 in the case where countConcern is user
 		assert.Equal(t, len(r.events), count)
 		assert.Equal(t, []string{
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10",
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20",
-			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66",
-			"Tue May 12 22:10:38 UTC 2020 dex456 download 1",
-			"Wed May 13 22:10:38 UTC 2020 kait789 download 1024",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10kB",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20kB",
+			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66kB",
+			"Tue May 12 22:10:38 UTC 2020 dex456 download 1kB",
+			"Wed May 13 22:10:38 UTC 2020 kait789 download 1.024MB",
 		}, events)
 
 		fmt.Println(events)
@@ -133,11 +155,58 @@ func Test_defaultFinder_Find(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 2, count)
 		assert.Equal(t, []string{
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10",
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10kB",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20kB",
 		}, events)
 	})
 
+	t.Run("can match username case sensitively", func(t *testing.T) {
+		r := newMockReader()
+		f := NewFinder(r)
+		count, _, err := f.Find(
+			WhereUsername(Exactly("Kyle123")),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("can match username by regex", func(t *testing.T) {
+		r := newMockReader()
+		f := NewFinder(r)
+		count, events, err := f.Find(
+			WhereUsernameMatches(regexp.MustCompile(`^k.*\d{3}$`)),
+			WhereOperation(HasPrefix("down")),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, []string{
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20kB",
+			"Wed May 13 22:10:38 UTC 2020 kait789 download 1.024MB",
+		}, events)
+	})
+
+	t.Run("can match username set", func(t *testing.T) {
+		r := newMockReader()
+		f := NewFinder(r)
+		count, _, err := f.Find(
+			WhereUsernameIn([]string{"kyle123", "kait789"}),
+			WhereOperationNotIn([]string{"upload"}),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("can exclude username set", func(t *testing.T) {
+		r := newMockReader()
+		f := NewFinder(r)
+		count, _, err := f.Find(
+			WithCountConcern(User),
+			WhereUsernameNotIn([]string{"kyle123", "kait789"}),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
 	t.Run("can match time range", func(t *testing.T) {
 		r := newMockReader()
 		f := NewFinder(r)
@@ -150,11 +219,39 @@ func Test_defaultFinder_Find(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 2, count)
 		assert.Equal(t, []string{
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10",
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10kB",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20kB",
 		}, events)
 	})
 
+	t.Run("can match open ended time range", func(t *testing.T) {
+		r := newMockReader()
+		f := NewFinder(r)
+		count, events, err := f.Find(
+			WhereTimestampAfter(time.Date(2020, 05, 01, 00, 00, 00, 0, time.UTC)),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, []string{
+			"Tue May 12 22:10:38 UTC 2020 dex456 download 1kB",
+			"Wed May 13 22:10:38 UTC 2020 kait789 download 1.024MB",
+		}, events)
+	})
+
+	t.Run("respects time range bounds", func(t *testing.T) {
+		min := time.Date(2020, 03, 12, 22, 10, 38, 0, time.UTC)
+		max := time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC)
+		for bounds, want := range map[Bounds]int{Inclusive: 3, Exclusive: 0, InclusiveMin: 2, InclusiveMax: 1} {
+			r := newMockReader()
+			f := NewFinder(r)
+			count, _, err := f.Find(
+				WhereTimestampIsWithin(min, max, bounds),
+			)
+			assert.NoError(t, err)
+			assert.Equal(t, want, count, bounds)
+		}
+	})
+
 	t.Run("can match operation", func(t *testing.T) {
 		r := newMockReader()
 		f := NewFinder(r)
@@ -164,8 +261,8 @@ func Test_defaultFinder_Find(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 2, count)
 		assert.Equal(t, []string{
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10",
-			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10kB",
+			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66kB",
 		}, events)
 	})
 
@@ -179,13 +276,83 @@ func Test_defaultFinder_Find(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 3, count)
 		assert.Equal(t, []string{
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10",
-			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20",
-			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 upload 10kB",
+			"Thu Mar 12 22:10:38 UTC 2020 kyle123 download 20kB",
+			"Sun Apr 12 22:10:38 UTC 2020 dex456 upload 66kB",
 		}, events)
 	})
 
-	//TODO: More tests to prove query combinations
+	t.Run("fails on malformed record by default", func(t *testing.T) {
+		r := newMalformedMockReader()
+		f := NewFinder(r)
+		count, events, err := f.Find()
+		assert.ErrorIs(t, err, errMock)
+		var recErr *RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, &RecordError{Source: "mock.log", Line: 3, Field: reader.FieldTimestamp, Err: errMock}, recErr)
+		assert.Equal(t, "mock.log:3: timestamp: mock", err.Error())
+		assert.Zero(t, count)
+		assert.Nil(t, events)
+	})
+
+	t.Run("propagates record errors from the reader", func(t *testing.T) {
+		r := newMockReader()
+		readerErr := &RecordError{Source: "server_log.csv", Line: 9, Offset: 330, Field: reader.FieldTimestamp, Value: "yesterday", Err: errMock}
+		r.events[2].timestampErr = readerErr
+		f := NewFinder(r)
+		_, _, err := f.Find()
+		var recErr *RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Same(t, readerErr, recErr)
+	})
 
-	//TODO: Test error conditions
+	t.Run("can skip malformed records", func(t *testing.T) {
+		r := newMalformedMockReader()
+		f := NewFinder(r)
+		count, events, err := f.Find(
+			WithErrorPolicy(Skip),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+		assert.Len(t, events, 4)
+	})
+
+	t.Run("can skip and report malformed records", func(t *testing.T) {
+		r := newMalformedMockReader()
+		f := NewFinder(r)
+		var diagnostics Diagnostics
+		count, _, err := f.Find(
+			WithErrorPolicy(SkipAndReport),
+			WithDiagnostics(&diagnostics),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+		assert.Equal(t, 1, diagnostics.Skipped)
+		assert.Equal(t, map[string]int{reader.FieldTimestamp: 1}, diagnostics.ByField)
+		assert.Equal(t, []*RecordError{
+			{Source: "mock.log", Line: 3, Field: reader.FieldTimestamp, Err: errMock},
+		}, diagnostics.Errors)
+	})
+
+	t.Run("can only count malformed records", func(t *testing.T) {
+		diagnostics := Diagnostics{CountOnly: true}
+		_, _, err := NewFinder(newMalformedMockReader()).Find(
+			WithErrorPolicy(SkipAndReport),
+			WithDiagnostics(&diagnostics),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, diagnostics.Skipped)
+		assert.Equal(t, map[string]int{reader.FieldTimestamp: 1}, diagnostics.ByField)
+		assert.Empty(t, diagnostics.Errors)
+	})
+
+	t.Run("reader failures are fatal regardless of policy", func(t *testing.T) {
+		f := NewFinder(failingReader{})
+		_, _, err := f.Find(
+			WithErrorPolicy(Skip),
+		)
+		assert.ErrorIs(t, err, errMock)
+	})
+
+	//TODO: More tests to prove query combinations
 }
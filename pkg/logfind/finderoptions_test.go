@@ -1,7 +1,9 @@
 package logfind
 
 import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
 	"github.com/stretchr/testify/assert"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -21,10 +23,14 @@ func TestNewFindOptions(t *testing.T) {
 		opts, err := newFindOptions()
 		assert.NoError(t, err)
 		assert.Equal(t, Event, opts.cc)
+		assert.Equal(t, Strict, opts.policy)
+		assert.Nil(t, opts.diagnostics)
 		assert.Nil(t, opts.username)
+		assert.Nil(t, opts.usernameMatchers)
 		assert.Nil(t, opts.minTime)
 		assert.Nil(t, opts.maxTime)
 		assert.Nil(t, opts.operation)
+		assert.Nil(t, opts.operationMatchers)
 		assert.Nil(t, opts.minSize)
 		assert.Nil(t, opts.maxSize)
 	})
@@ -37,6 +43,15 @@ func TestNewFindOptions(t *testing.T) {
 		assert.Nil(t, opts)
 	})
 
+	t.Run("validates time range across opt funcs", func(t *testing.T) {
+		opts, err := newFindOptions(
+			WhereTimestampAfter(time.Date(2022, 12, 31, 11, 59, 59, 0, time.UTC)),
+			WhereTimestampBefore(time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC)),
+		)
+		assert.ErrorIs(t, err, ErrTimeRangeInvalid)
+		assert.Nil(t, opts)
+	})
+
 	t.Run("calls opt funcs", func(t *testing.T) {
 		var called bool
 		// Flip called each time fn is called
@@ -80,6 +95,36 @@ func TestWithUsername(t *testing.T) {
 
 }
 
+func TestWhereUsername(t *testing.T) {
+
+	t.Run("appends matcher", func(t *testing.T) {
+		opt := finderOptions{}
+		/// Check default state because I'm paranoid
+		assert.Nil(t, opt.usernameMatchers)
+
+		assert.NoError(t, WhereUsername(Exactly("Maia86"))(&opt))
+		assert.NoError(t, WhereUsernameMatches(regexp.MustCompile("^M"))(&opt))
+		assert.Len(t, opt.usernameMatchers, 2)
+		assert.Nil(t, opt.username)
+	})
+
+}
+
+func TestWhereOperation(t *testing.T) {
+
+	t.Run("appends matcher", func(t *testing.T) {
+		opt := finderOptions{}
+		/// Check default state because I'm paranoid
+		assert.Nil(t, opt.operationMatchers)
+
+		assert.NoError(t, WhereOperation(Contains("load"))(&opt))
+		assert.NoError(t, WhereOperationMatches(regexp.MustCompile("^up"))(&opt))
+		assert.Len(t, opt.operationMatchers, 2)
+		assert.Nil(t, opt.operation)
+	})
+
+}
+
 func TestWithTimeRange(t *testing.T) {
 
 	t.Run("sets opt field(s)", func(t *testing.T) {
@@ -116,6 +161,117 @@ func TestWithTimeRange(t *testing.T) {
 
 }
 
+func TestWhereTimestampIsWithin(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		fn := WhereTimestampIsWithin(
+			time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC),
+			time.Date(2022, 12, 31, 11, 59, 59, 0, time.UTC),
+			InclusiveMin,
+		)
+		opt := finderOptions{}
+
+		err := fn(&opt)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC), *opt.minTime)
+		assert.Equal(t, time.Date(2022, 12, 31, 11, 59, 59, 0, time.UTC), *opt.maxTime)
+		assert.True(t, opt.minTimeInclusive)
+		assert.False(t, opt.maxTimeInclusive)
+	})
+
+	t.Run("rejects unknown bounds", func(t *testing.T) {
+		fn := WhereTimestampIsWithin(
+			time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC),
+			time.Date(2022, 12, 31, 11, 59, 59, 0, time.UTC),
+			"[[",
+		)
+		opt := finderOptions{}
+
+		err := fn(&opt)
+		assert.ErrorIs(t, err, ErrBoundsInvalid)
+		assert.Nil(t, opt.minTime)
+		assert.Nil(t, opt.maxTime)
+	})
+
+}
+
+func TestWhereTimestampAfter(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		opt := finderOptions{}
+		assert.NoError(t, WhereTimestampAfter(time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC))(&opt))
+		assert.Equal(t, time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC), *opt.minTime)
+		assert.False(t, opt.minTimeInclusive)
+		assert.Nil(t, opt.maxTime)
+
+		assert.NoError(t, WhereTimestampAtOrAfter(time.Date(2022, 02, 01, 00, 00, 00, 0, time.UTC))(&opt))
+		assert.Equal(t, time.Date(2022, 02, 01, 00, 00, 00, 0, time.UTC), *opt.minTime)
+		assert.True(t, opt.minTimeInclusive)
+	})
+
+}
+
+func TestWhereTimestampBefore(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		opt := finderOptions{}
+		assert.NoError(t, WhereTimestampBefore(time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC))(&opt))
+		assert.Equal(t, time.Date(2022, 01, 01, 00, 00, 00, 0, time.UTC), *opt.maxTime)
+		assert.False(t, opt.maxTimeInclusive)
+		assert.Nil(t, opt.minTime)
+
+		assert.NoError(t, WhereTimestampAtOrBefore(time.Date(2021, 12, 01, 00, 00, 00, 0, time.UTC))(&opt))
+		assert.Equal(t, time.Date(2021, 12, 01, 00, 00, 00, 0, time.UTC), *opt.maxTime)
+		assert.True(t, opt.maxTimeInclusive)
+	})
+
+}
+
+func TestTimestampRequirementsIntersect(t *testing.T) {
+
+	t.Run("keeps the later min and the earlier max", func(t *testing.T) {
+		opt, err := newFindOptions(
+			WhereTimestampIsWithin(time.Date(2020, 04, 15, 0, 0, 0, 0, time.UTC), time.Date(2020, 04, 16, 0, 0, 0, 0, time.UTC), InclusiveMin),
+			WhereTimestampBefore(time.Date(2020, 04, 15, 12, 0, 0, 0, time.UTC)),
+			WhereTimestampAtOrAfter(time.Date(2020, 04, 1, 0, 0, 0, 0, time.UTC)),
+			WhereTimestampAtOrBefore(time.Date(2020, 04, 20, 0, 0, 0, 0, time.UTC)),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2020, 04, 15, 0, 0, 0, 0, time.UTC), *opt.minTime)
+		assert.True(t, opt.minTimeInclusive)
+		assert.Equal(t, time.Date(2020, 04, 15, 12, 0, 0, 0, time.UTC), *opt.maxTime)
+		assert.False(t, opt.maxTimeInclusive)
+	})
+
+	t.Run("excludes ties unless both bounds include them", func(t *testing.T) {
+		day := time.Date(2020, 04, 15, 0, 0, 0, 0, time.UTC)
+		opt, err := newFindOptions(WhereTimestampAtOrAfter(day), WhereTimestampAfter(day), WhereTimestampAtOrAfter(day),
+			WhereTimestampBefore(day.Add(time.Hour)), WhereTimestampAtOrBefore(day.Add(time.Hour)))
+		assert.NoError(t, err)
+		assert.False(t, opt.minTimeInclusive)
+		assert.False(t, opt.maxTimeInclusive)
+	})
+
+	t.Run("fails on disjoint requirements", func(t *testing.T) {
+		_, err := newFindOptions(WhereTimestampAfter(time.Date(2020, 04, 16, 0, 0, 0, 0, time.UTC)),
+			WhereTimestampBefore(time.Date(2020, 04, 15, 0, 0, 0, 0, time.UTC)))
+		assert.ErrorIs(t, err, ErrTimeRangeInvalid)
+	})
+
+}
+
+func TestSizeRequirementsIntersect(t *testing.T) {
+
+	t.Run("keeps the larger min and the smaller max", func(t *testing.T) {
+		opt, err := newFindOptions(WhereSizeAtLeast(10*reader.KB), WhereSizeAtLeast(5*reader.KB),
+			WhereSizeAtMost(20*reader.KB), WhereSizeAtMost(30*reader.KB))
+		assert.NoError(t, err)
+		assert.Equal(t, 10*reader.KB, *opt.minSize)
+		assert.Equal(t, 20*reader.KB, *opt.maxSize)
+	})
+
+}
+
 func TestWithOperation(t *testing.T) {
 
 	t.Run("sets opt field(s)", func(t *testing.T) {
@@ -142,7 +298,7 @@ func TestWithMinSize(t *testing.T) {
 
 		err := fn(&opt)
 		assert.NoError(t, err)
-		assert.Equal(t, 10, *opt.minSize)
+		assert.Equal(t, 10*reader.KB, *opt.minSize)
 		assert.Nil(t, opt.maxSize)
 	})
 
@@ -160,7 +316,75 @@ func TestWithMaxSize(t *testing.T) {
 		err := fn(&opt)
 		assert.NoError(t, err)
 		assert.Nil(t, opt.minSize)
-		assert.Equal(t, 10, *opt.maxSize)
+		assert.Equal(t, 10*reader.KB, *opt.maxSize)
+	})
+
+}
+
+func TestWithErrorPolicy(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		fn := WithErrorPolicy(SkipAndReport)
+		opt := finderOptions{}
+		/// Check default state because I'm paranoid
+		assert.Empty(t, opt.policy)
+
+		err := fn(&opt)
+		assert.NoError(t, err)
+		assert.Equal(t, SkipAndReport, opt.policy)
+	})
+
+	t.Run("rejects unknown policy", func(t *testing.T) {
+		fn := WithErrorPolicy("ignore")
+		opt := finderOptions{}
+
+		err := fn(&opt)
+		assert.ErrorIs(t, err, ErrErrorPolicyInvalid)
+		assert.Empty(t, opt.policy)
+	})
+
+}
+
+func TestWithDiagnostics(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		d := &Diagnostics{}
+		fn := WithDiagnostics(d)
+		opt := finderOptions{}
+		/// Check default state because I'm paranoid
+		assert.Nil(t, opt.diagnostics)
+
+		err := fn(&opt)
+		assert.NoError(t, err)
+		assert.Same(t, d, opt.diagnostics)
+	})
+
+}
+
+func TestWhereSizeAtLeast(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		fn := WhereSizeAtLeast(512 * reader.KiB)
+		opt := finderOptions{}
+
+		err := fn(&opt)
+		assert.NoError(t, err)
+		assert.Equal(t, reader.Size(524288), *opt.minSize)
+		assert.Nil(t, opt.maxSize)
+	})
+
+}
+
+func TestWhereSizeAtMost(t *testing.T) {
+
+	t.Run("sets opt field(s)", func(t *testing.T) {
+		fn := WhereSizeAtMost(1500 * reader.Byte)
+		opt := finderOptions{}
+
+		err := fn(&opt)
+		assert.NoError(t, err)
+		assert.Nil(t, opt.minSize)
+		assert.Equal(t, reader.Size(1500), *opt.maxSize)
 	})
 
 }
@@ -1,23 +1,35 @@
 package logfind
 
-import "time"
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"regexp"
+	"time"
+)
 
 type finderOptions struct {
-	cc       CountConcern
-	username *string
+	cc               CountConcern
+	username         *string
+	usernameMatchers []StringMatcher
+
+	minTime          *time.Time
+	maxTime          *time.Time
+	minTimeInclusive bool
+	maxTimeInclusive bool
 
-	minTime *time.Time
-	maxTime *time.Time
+	operation         *string
+	operationMatchers []StringMatcher
 
-	operation *string
+	minSize *reader.Size
+	maxSize *reader.Size
 
-	minSize *int
-	maxSize *int
+	policy      ErrorPolicy
+	diagnostics *Diagnostics
 }
 
 func newFindOptions(opts ...FinderOptionFunc) (opt *finderOptions, err error) {
 	opt = &finderOptions{
-		cc: Event,
+		cc:     Event,
+		policy: Strict,
 	}
 
 	for _, optionFunc := range opts {
@@ -28,6 +40,12 @@ func newFindOptions(opts ...FinderOptionFunc) (opt *finderOptions, err error) {
 		}
 	}
 
+	// The bounds may have been given by separate options so the range can only be validated once all are applied.
+	if opt.minTime != nil && opt.maxTime != nil && opt.maxTime.Before(*opt.minTime) {
+		opt = nil
+		err = ErrTimeRangeInvalid
+	}
+
 	return
 }
 
@@ -62,15 +80,125 @@ func WhereUsernameEquals(username string) FinderOptionFunc {
 	}
 }
 
+// WhereUsername adds the requirement that matching log events username value satisfies m.
+// It may be given more than once in which case every StringMatcher must be satisfied.
+func WhereUsername(m StringMatcher) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		opt.usernameMatchers = append(opt.usernameMatchers, m)
+		return nil
+	}
+}
+
+// WhereUsernameMatches adds the requirement that matching log events username value contains a match of re.
+func WhereUsernameMatches(re *regexp.Regexp) FinderOptionFunc {
+	return WhereUsername(Regexp(re))
+}
+
+// WhereUsernameIn adds the requirement that matching log events username value is exactly one of usernames.
+func WhereUsernameIn(usernames []string) FinderOptionFunc {
+	return WhereUsername(In(usernames))
+}
+
+// WhereUsernameNotIn adds the requirement that matching log events username value is none of usernames.
+func WhereUsernameNotIn(usernames []string) FinderOptionFunc {
+	return WhereUsername(NotIn(usernames))
+}
+
 // WhereTimestampIsBetween adds the requirement that matching log events timestamp value is between the given time range.
+//
+// Note: Both bounds are exclusive. See WhereTimestampIsWithin for control over inclusivity.
 func WhereTimestampIsBetween(min, max time.Time) FinderOptionFunc {
+	return WhereTimestampIsWithin(min, max, Exclusive)
+}
+
+// Bounds describes which ends of a range are included in it.
+type Bounds string
+
+const (
+	// Inclusive - Both ends are included, i.e., [min, max].
+	Inclusive = Bounds("[]")
+	// Exclusive - Neither end is included, i.e., (min, max).
+	Exclusive = Bounds("()")
+	// InclusiveMin - Only the lower end is included, i.e., [min, max).
+	InclusiveMin = Bounds("[)")
+	// InclusiveMax - Only the upper end is included, i.e., (min, max].
+	InclusiveMax = Bounds("(]")
+)
+
+// WhereTimestampIsWithin adds the requirement that matching log events timestamp value is within the given time range,
+// where bounds determines whether min and max themselves are part of the range.
+//
+// Note: Time requirements add up, e.g., WhereTimestampIsWithin and WhereTimestampBefore match the timestamps satisfying
+// both, so the later minimum and the earlier maximum apply.
+func WhereTimestampIsWithin(min, max time.Time, bounds Bounds) FinderOptionFunc {
 	return func(opt *finderOptions) error {
 		if max.Before(min) {
 			return ErrTimeRangeInvalid
 		}
+		switch bounds {
+		case Inclusive, Exclusive, InclusiveMin, InclusiveMax:
+		default:
+			return ErrBoundsInvalid
+		}
+
+		opt.timeAtLeast(min, bounds == Inclusive || bounds == InclusiveMin)
+		opt.timeAtMost(max, bounds == Inclusive || bounds == InclusiveMax)
+		return nil
+	}
+}
 
+// timeAtLeast narrows the time range to start at min, keeping the current start when it is later. On a tie the range
+// includes min only if both bounds do.
+func (opt *finderOptions) timeAtLeast(min time.Time, inclusive bool) {
+	switch {
+	case opt.minTime == nil || min.After(*opt.minTime):
 		opt.minTime = &min
+		opt.minTimeInclusive = inclusive
+	case min.Equal(*opt.minTime):
+		opt.minTimeInclusive = opt.minTimeInclusive && inclusive
+	}
+}
+
+// timeAtMost narrows the time range to end at max, keeping the current end when it is earlier. On a tie the range
+// includes max only if both bounds do.
+func (opt *finderOptions) timeAtMost(max time.Time, inclusive bool) {
+	switch {
+	case opt.maxTime == nil || max.Before(*opt.maxTime):
 		opt.maxTime = &max
+		opt.maxTimeInclusive = inclusive
+	case max.Equal(*opt.maxTime):
+		opt.maxTimeInclusive = opt.maxTimeInclusive && inclusive
+	}
+}
+
+// WhereTimestampAfter adds the requirement that matching log events timestamp value is after min.
+func WhereTimestampAfter(min time.Time) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		opt.timeAtLeast(min, false)
+		return nil
+	}
+}
+
+// WhereTimestampAtOrAfter adds the requirement that matching log events timestamp value is equal to or after min.
+func WhereTimestampAtOrAfter(min time.Time) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		opt.timeAtLeast(min, true)
+		return nil
+	}
+}
+
+// WhereTimestampBefore adds the requirement that matching log events timestamp value is before max.
+func WhereTimestampBefore(max time.Time) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		opt.timeAtMost(max, false)
+		return nil
+	}
+}
+
+// WhereTimestampAtOrBefore adds the requirement that matching log events timestamp value is equal to or before max.
+func WhereTimestampAtOrBefore(max time.Time) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		opt.timeAtMost(max, true)
 		return nil
 	}
 }
@@ -83,22 +211,95 @@ func WhereOperationEquals(operation string) FinderOptionFunc {
 	}
 }
 
-// WhereSizeGreaterThanOrEqual adds the requirement that matching log events size value is greater than or equal to min.
-//
-// Note: Size is represented in kB.
-func WhereSizeGreaterThanOrEqual(min int) FinderOptionFunc {
+// WhereOperation adds the requirement that matching log events operation value satisfies m.
+// It may be given more than once in which case every StringMatcher must be satisfied.
+func WhereOperation(m StringMatcher) FinderOptionFunc {
 	return func(opt *finderOptions) error {
-		opt.minSize = &min
+		opt.operationMatchers = append(opt.operationMatchers, m)
 		return nil
 	}
 }
 
+// WhereOperationMatches adds the requirement that matching log events operation value contains a match of re.
+func WhereOperationMatches(re *regexp.Regexp) FinderOptionFunc {
+	return WhereOperation(Regexp(re))
+}
+
+// WhereOperationIn adds the requirement that matching log events operation value is exactly one of operations.
+func WhereOperationIn(operations []string) FinderOptionFunc {
+	return WhereOperation(In(operations))
+}
+
+// WhereOperationNotIn adds the requirement that matching log events operation value is none of operations.
+func WhereOperationNotIn(operations []string) FinderOptionFunc {
+	return WhereOperation(NotIn(operations))
+}
+
+// WhereSizeGreaterThanOrEqual adds the requirement that matching log events size value is greater than or equal to min.
+//
+// Note: min is represented in kB. Use WhereSizeAtLeast to give a size in any unit.
+func WhereSizeGreaterThanOrEqual(min int) FinderOptionFunc {
+	return WhereSizeAtLeast(reader.Size(min) * reader.KB)
+}
+
 // WhereSizeLessThanOrEqual adds the requirement that matching log events size value is less than or equal to max.
 //
-// Note: Size is represented in kB.
+// Note: max is represented in kB. Use WhereSizeAtMost to give a size in any unit.
 func WhereSizeLessThanOrEqual(max int) FinderOptionFunc {
+	return WhereSizeAtMost(reader.Size(max) * reader.KB)
+}
+
+// WhereSizeAtLeast adds the requirement that matching log events size value is greater than or equal to min.
+// Given more than once, the largest minimum applies.
+func WhereSizeAtLeast(min reader.Size) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		if opt.minSize == nil || min > *opt.minSize {
+			opt.minSize = &min
+		}
+		return nil
+	}
+}
+
+// WhereSizeAtMost adds the requirement that matching log events size value is less than or equal to max.
+// Given more than once, the smallest maximum applies.
+func WhereSizeAtMost(max reader.Size) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		if opt.maxSize == nil || max < *opt.maxSize {
+			opt.maxSize = &max
+		}
+		return nil
+	}
+}
+
+// ErrorPolicy determines how a Finder reacts to log records that cannot be read or interpreted.
+type ErrorPolicy string
+
+const (
+	// Strict - When strict is used, the first malformed record stops the search and is returned as a *RecordError. This is the default.
+	Strict = ErrorPolicy("strict")
+	// Skip - When skip is used, malformed records are silently ignored.
+	Skip = ErrorPolicy("skip")
+	// SkipAndReport - When skip-and-report is used, malformed records are ignored and tallied in the Diagnostics given to WithDiagnostics.
+	SkipAndReport = ErrorPolicy("skip-and-report")
+)
+
+// WithErrorPolicy customizes how the Finder handles malformed records. See the constant ErrorPolicies for details.
+func WithErrorPolicy(policy ErrorPolicy) FinderOptionFunc {
+	return func(opt *finderOptions) error {
+		switch policy {
+		case Strict, Skip, SkipAndReport:
+			opt.policy = policy
+			return nil
+		default:
+			return ErrErrorPolicyInvalid
+		}
+	}
+}
+
+// WithDiagnostics gives the Finder a Diagnostics to fill in with the records it skips under the SkipAndReport policy.
+func WithDiagnostics(d *Diagnostics) FinderOptionFunc {
 	return func(opt *finderOptions) error {
-		opt.maxSize = &max
+		opt.diagnostics = d
 		return nil
 	}
 }
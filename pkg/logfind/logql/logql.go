@@ -0,0 +1,453 @@
+// Package logql translates a subset of Loki's query language, LogQL, into logfind predicates and aggregations.
+//
+// Log queries select events with a stream selector over the username and operation labels, optionally followed by
+// label filters, e.g., `{operation="upload"} | username="jeff22" and size > 50kB`. The logfmt and json parser stages
+// are accepted and ignored, since events already expose their fields as labels. Line filters are not supported.
+//
+// Metric queries wrap a log query in count_over_time, or in sum_over_time over `| unwrap size`, optionally summed by
+// labels, e.g., `sum by (username) (count_over_time({operation="upload"}[1h]))`.
+package logql
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ErrSyntax      = logfind.Error("syntax error")
+	ErrUnsupported = logfind.Error("unsupported")
+	ErrStepInvalid = logfind.Error("invalid step, must be positive")
+)
+
+// Range aggregations.
+const (
+	// CountOverTime counts the events in each range.
+	CountOverTime = "count_over_time"
+	// SumOverTime sums the sizes, in bytes, of the events in each range.
+	SumOverTime = "sum_over_time"
+)
+
+// Labels are the labels that selectors and filters may use.
+var Labels = []string{reader.FieldUsername, reader.FieldOperation}
+
+// Query is a parsed LogQL query.
+type Query struct {
+	// Options hold the predicates of the selector and label filters.
+	Options []logfind.FinderOptionFunc
+	// Metric describes the aggregation of a metric query. It is nil for log queries.
+	Metric *Metric
+}
+
+// Metric is the aggregation of a metric query.
+type Metric struct {
+	// Func is CountOverTime or SumOverTime.
+	Func string
+	// Range is the duration of the range each point aggregates, ending at the point.
+	Range time.Duration
+	// Sum is set when the series are summed, grouped by the labels in By.
+	Sum bool
+	By  []string
+}
+
+// Series is a result of a metric query.
+type Series struct {
+	Labels map[string]string
+	Points []Point
+}
+
+// Point is the value of a Series at a time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Parse parses a LogQL query.
+func Parse(expr string) (*Query, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, syntaxError(t.pos, fmt.Sprintf("unexpected %q", t.value))
+	}
+	return q, nil
+}
+
+// Evaluate runs a metric query over the events of r from start to end, with a point every step.
+// Like Loki, a series has no point at times whose range holds no events.
+func (q *Query) Evaluate(r reader.Reader, start, end time.Time, step time.Duration) ([]Series, error) {
+	if q.Metric == nil {
+		return nil, fmt.Errorf("%w: log queries have no series, read their events instead", ErrUnsupported)
+	}
+	if step <= 0 {
+		return nil, ErrStepInvalid
+	}
+
+	fr, err := logfind.NewFilterReader(r, q.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*group)
+	for {
+		e, err := fr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := reader.NewRecord(e)
+		if err != nil {
+			return nil, err
+		}
+		labels := q.Metric.labels(rec)
+		key := fmt.Sprint(labels)
+		if groups[key] == nil {
+			groups[key] = &group{labels: labels}
+		}
+		groups[key].records = append(groups[key].records, rec)
+	}
+
+	series := make([]Series, 0, len(groups))
+	for _, g := range groups {
+		s := Series{Labels: g.labels}
+		sort.Slice(g.records, func(i, j int) bool {
+			return g.records[i].Time.Before(g.records[j].Time)
+		})
+		// first and last delimit the records within the range (t-Range, t] as t advances.
+		first, last := 0, 0
+		for t := start; !t.After(end); t = t.Add(step) {
+			for last < len(g.records) && !g.records[last].Time.After(t) {
+				last++
+			}
+			for first < last && !g.records[first].Time.After(t.Add(-q.Metric.Range)) {
+				first++
+			}
+			if first == last {
+				continue
+			}
+			var value float64
+			for _, rec := range g.records[first:last] {
+				if q.Metric.Func == SumOverTime {
+					value += float64(rec.Bytes)
+				} else {
+					value++
+				}
+			}
+			s.Points = append(s.Points, Point{Time: t, Value: value})
+		}
+		if len(s.Points) > 0 {
+			series = append(series, s)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return fmt.Sprint(series[i].Labels) < fmt.Sprint(series[j].Labels)
+	})
+	return series, nil
+}
+
+type group struct {
+	labels  map[string]string
+	records []reader.Record
+}
+
+// labels returns the labels of the series rec belongs to.
+func (m *Metric) labels(rec reader.Record) map[string]string {
+	all := map[string]string{
+		reader.FieldUsername:  rec.User,
+		reader.FieldOperation: rec.Op,
+	}
+	if !m.Sum {
+		return all
+	}
+	labels := make(map[string]string, len(m.By))
+	for _, name := range m.By {
+		labels[name] = all[name]
+	}
+	return labels
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token when it has the given value.
+func (p *parser) accept(value string) bool {
+	if t := p.peek(); t.kind != tokenString && t.value == value {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) error {
+	if !p.accept(value) {
+		t := p.peek()
+		return syntaxError(t.pos, fmt.Sprintf("expected %q", value))
+	}
+	return nil
+}
+
+func (p *parser) query() (*Query, error) {
+	if t := p.peek(); t.kind == tokenWord {
+		switch t.value {
+		case "sum":
+			return p.sum()
+		case CountOverTime, SumOverTime:
+			return p.rangeAggregation()
+		}
+	}
+	q, _, err := p.logQuery()
+	return q, err
+}
+
+// sum parses `sum [by (labels)] (range aggregation) [by (labels)]`.
+func (p *parser) sum() (*Query, error) {
+	p.next()
+	by, err := p.by()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	q, err := p.rangeAggregation()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if by == nil {
+		if by, err = p.by(); err != nil {
+			return nil, err
+		}
+	}
+	q.Metric.Sum = true
+	q.Metric.By = by
+	return q, nil
+}
+
+// by parses an optional `by (labels)` clause.
+func (p *parser) by() ([]string, error) {
+	if !p.accept("by") {
+		return nil, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	by := []string{}
+	for !p.accept(")") {
+		t := p.next()
+		if t.kind != tokenWord {
+			return nil, syntaxError(t.pos, "expected a label")
+		}
+		if err := checkLabel(t); err != nil {
+			return nil, err
+		}
+		by = append(by, t.value)
+		if !p.accept(",") && p.peek().value != ")" {
+			return nil, syntaxError(p.peek().pos, `expected "," or ")"`)
+		}
+	}
+	return by, nil
+}
+
+// rangeAggregation parses `count_over_time(log query [range])` and `sum_over_time(log query | unwrap size [range])`.
+func (p *parser) rangeAggregation() (*Query, error) {
+	fn := p.next()
+	if fn.value != CountOverTime && fn.value != SumOverTime {
+		return nil, syntaxError(fn.pos, fmt.Sprintf("expected %s or %s", CountOverTime, SumOverTime))
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	q, unwrapped, err := p.logQuery()
+	if err != nil {
+		return nil, err
+	}
+	if unwrapped != (fn.value == SumOverTime) {
+		return nil, syntaxError(fn.pos, fmt.Sprintf("%s requires `| unwrap size` and %s does not allow it", SumOverTime, CountOverTime))
+	}
+	if err = p.expect("["); err != nil {
+		return nil, err
+	}
+	rng := p.next()
+	d, err := query.ParseDuration(rng.value)
+	if err != nil || d <= 0 {
+		return nil, syntaxError(rng.pos, fmt.Sprintf("invalid range %q", rng.value))
+	}
+	if err = p.expect("]"); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	q.Metric = &Metric{Func: fn.value, Range: d}
+	return q, nil
+}
+
+// logQuery parses a stream selector followed by pipeline stages.
+func (p *parser) logQuery() (q *Query, unwrapped bool, err error) {
+	q = &Query{}
+
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	for !p.accept("}") {
+		if err = p.matcher(q, false); err != nil {
+			return
+		}
+		if !p.accept(",") && p.peek().value != "}" {
+			return nil, false, syntaxError(p.peek().pos, `expected "," or "}"`)
+		}
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tokenOp {
+			break
+		}
+		switch t.value {
+		case "|=", "|~", "!=", "!~":
+			return nil, false, fmt.Errorf("%w at position %d: line filters", ErrUnsupported, t.pos)
+		case "|":
+		default:
+			return
+		}
+		p.next()
+
+		switch {
+		case p.accept("logfmt"), p.accept("json"):
+		case p.accept("unwrap"):
+			if !p.accept(reader.FieldSize) {
+				return nil, false, syntaxError(p.peek().pos, "only size can be unwrapped")
+			}
+			unwrapped = true
+		default:
+			for {
+				if err = p.matcher(q, true); err != nil {
+					return
+				}
+				if !p.accept("and") && !p.accept(",") {
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// matcher parses a label matcher, or, in label filters, a size comparison, adding its predicate to q.
+func (p *parser) matcher(q *Query, filter bool) error {
+	label := p.next()
+	if label.kind != tokenWord {
+		return syntaxError(label.pos, "expected a label")
+	}
+	op := p.next()
+	if op.kind != tokenOp {
+		return syntaxError(op.pos, "expected an operator")
+	}
+	value := p.next()
+
+	if filter && label.value == reader.FieldSize {
+		size, err := reader.ParseSize(value.value, reader.KB)
+		if err != nil {
+			return syntaxError(value.pos, fmt.Sprintf("invalid size %q", value.value))
+		}
+		opts, err := sizeOptions(op, size)
+		q.Options = append(q.Options, opts...)
+		return err
+	}
+
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	if value.kind != tokenString {
+		return syntaxError(value.pos, "expected a string")
+	}
+
+	var m logfind.StringMatcher
+	switch op.value {
+	case "=", "!=":
+		m = logfind.Exactly(value.value)
+	case "=~", "!~":
+		// LogQL regular expressions match whole values.
+		re, err := regexp.Compile("^(?:" + value.value + ")$")
+		if err != nil {
+			return syntaxError(value.pos, err.Error())
+		}
+		m = logfind.Regexp(re)
+	default:
+		return syntaxError(op.pos, fmt.Sprintf("unexpected operator %q", op.value))
+	}
+	if strings.HasPrefix(op.value, "!") {
+		m = not(m)
+	}
+
+	if label.value == reader.FieldUsername {
+		q.Options = append(q.Options, logfind.WhereUsername(m))
+	} else {
+		q.Options = append(q.Options, logfind.WhereOperation(m))
+	}
+	return nil
+}
+
+func checkLabel(t token) error {
+	for _, label := range Labels {
+		if t.value == label {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w at position %d: label %q, expected %s", ErrUnsupported, t.pos, t.value, strings.Join(Labels, " or "))
+}
+
+func not(m logfind.StringMatcher) logfind.StringMatcher {
+	return func(s string) bool {
+		return !m(s)
+	}
+}
+
+// sizeOptions returns the predicates of a size comparison.
+func sizeOptions(op token, size reader.Size) ([]logfind.FinderOptionFunc, error) {
+	switch op.value {
+	case ">":
+		return []logfind.FinderOptionFunc{logfind.WhereSizeAtLeast(size + 1)}, nil
+	case ">=":
+		return []logfind.FinderOptionFunc{logfind.WhereSizeAtLeast(size)}, nil
+	case "<":
+		return []logfind.FinderOptionFunc{logfind.WhereSizeAtMost(size - 1)}, nil
+	case "<=":
+		return []logfind.FinderOptionFunc{logfind.WhereSizeAtMost(size)}, nil
+	case "==", "=":
+		return []logfind.FinderOptionFunc{logfind.WhereSizeAtLeast(size), logfind.WhereSizeAtMost(size)}, nil
+	default:
+		return nil, syntaxError(op.pos, fmt.Sprintf("unexpected operator %q for size", op.value))
+	}
+}
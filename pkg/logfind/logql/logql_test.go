@@ -0,0 +1,138 @@
+package logql
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var records = []reader.Record{
+	{Time: time.Date(2020, 04, 15, 01, 10, 00, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34 * reader.KB},
+	{Time: time.Date(2020, 04, 15, 01, 35, 00, 0, time.UTC), User: "Maia86", Op: "download", Bytes: 75 * reader.KB},
+	{Time: time.Date(2020, 04, 15, 02, 10, 00, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 45 * reader.KB},
+	{Time: time.Date(2020, 04, 15, 02, 20, 00, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 75 * reader.KB},
+	{Time: time.Date(2020, 04, 15, 02, 30, 00, 0, time.UTC), User: "Jeff22", Op: "upload", Bytes: 50 * reader.KB},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr  string
+		count int
+	}{
+		{expr: `{}`, count: 5},
+		{expr: `{operation="upload"}`, count: 3},
+		{expr: `{operation="upload"} | username="jeff22"`, count: 2},
+		{expr: `{operation="upload", username!="jeff22"}`, count: 1},
+		{expr: `{username=~"(?i)jeff.*"} | logfmt | size > 50kB`, count: 1},
+		{expr: `{username=~"jeff"}`, count: 0},
+		{expr: `{username!~"jeff.*|Jeff.*"}`, count: 2},
+		{expr: "{} | json | size >= 50 and size <= 75kB", count: 3},
+		{expr: `{} | size < 50, operation="upload"`, count: 1},
+		{expr: `{} | size == 75kB`, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Parse(tt.expr)
+			assert.NoError(t, err)
+			assert.Nil(t, q.Metric)
+
+			count, _, err := logfind.NewFinder(reader.NewSliceReader(records)).Find(q.Options...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.count, count)
+		})
+	}
+
+	t.Run("parses metric queries", func(t *testing.T) {
+		q, err := Parse(`sum by (username) (count_over_time({operation="upload"}[5m]))`)
+		assert.NoError(t, err)
+		assert.Equal(t, &Metric{Func: CountOverTime, Range: 5 * time.Minute, Sum: true, By: []string{"username"}}, q.Metric)
+
+		q, err = Parse(`sum(sum_over_time({} | unwrap size [1d])) by (operation, username)`)
+		assert.NoError(t, err)
+		assert.Equal(t, &Metric{Func: SumOverTime, Range: 24 * time.Hour, Sum: true, By: []string{"operation", "username"}}, q.Metric)
+
+		q, err = Parse(`count_over_time({}[1h])`)
+		assert.NoError(t, err)
+		assert.Equal(t, &Metric{Func: CountOverTime, Range: time.Hour}, q.Metric)
+	})
+
+	for _, expr := range []string{
+		``,
+		`{operation="upload"`,
+		`{operation="upload}`,
+		`{job="server"}`,
+		`{operation=upload}`,
+		`{size="50"}`,
+		`{} |= "error"`,
+		`{} | size != 50`,
+		`{} | size > big`,
+		`{username=~"("}`,
+		`count_over_time({}[soon])`,
+		`count_over_time({} | unwrap size [5m])`,
+		`sum_over_time({}[5m])`,
+		`sum by (job) (count_over_time({}[5m]))`,
+		`rate({}[5m])`,
+		`{} extra`,
+	} {
+		t.Run("fails on "+expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQuery_Evaluate(t *testing.T) {
+	start := time.Date(2020, 04, 15, 01, 00, 00, 0, time.UTC)
+	end := time.Date(2020, 04, 15, 03, 00, 00, 0, time.UTC)
+	at := func(hour, min int) time.Time {
+		return time.Date(2020, 04, 15, hour, min, 00, 0, time.UTC)
+	}
+
+	t.Run("counts per stream", func(t *testing.T) {
+		q, err := Parse(`count_over_time({operation="upload"}[1h])`)
+		assert.NoError(t, err)
+		series, err := q.Evaluate(reader.NewSliceReader(records), start, end, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []Series{
+			{Labels: map[string]string{"operation": "upload", "username": "Jeff22"}, Points: []Point{{Time: at(3, 0), Value: 1}}},
+			{Labels: map[string]string{"operation": "upload", "username": "jeff22"}, Points: []Point{{Time: at(3, 0), Value: 2}}},
+		}, series)
+	})
+
+	t.Run("sums by label", func(t *testing.T) {
+		q, err := Parse(`sum by (operation) (count_over_time({}[30m]))`)
+		assert.NoError(t, err)
+		series, err := q.Evaluate(reader.NewSliceReader(records), start, end, 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []Series{
+			{Labels: map[string]string{"operation": "download"}, Points: []Point{{Time: at(1, 30), Value: 1}, {Time: at(2, 0), Value: 1}}},
+			{Labels: map[string]string{"operation": "upload"}, Points: []Point{{Time: at(2, 30), Value: 3}}},
+		}, series)
+	})
+
+	t.Run("sums sizes", func(t *testing.T) {
+		q, err := Parse(`sum(sum_over_time({} | unwrap size [2h]))`)
+		assert.NoError(t, err)
+		series, err := q.Evaluate(reader.NewSliceReader(records), end, end, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []Series{
+			{Labels: map[string]string{}, Points: []Point{{Time: end, Value: 279000}}},
+		}, series)
+	})
+
+	t.Run("fails on log queries", func(t *testing.T) {
+		q, err := Parse(`{}`)
+		assert.NoError(t, err)
+		_, err = q.Evaluate(reader.NewSliceReader(records), start, end, time.Hour)
+		assert.ErrorIs(t, err, ErrUnsupported)
+	})
+
+	t.Run("fails on invalid step", func(t *testing.T) {
+		q, err := Parse(`count_over_time({}[1h])`)
+		assert.NoError(t, err)
+		_, err = q.Evaluate(reader.NewSliceReader(records), start, end, 0)
+		assert.ErrorIs(t, err, ErrStepInvalid)
+	})
+}
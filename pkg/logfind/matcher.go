@@ -0,0 +1,156 @@
+package logfind
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// StringMatcher reports whether the value of a string field, e.g., username or operation, satisfies a predicate.
+type StringMatcher func(value string) bool
+
+// Exactly matches values that are byte for byte equal to s.
+func Exactly(s string) StringMatcher {
+	return func(value string) bool {
+		return value == s
+	}
+}
+
+// EqualFold matches values that are equal to s under Unicode case-folding.
+func EqualFold(s string) StringMatcher {
+	return func(value string) bool {
+		return strings.EqualFold(value, s)
+	}
+}
+
+// HasPrefix matches values that begin with prefix.
+func HasPrefix(prefix string) StringMatcher {
+	return func(value string) bool {
+		return strings.HasPrefix(value, prefix)
+	}
+}
+
+// HasSuffix matches values that end with suffix.
+func HasSuffix(suffix string) StringMatcher {
+	return func(value string) bool {
+		return strings.HasSuffix(value, suffix)
+	}
+}
+
+// Contains matches values that contain substr.
+func Contains(substr string) StringMatcher {
+	return func(value string) bool {
+		return strings.Contains(value, substr)
+	}
+}
+
+// In matches values that are byte for byte equal to one of values.
+// The values are held in a hash set so large allow-lists are matched in constant time.
+func In(values []string) StringMatcher {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return func(value string) bool {
+		_, ok := set[value]
+		return ok
+	}
+}
+
+// NotIn matches values that are not equal to any of values. See In.
+func NotIn(values []string) StringMatcher {
+	in := In(values)
+	return func(value string) bool {
+		return !in(value)
+	}
+}
+
+// Regexp matches values that contain a match of re. Anchor re with ^ and $ to match whole values.
+func Regexp(re *regexp.Regexp) StringMatcher {
+	return re.MatchString
+}
+
+// Glob matches whole values against a shell style pattern where * matches any run of characters,
+// ? matches a single character and [...] matches a character class. Classes hold characters and ranges,
+// e.g., [a-z0-9], and are negated by a leading !; backslashes and brackets in them are literal.
+// Outside classes, a backslash matches the next character literally.
+func Glob(pattern string) (StringMatcher, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); {
+		c, size := utf8.DecodeRuneInString(pattern[i:])
+		i += size
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, ErrGlobInvalid
+			}
+			class := pattern[i : i+end]
+			i += end + 1
+			b.WriteString("[")
+			if strings.HasPrefix(class, "!") {
+				b.WriteString("^")
+				class = class[1:]
+			}
+			b.WriteString(globClassEscaper.Replace(class))
+			b.WriteString("]")
+		case '\\':
+			if i < len(pattern) {
+				c, size = utf8.DecodeRuneInString(pattern[i:])
+				i += size
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, ErrGlobInvalid
+	}
+	return Regexp(re), nil
+}
+
+// globClassEscaper escapes the characters of a glob class that would otherwise change its meaning as a regexp class,
+// e.g., \d or [:alpha:].
+var globClassEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`)
+
+// ParseStringMatcher parses a matcher expression of the form mode:value where mode is one of
+// exact, fold, prefix, suffix, contains, glob or regex. An expression without a known mode
+// is matched with EqualFold, which is how WhereUsernameEquals and WhereOperationEquals behave.
+func ParseStringMatcher(expr string) (StringMatcher, error) {
+	mode, value, found := strings.Cut(expr, ":")
+	if !found {
+		return EqualFold(expr), nil
+	}
+
+	switch mode {
+	case "exact":
+		return Exactly(value), nil
+	case "fold":
+		return EqualFold(value), nil
+	case "prefix":
+		return HasPrefix(value), nil
+	case "suffix":
+		return HasSuffix(value), nil
+	case "contains":
+		return Contains(value), nil
+	case "glob":
+		return Glob(value)
+	case "regex":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return Regexp(re), nil
+	default:
+		return EqualFold(expr), nil
+	}
+}
@@ -0,0 +1,77 @@
+package logfind
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestParseStringMatcher(t *testing.T) {
+	tests := []struct {
+		expr    string
+		matches []string
+		misses  []string
+	}{
+		{expr: "Maia86", matches: []string{"Maia86", "maia86"}, misses: []string{"Maia8"}},
+		{expr: "exact:Maia86", matches: []string{"Maia86"}, misses: []string{"maia86"}},
+		{expr: "fold:Maia86", matches: []string{"maia86", "MAIA86"}, misses: []string{"Maia866"}},
+		{expr: "prefix:svc-", matches: []string{"svc-backup"}, misses: []string{"backup-svc-"}},
+		{expr: "suffix:22", matches: []string{"jeff22"}, misses: []string{"jeff221"}},
+		{expr: "contains:ff", matches: []string{"jeff22"}, misses: []string{"sarah94"}},
+		{expr: "glob:j*2?", matches: []string{"jeff22", "j22"}, misses: []string{"jeff2", "kjeff22"}},
+		{expr: "glob:[!k]*", matches: []string{"jeff22"}, misses: []string{"kyle123"}},
+		{expr: "glob:a.b", matches: []string{"a.b"}, misses: []string{"axb"}},
+		{expr: "glob:café*", matches: []string{"café", "caféine"}, misses: []string{"cafe"}},
+		{expr: "glob:用户?", matches: []string{"用户1", "用户名"}, misses: []string{"用户", "用户12"}},
+		{expr: "glob:[é\\d]x", matches: []string{"éx", "\\x", "dx"}, misses: []string{"1x"}},
+		{expr: "glob:[[:alpha:]]", matches: []string{"[]", ":]", "a]"}, misses: []string{"b", "b]"}},
+		{expr: "glob:\\*ü", matches: []string{"*ü"}, misses: []string{"xü"}},
+		{expr: "regex:^[a-z]+\\d{2}$", matches: []string{"jeff22"}, misses: []string{"Maia86", "kyle123"}},
+		{expr: "team:ops", matches: []string{"TEAM:ops"}, misses: []string{"ops"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m, err := ParseStringMatcher(tt.expr)
+			assert.NoError(t, err)
+			for _, value := range tt.matches {
+				assert.True(t, m(value), value)
+			}
+			for _, value := range tt.misses {
+				assert.False(t, m(value), value)
+			}
+		})
+	}
+
+	t.Run("fails on invalid regex", func(t *testing.T) {
+		m, err := ParseStringMatcher("regex:(")
+		assert.Error(t, err)
+		assert.Nil(t, m)
+	})
+
+	t.Run("fails on invalid glob", func(t *testing.T) {
+		m, err := ParseStringMatcher("glob:[abc")
+		assert.ErrorIs(t, err, ErrGlobInvalid)
+		assert.Nil(t, m)
+	})
+}
+
+func TestRegexp(t *testing.T) {
+	m := Regexp(regexp.MustCompile("^dex"))
+	assert.True(t, m("dex456"))
+	assert.False(t, m("kyle123"))
+}
+
+func TestIn(t *testing.T) {
+	m := In([]string{"jeff22", "Maia86"})
+	assert.True(t, m("jeff22"))
+	assert.True(t, m("Maia86"))
+	assert.False(t, m("maia86"))
+	assert.False(t, m("sarah94"))
+}
+
+func TestNotIn(t *testing.T) {
+	m := NotIn([]string{"jeff22", "Maia86"})
+	assert.False(t, m("jeff22"))
+	assert.True(t, m("maia86"))
+	assert.True(t, m("sarah94"))
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"flag"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	ErrUnterminatedQuote = logfind.Error("unterminated quote")
+)
+
+// Split splits expr into arguments the way a shell splits a simple command line: unquoted whitespace separates
+// arguments, single quotes preserve everything they enclose, and double quotes and backslashes escape.
+func Split(expr string) (args []string, err error) {
+	var b strings.Builder
+	inArg := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			i++
+			b.WriteByte(expr[i])
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, ErrUnterminatedQuote
+			}
+			b.WriteString(expr[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			closed := false
+			for i++; i < len(expr); i++ {
+				if expr[i] == '"' {
+					closed = true
+					break
+				}
+				if expr[i] == '\\' && i+1 < len(expr) && (expr[i+1] == '"' || expr[i+1] == '\\') {
+					i++
+				}
+				b.WriteByte(expr[i])
+			}
+			if !closed {
+				return nil, ErrUnterminatedQuote
+			}
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return
+}
+
+// ParseExpression parses a filter expression written as lf flags, e.g., `-u jeff22 --operation=upload --on 2020-04-15`,
+// into a Query with lf's defaults. This lets other front ends, e.g., saved queries or dashboards, express filters exactly
+// as they would be typed on the command line.
+func ParseExpression(expr string) (*Query, error) {
+	args, err := Split(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	q := New()
+	fs := flag.NewFlagSet("expression", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	q.RegisterFlags(fs)
+	if err = fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return q, nil
+}
+
+// FromValues parses URL query parameters named after lf's flags, e.g., `username=jeff22&on=2020-04-15`, into a Query
+// with lf's defaults. When a parameter is repeated its last value wins, as with repeated flags.
+func FromValues(values url.Values) (*Query, error) {
+	q := New()
+	fs := flag.NewFlagSet("values", flag.ContinueOnError)
+	q.RegisterFlags(fs)
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+		for _, value := range values[name] {
+			if err := fs.Set(name, value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for parameter %s: %w", value, name, err)
+			}
+		}
+	}
+	return q, nil
+}
+
+// FlagNames returns the names of the flags defined by RegisterFlags, sorted.
+func FlagNames() []string {
+	fs := flag.NewFlagSet("names", flag.ContinueOnError)
+	New().RegisterFlags(fs)
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,84 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "  -u jeff22\t--on 2020-04-15 ", want: []string{"-u", "jeff22", "--on", "2020-04-15"}},
+		{in: `--username='regex:^a b$'`, want: []string{"--username=regex:^a b$"}},
+		{in: `-u "say \"hi\""`, want: []string{"-u", `say "hi"`}},
+		{in: `-u a\ b`, want: []string{"-u", "a b"}},
+		{in: `-u ''`, want: []string{"-u", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Split(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("fails on unterminated quote", func(t *testing.T) {
+		_, err := Split(`-u 'jeff22`)
+		assert.ErrorIs(t, err, ErrUnterminatedQuote)
+	})
+}
+
+func TestParseExpression(t *testing.T) {
+	t.Run("parses flags", func(t *testing.T) {
+		q, err := ParseExpression(`-u jeff22 --operation=upload --on 2020-04-15`)
+		assert.NoError(t, err)
+		assert.Equal(t, "jeff22", q.Username)
+		assert.Equal(t, "upload", q.Operation)
+		assert.Equal(t, "2020-04-15", q.On)
+		assert.Equal(t, "UTC", q.TZ)
+	})
+
+	t.Run("fails on unknown flag", func(t *testing.T) {
+		_, err := ParseExpression(`--colour=red`)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails on positional argument", func(t *testing.T) {
+		_, err := ParseExpression(`-u jeff22 server_log.csv`)
+		assert.Error(t, err)
+	})
+}
+
+func TestFromValues(t *testing.T) {
+	t.Run("parses parameters", func(t *testing.T) {
+		q, err := FromValues(url.Values{
+			"u":        {"jeff22"},
+			"op":       {"download", "upload"},
+			"since":    {"24h"},
+			"on-error": {"skip"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "jeff22", q.Username)
+		assert.Equal(t, "upload", q.Operation)
+		assert.Equal(t, "24h", q.Since)
+		assert.Equal(t, "skip", q.OnError)
+		assert.Equal(t, "[)", q.Bounds)
+	})
+
+	t.Run("fails on unknown parameter", func(t *testing.T) {
+		_, err := FromValues(url.Values{"colour": {"red"}})
+		assert.EqualError(t, err, `unknown parameter "colour"`)
+	})
+}
+
+func TestFlagNames(t *testing.T) {
+	names := FlagNames()
+	assert.Contains(t, names, "username")
+	assert.Contains(t, names, "u")
+	assert.Contains(t, names, "op")
+	assert.IsIncreasing(t, names)
+}
@@ -0,0 +1,154 @@
+// Package query maps the textual filter parameters understood by lf, e.g., its command line flags,
+// onto logfind.FinderOptionFuncs so that every front end filters events the same way.
+package query
+
+import (
+	"bufio"
+	"flag"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"os"
+	"strings"
+	"time"
+)
+
+// Query holds the filter parameters of a search as they were given by a user.
+// The zero value matches every event; use New for a Query with lf's defaults.
+type Query struct {
+	MinTimestamp string
+	MaxTimestamp string
+	Bounds       string
+	Since        string
+	On           string
+	TZ           string
+
+	Username            string
+	UsernameFile        string
+	ExcludeUsernameFile string
+	Operation           string
+
+	MinSize string
+	MaxSize string
+
+	OnError string
+
+	// Now returns the current time for relative ranges such as Since. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// New returns a Query with lf's defaults.
+func New() *Query {
+	return &Query{
+		Bounds:  string(logfind.InclusiveMin),
+		TZ:      "UTC",
+		OnError: string(logfind.Strict),
+	}
+}
+
+// RegisterFlags defines a flag for each parameter of q on fs.
+func (q *Query) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&q.MinTimestamp, "minTimestamp", q.MinTimestamp, "The minimum date to match, e.g., 2020-04-15T00:00:00Z or 2020-04-15. Note this is inclusive unless changed by --bounds.")
+	fs.StringVar(&q.MaxTimestamp, "maxTimestamp", q.MaxTimestamp, "The maximum date to match. Note this is exclusive unless changed by --bounds.")
+	fs.StringVar(&q.Bounds, "bounds", q.Bounds, "Whether --minTimestamp and --maxTimestamp are inclusive. Values are [), [], (), (].")
+	fs.StringVar(&q.Since, "since", q.Since, "Only match events that happened within the given duration before now, e.g., 90m, 24h or 7d.")
+	fs.StringVar(&q.On, "on", q.On, "Only match events that happened on the given day, e.g., 2020-04-15.")
+	fs.StringVar(&q.TZ, "tz", q.TZ, "The time zone in which to interpret dates and timestamps without an offset, e.g., America/New_York.")
+
+	usernameUsage := "The username to match. Prefix with exact:, fold:, prefix:, suffix:, contains:, glob: or regex: to choose how it is matched.  Case-insensitive equality is default."
+	fs.StringVar(&q.Username, "username", q.Username, usernameUsage)
+	fs.StringVar(&q.Username, "u", q.Username, "Shorthand for --username.")
+	fs.StringVar(&q.UsernameFile, "username-file", q.UsernameFile, "A file of usernames to match, one per line. Blank lines and lines starting with # are ignored.")
+	fs.StringVar(&q.ExcludeUsernameFile, "exclude-username-file", q.ExcludeUsernameFile, "A file of usernames to exclude, in the same format as --username-file.")
+	fs.StringVar(&q.Operation, "operation", q.Operation, "The operation to match. Accepts the same prefixes as --username.")
+	fs.StringVar(&q.Operation, "op", q.Operation, "Shorthand for --operation.")
+
+	fs.StringVar(&q.MinSize, "minSize", q.MinSize, "The minimum size to match, e.g., 50kB, 1.5MB or 512KiB. Numbers without a unit are in kB.")
+	fs.StringVar(&q.MaxSize, "maxSize", q.MaxSize, "The maximum size to match. Note this in inclusive.")
+
+	fs.StringVar(&q.OnError, "on-error", q.OnError, "Changes how malformed records are handled. Values are strict, skip, skip-and-report.")
+}
+
+// Options translates q into logfind.FinderOptionFuncs.
+//
+// Note: The diagnostics of the skip-and-report policy are not collected unless the caller adds logfind.WithDiagnostics.
+func (q *Query) Options() (opts []logfind.FinderOptionFunc, err error) {
+	if q.OnError != "" {
+		opts = append(opts, logfind.WithErrorPolicy(logfind.ErrorPolicy(q.OnError)))
+	}
+
+	timeOpts, err := q.timeOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, timeOpts...)
+
+	if q.Username != "" {
+		m, err := logfind.ParseStringMatcher(q.Username)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereUsername(m))
+	}
+
+	if q.UsernameFile != "" {
+		usernames, err := ReadList(q.UsernameFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereUsernameIn(usernames))
+	}
+
+	if q.ExcludeUsernameFile != "" {
+		usernames, err := ReadList(q.ExcludeUsernameFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereUsernameNotIn(usernames))
+	}
+
+	if q.Operation != "" {
+		m, err := logfind.ParseStringMatcher(q.Operation)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereOperation(m))
+	}
+
+	if q.MinSize != "" {
+		minSize, err := reader.ParseSize(q.MinSize, reader.KB)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereSizeAtLeast(minSize))
+	}
+
+	if q.MaxSize != "" {
+		maxSize, err := reader.ParseSize(q.MaxSize, reader.KB)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logfind.WhereSizeAtMost(maxSize))
+	}
+
+	return
+}
+
+// ReadList reads the non-empty, non-comment lines of the file at path.
+func ReadList(path string) (list []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	err = scanner.Err()
+	return
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"flag"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+Mon Apr 13 00:00:00 UTC 2020,Maia86,upload,9
+Mon Apr 13 23:23:52 UTC 2020,jeff22,upload,1500
+`
+
+// count runs q against input and returns the number of matching events.
+func count(t *testing.T, q *Query) int {
+	opts, err := q.Options()
+	assert.NoError(t, err)
+	n, _, err := logfind.NewFinder(csv.NewReader(strings.NewReader(input))).Find(opts...)
+	assert.NoError(t, err)
+	return n
+}
+
+func TestQuery_Options(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2020, 04, 14, 00, 00, 00, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{name: "zero value matches all", query: Query{}, want: 4},
+		{name: "username", query: Query{Username: "maia86"}, want: 2},
+		{name: "username mode", query: Query{Username: "exact:maia86"}, want: 0},
+		{name: "operation", query: Query{Operation: "upload"}, want: 2},
+		{name: "min timestamp is inclusive", query: Query{MinTimestamp: "2020-04-13"}, want: 2},
+		{name: "max timestamp is exclusive", query: Query{MaxTimestamp: "2020-04-13"}, want: 2},
+		{name: "bounds", query: Query{MaxTimestamp: "2020-04-13", Bounds: "[]"}, want: 3},
+		{name: "on", query: Query{On: "2020-04-12"}, want: 2},
+		{name: "on in time zone", query: Query{On: "2020-04-12", TZ: "America/New_York"}, want: 3},
+		{name: "since", query: Query{Since: "1d", Now: now}, want: 2},
+		{name: "max timestamp narrows on", query: Query{On: "2020-04-13", MaxTimestamp: "2020-04-13T12:00:00Z"}, want: 1},
+		{name: "since and min timestamp intersect", query: Query{Since: "2d", MinTimestamp: "2020-04-13", Now: now}, want: 2},
+		{name: "min size", query: Query{MinSize: "50"}, want: 2},
+		{name: "max size", query: Query{MaxSize: "1.5MB"}, want: 4},
+		{name: "size range", query: Query{MinSize: "10kB", MaxSize: "0.1MB"}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, count(t, &tt.query))
+		})
+	}
+
+	t.Run("username files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "accounts.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("# service accounts\nMaia86\n\njeff22\n"), 0o600))
+
+		assert.Equal(t, 3, count(t, &Query{UsernameFile: path}))
+		assert.Equal(t, 1, count(t, &Query{ExcludeUsernameFile: path}))
+	})
+
+	for name, q := range map[string]Query{
+		"bad timestamp": {MinTimestamp: "yesterday"},
+		"bad bounds":    {Bounds: "[["},
+		"bad duration":  {Since: "forever"},
+		"bad day":       {On: "15/04/2020"},
+		"bad zone":      {TZ: "Mars/Olympus_Mons"},
+		"bad size":      {MinSize: "lots"},
+		"bad regex":     {Username: "regex:("},
+		"missing file":  {UsernameFile: "does-not-exist.txt"},
+	} {
+		t.Run("fails on "+name, func(t *testing.T) {
+			opts, err := q.Options()
+			assert.Error(t, err)
+			assert.Nil(t, opts)
+		})
+	}
+
+	t.Run("fails on bad error policy", func(t *testing.T) {
+		q := &Query{OnError: "ignore"}
+		opts, err := q.Options()
+		assert.NoError(t, err)
+		_, _, err = logfind.NewFinder(csv.NewReader(strings.NewReader(input))).Find(opts...)
+		assert.ErrorIs(t, err, logfind.ErrErrorPolicyInvalid)
+	})
+}
+
+func TestQuery_RegisterFlags(t *testing.T) {
+	t.Run("parses flags and shorthands", func(t *testing.T) {
+		q := New()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		q.RegisterFlags(fs)
+		err := fs.Parse([]string{"-u", "jeff22", "-op", "upload", "--on=2020-04-15", "--minSize=50kB"})
+		assert.NoError(t, err)
+		assert.Equal(t, "jeff22", q.Username)
+		assert.Equal(t, "upload", q.Operation)
+		assert.Equal(t, "2020-04-15", q.On)
+		assert.Equal(t, "50kB", q.MinSize)
+		assert.Equal(t, "UTC", q.TZ)
+		assert.Equal(t, "[)", q.Bounds)
+		assert.Equal(t, "strict", q.OnError)
+	})
+}
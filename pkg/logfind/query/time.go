@@ -0,0 +1,124 @@
+package query
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the layouts accepted for MinTimestamp and MaxTimestamp, tried in order.
+// Layouts without a zone are interpreted in the zone given by TZ.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.UnixDate,
+}
+
+// ParseTimestamp parses s using the first of timestampLayouts that fits.
+func ParseTimestamp(s string, loc *time.Location) (t time.Time, err error) {
+	for _, layout := range timestampLayouts {
+		t, err = time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return
+		}
+	}
+	err = fmt.Errorf("invalid timestamp %q: expected RFC 3339, e.g., 2020-04-15T00:00:00Z, or a date, e.g., 2020-04-15", s)
+	return
+}
+
+// ParseDay parses s as a calendar date and returns the range covering that day in loc.
+func ParseDay(s string, loc *time.Location) (start, end time.Time, err error) {
+	start, err = time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		err = fmt.Errorf("invalid date %q: expected YYYY-MM-DD", s)
+		return
+	}
+	end = start.AddDate(0, 0, 1)
+	return
+}
+
+// ParseDuration parses a duration such as 90m, 24h or 7d. In addition to the units understood by time.ParseDuration
+// a d suffix may be used for whole days.
+func ParseDuration(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// timeOptions turns the time related parameters of q into logfind.FinderOptionFuncs.
+func (q *Query) timeOptions() (opts []logfind.FinderOptionFunc, err error) {
+	tz := q.TZ
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return
+	}
+
+	bounds := logfind.Bounds(q.Bounds)
+	switch bounds {
+	case "":
+		bounds = logfind.InclusiveMin
+	case logfind.Inclusive, logfind.Exclusive, logfind.InclusiveMin, logfind.InclusiveMax:
+	default:
+		err = logfind.ErrBoundsInvalid
+		return
+	}
+
+	if q.MinTimestamp != "" {
+		var min time.Time
+		if min, err = ParseTimestamp(q.MinTimestamp, loc); err != nil {
+			return
+		}
+		if bounds == logfind.Inclusive || bounds == logfind.InclusiveMin {
+			opts = append(opts, logfind.WhereTimestampAtOrAfter(min))
+		} else {
+			opts = append(opts, logfind.WhereTimestampAfter(min))
+		}
+	}
+
+	if q.MaxTimestamp != "" {
+		var max time.Time
+		if max, err = ParseTimestamp(q.MaxTimestamp, loc); err != nil {
+			return
+		}
+		if bounds == logfind.Inclusive || bounds == logfind.InclusiveMax {
+			opts = append(opts, logfind.WhereTimestampAtOrBefore(max))
+		} else {
+			opts = append(opts, logfind.WhereTimestampBefore(max))
+		}
+	}
+
+	if q.Since != "" {
+		var d time.Duration
+		if d, err = ParseDuration(q.Since); err != nil {
+			return
+		}
+		now := time.Now
+		if q.Now != nil {
+			now = q.Now
+		}
+		opts = append(opts, logfind.WhereTimestampAtOrAfter(now().Add(-d)))
+	}
+
+	if q.On != "" {
+		var start, end time.Time
+		if start, end, err = ParseDay(q.On, loc); err != nil {
+			return
+		}
+		opts = append(opts, logfind.WhereTimestampIsWithin(start, end, logfind.InclusiveMin))
+	}
+
+	return
+}
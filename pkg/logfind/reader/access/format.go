@@ -0,0 +1,207 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The predefined log formats, written as nginx log_format strings.
+const (
+	// Common is the Common Log Format of Apache and nginx.
+	Common = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	// Combined is the Common Log Format followed by the referer and user agent, nginx's default.
+	Combined = Common + ` "$http_referer" "$http_user_agent"`
+)
+
+// apacheDirectives translates the directives of Apache's LogFormat into nginx variables.
+var apacheDirectives = map[string]string{
+	"h":                  "$remote_addr",
+	"a":                  "$remote_addr",
+	"l":                  "$remote_logname",
+	"u":                  "$remote_user",
+	"t":                  "[$time_local]",
+	"r":                  "$request",
+	"m":                  "$request_method",
+	"U":                  "$uri",
+	"s":                  "$status",
+	">s":                 "$status",
+	"b":                  "$body_bytes_sent",
+	"B":                  "$body_bytes_sent",
+	"O":                  "$bytes_sent",
+	"I":                  "$request_length",
+	"T":                  "$request_time",
+	"D":                  "$request_time_us",
+	"v":                  "$server_name",
+	"{Referer}i":         "$http_referer",
+	"{User-Agent}i":      "$http_user_agent",
+	"{User-agent}i":      "$http_user_agent",
+	"{X-Forwarded-For}i": "$http_x_forwarded_for",
+}
+
+// LogFormat is a compiled log format, see ParseLogFormat.
+type LogFormat struct {
+	// tokens alternate between literal text and variable names, starting with literal text, which may be empty.
+	tokens []string
+}
+
+// ParseLogFormat compiles a log format given as an nginx log_format string, e.g., `$remote_user [$time_local]`, as an
+// Apache LogFormat string, e.g., `%u %t`, or as the name of a predefined format, combined or common.
+// Variables must be separated by some text so that lines can be split into their values.
+func ParseLogFormat(s string) (*LogFormat, error) {
+	switch strings.ToLower(s) {
+	case "combined":
+		s = Combined
+	case "common":
+		s = Common
+	}
+	if strings.Contains(s, "%") {
+		var err error
+		if s, err = translateApache(s); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &LogFormat{}
+	var literal strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			literal.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		var name string
+		if strings.HasPrefix(s[i:], "${") {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated ${ at column %d", ErrLogFormatInvalid, i+1)
+			}
+			name, i = s[i+2:i+end], i+end+1
+		} else {
+			j := i + 1
+			for j < len(s) && isNameByte(s[j]) {
+				j++
+			}
+			name, i = s[i+1:j], j
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%w: $ without a variable name", ErrLogFormatInvalid)
+		}
+		if literal.Len() == 0 && len(f.tokens) > 0 {
+			return nil, fmt.Errorf("%w: $%s directly follows $%s", ErrLogFormatInvalid, name, f.tokens[len(f.tokens)-1])
+		}
+		f.tokens = append(f.tokens, literal.String(), name)
+		literal.Reset()
+	}
+	if len(f.tokens) == 0 {
+		return nil, fmt.Errorf("%w: no variables", ErrLogFormatInvalid)
+	}
+	f.tokens = append(f.tokens, literal.String())
+	return f, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// translateApache rewrites the directives of an Apache LogFormat string as nginx variables.
+func translateApache(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		rest := s[i+1:]
+		if strings.HasPrefix(rest, "%") {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+
+		var directive string
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			end := strings.IndexByte(rest, '}')
+			if end < 0 || end+1 >= len(rest) {
+				return "", fmt.Errorf("%w: unterminated %%{ at column %d", ErrLogFormatInvalid, i+1)
+			}
+			directive = rest[:end+2]
+		case strings.HasPrefix(rest, ">") && len(rest) > 1:
+			directive = rest[:2]
+		case len(rest) > 0:
+			directive = rest[:1]
+		}
+		variable, ok := apacheDirectives[directive]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported directive %%%s", ErrLogFormatInvalid, directive)
+		}
+		b.WriteString(variable)
+		i += len(directive)
+	}
+	return b.String(), nil
+}
+
+// Variables returns the names of the variables of the format, without the leading $.
+func (f *LogFormat) Variables() []string {
+	names := make([]string, 0, len(f.tokens)/2)
+	for i := 1; i < len(f.tokens); i += 2 {
+		names = append(names, f.tokens[i])
+	}
+	return names
+}
+
+// has reports whether the format has the named variable.
+func (f *LogFormat) has(name string) bool {
+	for i := 1; i < len(f.tokens); i += 2 {
+		if f.tokens[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// match splits line into the values of the variables of the format.
+func (f *LogFormat) match(line string) (map[string]string, error) {
+	if !strings.HasPrefix(line, f.tokens[0]) {
+		return nil, fmt.Errorf("expected %q at column 1", f.tokens[0])
+	}
+	values := make(map[string]string, len(f.tokens)/2)
+	pos := len(f.tokens[0])
+	for i := 1; i < len(f.tokens); i += 2 {
+		name, next := f.tokens[i], f.tokens[i+1]
+		if next == "" {
+			// Only the last variable may be followed by nothing.
+			values[name] = line[pos:]
+			pos = len(line)
+			continue
+		}
+		end := indexUnescaped(line[pos:], next)
+		if end < 0 {
+			return nil, fmt.Errorf("expected %q after $%s at column %d", next, name, pos+1)
+		}
+		values[name] = line[pos : pos+end]
+		pos += end + len(next)
+	}
+	if pos != len(line) {
+		return nil, errors.New("unexpected text at the end of the line")
+	}
+	return values, nil
+}
+
+// indexUnescaped returns the index of the first occurrence of literal in s that is not preceded by a backslash,
+// as double quotes are escaped within quoted values, or -1.
+func indexUnescaped(s, literal string) int {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], literal)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if i == 0 || s[i-1] != '\\' || literal[0] != '"' {
+			return i
+		}
+		offset = i + 1
+	}
+}
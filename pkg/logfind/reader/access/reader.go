@@ -0,0 +1,379 @@
+// Package access reads the access logs of web servers such as nginx and Apache, by default in the Combined or Common
+// Log Format:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "PUT /files/report.pdf HTTP/1.1" 201 2326 "-" "curl/7.68.0"
+//
+// The remote user is the username of an event, "-" for anonymous requests, and the number of bytes of the response
+// body its size. The operation is derived from the method and path of the request by rules, by default PUT and POST
+// being uploads and GET downloads, see Rule. The other variables of a line, e.g., status, are exposed as its labels,
+// see lfReader.Labeler.
+package access
+
+import (
+	"bufio"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	lfReader.Register("access", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.LogFormat != "" {
+			f, err := ParseLogFormat(opts.LogFormat)
+			if err != nil {
+				return nil, err
+			}
+			readerOpts = append(readerOpts, WithLogFormat(f))
+		}
+		if opts.OperationRules != "" {
+			rules, err := ParseRules(opts.OperationRules)
+			if err != nil {
+				return nil, err
+			}
+			readerOpts = append(readerOpts, WithRules(rules))
+		}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.TimestampLayout != "" {
+			readerOpts = append(readerOpts, WithTimestampLayout(opts.TimestampLayout))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// defaultFormats are tried in order on each line when no format is given.
+var defaultFormats = []*LogFormat{mustParseLogFormat(Combined), mustParseLogFormat(Common)}
+
+func mustParseLogFormat(s string) *LogFormat {
+	f, err := ParseLogFormat(s)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// detect matches files whose name contains "access" and ".log", e.g., access.log.1, or whose first line is in the
+// Combined or Common Log Format.
+func detect(name string, head []byte) lfReader.Confidence {
+	base := strings.ToLower(filepath.Base(name))
+	if strings.Contains(base, "access") && strings.Contains(base, ".log") {
+		return lfReader.ExtensionMatch
+	}
+	line, _, _ := strings.Cut(strings.TrimLeft(string(head), " \t\r\n"), "\n")
+	line = strings.TrimSuffix(line, "\r")
+	for _, f := range defaultFormats {
+		if _, err := f.match(line); err == nil {
+			return lfReader.ContentMatch
+		}
+	}
+	return lfReader.NoMatch
+}
+
+const (
+	ErrFieldMissing     = lfReader.Error("field missing")
+	ErrLogFormatInvalid = lfReader.Error("invalid log format")
+	ErrRuleInvalid      = lfReader.Error("invalid operation rule")
+	ErrRequestMalformed = lfReader.Error("malformed request line")
+)
+
+// The variables fields are read from when they are not mapped to others, in order of preference.
+var (
+	timestampVariables = []string{"time_local", "time_iso8601", "msec"}
+	usernameVariables  = []string{"remote_user"}
+	sizeVariables      = []string{"body_bytes_sent", "bytes_sent"}
+)
+
+// timeLocalLayout is the layout of $time_local and Apache's %t.
+const timeLocalLayout = "02/Jan/2006:15:04:05 -0700"
+
+// maxLineSize is the size of the longest line the reader accepts.
+const maxLineSize = 1 << 20
+
+type reader struct {
+	scanner  *bufio.Scanner
+	location lfReader.Location
+	next     int64
+
+	formats         []*LogFormat
+	rules           []Rule
+	sizeUnit        lfReader.Size
+	timestampLayout string
+	loc             *time.Location
+	columns         map[string]string
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithLogFormat declares the format of the lines, see ParseLogFormat. By default lines are read in the Combined or
+// Common Log Format.
+func WithLogFormat(f *LogFormat) ReaderOptionFunc {
+	return func(r *reader) {
+		r.formats = []*LogFormat{f}
+	}
+}
+
+// WithRules replaces the rules deriving operations from requests. The default is DefaultRules.
+func WithRules(rules []Rule) ReaderOptionFunc {
+	return func(r *reader) {
+		r.rules = rules
+	}
+}
+
+// WithSizeUnit declares the unit of sizes. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithTimestampLayout declares the layout of timestamps, see time.Parse. By default the layout follows from the
+// variable holding the timestamp, e.g., $time_local or $time_iso8601.
+func WithTimestampLayout(layout string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the time zone of timestamps whose layout has no zone. The default is UTC.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldSize, to the variables holding them, without the
+// leading $, e.g., request_length. Mapping lfReader.FieldOperation reads the operation from a variable rather than
+// deriving it from the request.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, skipping blank lines.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		scanner:  bufio.NewScanner(r),
+		formats:  defaultFormats,
+		rules:    DefaultRules,
+		sizeUnit: lfReader.Byte,
+		loc:      time.UTC,
+	}
+	rdr.scanner.Buffer(nil, maxLineSize)
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the event on the next non-blank line. A line that does not fit the log format is reported as a
+// *lfReader.RecordError, after which reading continues with the next line.
+func (r *reader) Read() (lfReader.Event, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		r.location.Line++
+		r.location.Offset = r.next
+		r.next += int64(len(line)) + 1
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var err error
+		for _, f := range r.formats {
+			var values map[string]string
+			if values, err = f.match(line); err == nil {
+				return event{values: values, format: f, location: r.location, r: r}, nil
+			}
+		}
+		return nil, &lfReader.RecordError{
+			Source: r.location.Source,
+			Line:   r.location.Line,
+			Offset: r.location.Offset,
+			Err:    err,
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+type event struct {
+	values map[string]string
+	// format is the log format the line was read with.
+	format   *LogFormat
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+var _ lfReader.Labeler = event{}
+
+// variable returns the name of the variable holding field, looked up among defaults unless mapped, and whether the
+// format has it.
+func (e event) variable(field string, defaults []string) (string, bool) {
+	if name, ok := e.r.columns[field]; ok {
+		return name, e.format.has(name)
+	}
+	for _, name := range defaults {
+		if e.format.has(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// value returns the value of field.
+func (e event) value(field string, defaults []string) (name, value string, err error) {
+	name, ok := e.variable(field, defaults)
+	if !ok {
+		return "", "", e.error(field, "", ErrFieldMissing)
+	}
+	return name, e.values[name], nil
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field, value string, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	name, value, err := e.value(lfReader.FieldTimestamp, timestampVariables)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	layout := e.r.timestampLayout
+	if layout == "" {
+		switch name {
+		case "msec":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+			}
+			whole, frac := math.Modf(seconds)
+			return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+		case "time_iso8601":
+			layout = time.RFC3339
+		default:
+			layout = timeLocalLayout
+		}
+	}
+	t, err := time.ParseInLocation(layout, value, e.r.loc)
+	if err != nil {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+	}
+	return t, nil
+}
+
+func (e event) Username() (string, error) {
+	_, value, err := e.value(lfReader.FieldUsername, usernameVariables)
+	return value, err
+}
+
+// Operation reads the operation from the variable it is mapped to or derives it from the request.
+func (e event) Operation() (string, error) {
+	if _, ok := e.r.columns[lfReader.FieldOperation]; ok {
+		_, value, err := e.value(lfReader.FieldOperation, nil)
+		return value, err
+	}
+
+	method, path, err := e.request()
+	if err != nil {
+		return "", err
+	}
+	return Operation(e.r.rules, method, path), nil
+}
+
+// request returns the method and path of the request, taken from $request_method and $request_uri or $uri when the
+// format has them and otherwise from the request line.
+func (e event) request() (method, path string, err error) {
+	method, hasMethod := e.values["request_method"]
+	path, hasPath := e.values["request_uri"]
+	if !hasPath {
+		path, hasPath = e.values["uri"]
+	}
+	if hasMethod && hasPath {
+		return method, path, nil
+	}
+
+	line, ok := e.values["request"]
+	if !ok {
+		return "", "", e.error(lfReader.FieldOperation, "", ErrFieldMissing)
+	}
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return "", "", e.error(lfReader.FieldOperation, line, ErrRequestMalformed)
+	}
+	if !hasMethod {
+		method = parts[0]
+	}
+	if !hasPath {
+		path = parts[1]
+	}
+	return method, path, nil
+}
+
+// Size returns the size of the response body, zero when logged as "-".
+func (e event) Size() (lfReader.Size, error) {
+	_, value, err := e.value(lfReader.FieldSize, sizeVariables)
+	if err != nil {
+		return 0, err
+	}
+	if value == "-" {
+		return 0, nil
+	}
+	size, err := lfReader.ParseSize(value, e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
+
+// Labels returns the variables of the line that are not read as fields of the event.
+func (e event) Labels() map[string]string {
+	labels := make(map[string]string, len(e.values))
+	for name, value := range e.values {
+		labels[name] = value
+	}
+	for field, defaults := range map[string][]string{
+		lfReader.FieldTimestamp: timestampVariables,
+		lfReader.FieldUsername:  usernameVariables,
+		lfReader.FieldOperation: nil,
+		lfReader.FieldSize:      sizeVariables,
+	} {
+		if name, ok := e.variable(field, defaults); ok {
+			delete(labels, name)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,179 @@
+package access
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/logfmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `10.0.0.1 - sarah94 [12/Apr/2020:22:10:38 +0000] "GET /files/report.pdf HTTP/1.1" 200 34000 "-" "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko)"
+
+10.0.0.2 - Maia86 [12/Apr/2020:18:15:06 -0400] "PUT /files/photo.jpg?overwrite=1 HTTP/1.1" 201 -
+10.0.0.3 - - [12/Apr/2020:22:20:00 +0000] "DELETE /files/old.txt HTTP/1.1" 204 0
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads combined and common log format", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 4, 12, 22, 15, 6, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 0},
+			{Time: time.Date(2020, 4, 12, 22, 20, 0, 0, time.UTC), User: "-", Op: "delete", Bytes: 0},
+		}, normalize(records))
+	})
+
+	t.Run("exposes other variables as labels", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"remote_addr":     "10.0.0.1",
+			"request":         "GET /files/report.pdf HTTP/1.1",
+			"status":          "200",
+			"http_referer":    "-",
+			"http_user_agent": "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko)",
+		}, e.(lfReader.Labeler).Labels())
+	})
+
+	t.Run("reports location", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		_, err := r.Read()
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r.(lfReader.Locator).Location().Line)
+	})
+
+	t.Run("respects log format, rules and columns", func(t *testing.T) {
+		f, err := ParseLogFormat(`$time_iso8601 $remote_user $request_method $uri $request_length "$http_user_agent"`)
+		assert.NoError(t, err)
+		rules, err := ParseRules("* /upload/=upload; GET=download")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader("2020-04-12T22:10:38Z jeff22 POST /upload/a.txt 4096 \"curl/7.68.0 \\\"quoted\\\"\"\n"),
+			WithLogFormat(f),
+			WithRules(rules),
+			WithColumns(map[string]string{lfReader.FieldSize: "request_length"}),
+		)
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Record{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 4096}, rec)
+		assert.Equal(t, `curl/7.68.0 \"quoted\"`, e.(lfReader.Labeler).Labels()["http_user_agent"])
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader("not an access log\n10.0.0.1 - jeff22 [yesterday] \"-\" 400 0\n"))
+		_, err := r.Read()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 1, recErr.Line)
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, lfReader.FieldTimestamp, recErr.Field)
+		assert.Equal(t, "yesterday", recErr.Value)
+		_, err = e.Operation()
+		assert.ErrorIs(t, err, ErrRequestMalformed)
+
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+// normalize converts the times of records to UTC.
+func normalize(records []lfReader.Record) []lfReader.Record {
+	for i := range records {
+		records[i].Time = records[i].Time.UTC()
+	}
+	return records
+}
+
+func TestParseLogFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{name: "predefined", format: "common", want: []string{"remote_addr", "remote_user", "time_local", "request", "status", "body_bytes_sent"}},
+		{name: "nginx", format: `${remote_addr}:$remote_port "$request"`, want: []string{"remote_addr", "remote_port", "request"}},
+		{name: "apache", format: `%h %l %u %t "%r" %>s %b "%{Referer}i"`, want: []string{"remote_addr", "remote_logname", "remote_user", "time_local", "request", "status", "body_bytes_sent", "http_referer"}},
+		{name: "adjacent variables", format: "$status$body_bytes_sent", wantErr: "directly follows"},
+		{name: "unsupported directive", format: "%h %{%d}t", wantErr: "unsupported directive"},
+		{name: "no variables", format: "plain", wantErr: "no variables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseLogFormat(tt.format)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrLogFormatInvalid)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, f.Variables())
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("put,POST=upload; GET /files/=download; * /admin/=admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Methods: []string{"PUT", "POST"}, Operation: "upload"},
+		{Methods: []string{"GET"}, PathPrefix: "/files/", Operation: "download"},
+		{PathPrefix: "/admin/", Operation: "admin"},
+	}, rules)
+
+	assert.Equal(t, "upload", Operation(rules, "post", "/x"))
+	assert.Equal(t, "download", Operation(rules, "GET", "/files/a?b=c"))
+	assert.Equal(t, "get", Operation(rules, "GET", "/other"))
+	assert.Equal(t, "admin", Operation(rules, "DELETE", "/admin/users"))
+
+	for _, s := range []string{"", "GET", "=upload", "GET /a /b=x", ",GET=download"} {
+		_, err := ParseRules(s)
+		assert.ErrorIs(t, err, ErrRuleInvalid, s)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	format, err := lfReader.Detect("/var/log/nginx/access.log.1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "access", format)
+
+	format, err = lfReader.Detect("", []byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "access", format)
+
+	t.Run("reads with options", func(t *testing.T) {
+		r, err := lfReader.NewFormatReader(strings.NewReader(`jeff22 "PUT /a"`+"\n"), "access", lfReader.FormatOptions{
+			LogFormat:      `$remote_user "$request"`,
+			OperationRules: "PUT=store",
+		})
+		assert.NoError(t, err)
+		e, err := r.Read()
+		assert.NoError(t, err)
+		op, err := e.Operation()
+		assert.NoError(t, err)
+		assert.Equal(t, "store", op)
+
+		_, err = lfReader.NewFormatReader(strings.NewReader(""), "access", lfReader.FormatOptions{LogFormat: "$a$b"})
+		assert.ErrorIs(t, err, ErrLogFormatInvalid)
+
+		// When the format is detected, invalid options are reported by the first Read.
+		r, err = lfReader.NewFormatReader(strings.NewReader(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 2326`), "", lfReader.FormatOptions{OperationRules: "GET"})
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.ErrorIs(t, err, ErrRuleInvalid)
+	})
+}
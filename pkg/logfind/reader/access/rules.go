@@ -0,0 +1,90 @@
+package access
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule derives the operation of requests from their method and path.
+type Rule struct {
+	// Methods are the HTTP methods the rule applies to. Empty means every method.
+	Methods []string
+	// PathPrefix is the prefix of the paths the rule applies to. Empty means every path.
+	PathPrefix string
+	// Operation is the operation of the matching requests.
+	Operation string
+}
+
+// DefaultRules treat PUT and POST requests as uploads and GET requests as downloads.
+var DefaultRules = []Rule{
+	{Methods: []string{"PUT", "POST"}, Operation: "upload"},
+	{Methods: []string{"GET"}, Operation: "download"},
+}
+
+// ParseRules parses rules separated by semicolons, each written as `METHODS [PATH-PREFIX]=OPERATION`, e.g.,
+// `PUT,POST=upload; GET /files/=download; * /admin/=admin`. Methods are separated by commas, * meaning every method.
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		match, operation, ok := strings.Cut(part, "=")
+		match, operation = strings.TrimSpace(match), strings.TrimSpace(operation)
+		if !ok || match == "" || operation == "" {
+			return nil, fmt.Errorf("%w %q, expected METHODS [PATH-PREFIX]=OPERATION", ErrRuleInvalid, part)
+		}
+
+		rule := Rule{Operation: operation}
+		fields := strings.Fields(match)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%w %q, expected METHODS [PATH-PREFIX]=OPERATION", ErrRuleInvalid, part)
+		}
+		if fields[0] != "*" {
+			for _, method := range strings.Split(fields[0], ",") {
+				if method == "" {
+					return nil, fmt.Errorf("%w %q, empty method", ErrRuleInvalid, part)
+				}
+				rule.Methods = append(rule.Methods, strings.ToUpper(method))
+			}
+		}
+		if len(fields) == 2 {
+			rule.PathPrefix = fields[1]
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%w: no rules", ErrRuleInvalid)
+	}
+	return rules, nil
+}
+
+// matches reports whether the rule applies to a request.
+func (r Rule) matches(method, path string) bool {
+	if !strings.HasPrefix(path, r.PathPrefix) {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Operation returns the operation of the first rule matching a request or, failing that, its method in lower case.
+func Operation(rules []Rule, method, path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, r := range rules {
+		if r.matches(method, path) {
+			return r.Operation
+		}
+	}
+	return strings.ToLower(method)
+}
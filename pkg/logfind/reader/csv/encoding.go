@@ -0,0 +1,117 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding is the text encoding of a log.
+type Encoding string
+
+const (
+	UTF8 = Encoding("utf-8")
+	// Latin1 is ISO 8859-1, whose bytes are the first 256 Unicode code points.
+	Latin1 = Encoding("latin1")
+)
+
+// encodingNames maps the names ParseEncoding accepts, in lower case, to their Encoding.
+var encodingNames = map[string]Encoding{
+	"utf-8":      UTF8,
+	"utf8":       UTF8,
+	"latin1":     Latin1,
+	"latin-1":    Latin1,
+	"iso-8859-1": Latin1,
+	"iso8859-1":  Latin1,
+}
+
+// ParseEncoding returns the Encoding called name, e.g., utf-8 or latin1, ignoring case.
+func ParseEncoding(name string) (Encoding, error) {
+	if e, ok := encodingNames[strings.ToLower(name)]; ok {
+		return e, nil
+	}
+	return "", fmt.Errorf("%w %q, expected utf-8 or latin1", ErrEncodingUnknown, name)
+}
+
+// ParseDelimiter parses a field or comment delimiter written as a single character, or as \t or tab for a tab.
+func ParseDelimiter(s string) (rune, error) {
+	if s == `\t` || strings.EqualFold(s, "tab") {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("%w %q, expected a single character other than a quote or line break", ErrDelimiterInvalid, s)
+	}
+	return r, nil
+}
+
+// decoder returns a reader of r as UTF-8 text.
+func (e Encoding) decoder(r io.Reader) io.Reader {
+	if e == Latin1 {
+		return &latin1Reader{r: r}
+	}
+	return r
+}
+
+// latin1Reader converts Latin-1 text to UTF-8.
+type latin1Reader struct {
+	r   io.Reader
+	raw []byte
+	// pending is the text converted but not yet returned, and err the error to return after it.
+	pending []byte
+	err     error
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		if cap(l.raw) < len(p) {
+			l.raw = make([]byte, len(p))
+		}
+		var n int
+		n, l.err = l.r.Read(l.raw[:len(p)])
+		l.pending = l.pending[:0]
+		for _, b := range l.raw[:n] {
+			l.pending = utf8.AppendRune(l.pending, rune(b))
+		}
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	if len(l.pending) == 0 {
+		err := l.err
+		l.err = nil
+		return n, err
+	}
+	return n, nil
+}
+
+// bom is the UTF-8 byte order mark.
+var bom = []byte("\xef\xbb\xbf")
+
+// bomReader drops the byte order mark starting r, if any.
+type bomReader struct {
+	r       *bufio.Reader
+	checked bool
+	// stripped is the number of bytes dropped.
+	stripped int64
+}
+
+func newBOMReader(r io.Reader) *bomReader {
+	return &bomReader{r: bufio.NewReader(r)}
+}
+
+// Read checks for the byte order mark on the first call rather than on creation, so that logs which are still being
+// written, e.g., followed ones, are not waited for before they are read.
+func (b *bomReader) Read(p []byte) (int, error) {
+	if !b.checked {
+		b.checked = true
+		// Peeking waits for the whole mark even when r returns it in parts, e.g., from a pipe.
+		if head, _ := b.r.Peek(len(bom)); bytes.Equal(head, bom) {
+			n, _ := b.r.Discard(len(bom))
+			b.stripped = int64(n)
+		}
+	}
+	return b.r.Read(p)
+}
@@ -0,0 +1,62 @@
+package csv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseEncoding(t *testing.T) {
+	for name, want := range map[string]Encoding{"UTF-8": UTF8, "latin1": Latin1, "ISO-8859-1": Latin1} {
+		got, err := ParseEncoding(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseEncoding("cp1252")
+	assert.ErrorIs(t, err, ErrEncodingUnknown)
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for s, want := range map[string]rune{";": ';', `\t`: '\t', "tab": '\t', "|": '|', "§": '§'} {
+		got, err := ParseDelimiter(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	for _, s := range []string{"", ";;", `"`, "\n"} {
+		_, err := ParseDelimiter(s)
+		assert.ErrorIs(t, err, ErrDelimiterInvalid, s)
+	}
+}
+
+func TestLatin1Reader(t *testing.T) {
+	t.Run("converts every byte", func(t *testing.T) {
+		text, err := io.ReadAll(Latin1.decoder(iotest.OneByteReader(strings.NewReader("J\xfcrgen \xa9 \xff"))))
+		assert.NoError(t, err)
+		assert.Equal(t, "Jürgen © ÿ", string(text))
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		_, err := io.ReadAll(Latin1.decoder(iotest.TimeoutReader(strings.NewReader("J\xfcrgen"))))
+		assert.ErrorIs(t, err, iotest.ErrTimeout)
+	})
+}
+
+func TestBOMReader(t *testing.T) {
+	t.Run("strips byte order mark read in parts", func(t *testing.T) {
+		r := newBOMReader(iotest.OneByteReader(strings.NewReader("\ufefftimestamp")))
+		text, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "timestamp", string(text))
+		assert.Equal(t, int64(3), r.stripped)
+	})
+
+	t.Run("keeps text without byte order mark", func(t *testing.T) {
+		for _, input := range []string{"", "a", "\xef\xbb", "timestamp"} {
+			text, err := io.ReadAll(newBOMReader(iotest.OneByteReader(strings.NewReader(input))))
+			assert.NoError(t, err)
+			assert.Equal(t, input, string(text))
+		}
+	})
+}
@@ -2,44 +2,312 @@ package csv
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
 	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
 	"io"
-	"strconv"
+	"strings"
 	"time"
 )
 
+func init() {
+	lfReader.Register("csv", detect(',', ".csv"), newFormatReader(','))
+	lfReader.Register("tsv", detect('\t', ".tsv", ".tab"), newFormatReader('\t'))
+}
+
+// detect returns a lfReader.DetectFunc matching files with one of exts, or whose first line holds comma but no other
+// likely separator, parses as a record and does not start with a key=value pair.
+func detect(comma rune, exts ...string) lfReader.DetectFunc {
+	return func(name string, head []byte) lfReader.Confidence {
+		if c := lfReader.HasExtension(name, exts...); c != lfReader.NoMatch {
+			return c
+		}
+		line, _, _ := strings.Cut(string(head), "\n")
+		first, _, _ := strings.Cut(line, string(comma))
+		if strings.HasPrefix(strings.TrimSpace(line), "{") || !strings.ContainsRune(line, comma) || strings.Contains(first, "=") {
+			return lfReader.NoMatch
+		}
+		for _, other := range []rune{',', '\t'} {
+			if other != comma && strings.ContainsRune(line, other) {
+				return lfReader.NoMatch
+			}
+		}
+		csvReader := csv.NewReader(strings.NewReader(line))
+		csvReader.Comma = comma
+		if _, err := csvReader.Read(); err != nil {
+			return lfReader.NoMatch
+		}
+		return lfReader.WeakMatch
+	}
+}
+
+// newFormatReader returns the lfReader.NewFunc of the format separating fields with comma.
+func newFormatReader(comma rune) lfReader.NewFunc {
+	return func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithComma(comma), WithComment(opts.Comment), WithLazyQuotes(opts.LazyQuotes), WithStripBOM(true)}
+		if opts.Delimiter != 0 {
+			readerOpts = append(readerOpts, WithComma(opts.Delimiter))
+		}
+		if opts.Encoding != "" {
+			encoding, err := ParseEncoding(opts.Encoding)
+			if err != nil {
+				return nil, err
+			}
+			readerOpts = append(readerOpts, WithEncoding(encoding))
+		}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.TimestampLayout != "" {
+			readerOpts = append(readerOpts, WithTimestampLayout(opts.TimestampLayout))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		if opts.Columns != nil {
+			readerOpts = append(readerOpts, WithColumns(opts.Columns))
+		}
+		return NewReader(r, readerOpts...), nil
+	}
+}
+
+const (
+	ErrColumnMissing    = lfReader.Error("column missing from header")
+	ErrEncodingUnknown  = lfReader.Error("unknown encoding")
+	ErrDelimiterInvalid = lfReader.Error("invalid delimiter")
+)
+
 type reader struct {
 	csvReader  *csv.Reader
 	pastHeader bool
+	location   lfReader.Location
+	sizeUnit   lfReader.Size
+	// lines locates the lines of the log, bom drops its byte order mark, when enabled by WithStripBOM.
+	lines *lineReader
+	bom   *bomReader
+
+	comma      rune
+	comment    rune
+	lazyQuotes bool
+	stripBOM   bool
+	encoding   Encoding
+
+	timestampLayout string
+	loc             *time.Location
+	columns         map[string]string
+	// format is shared by the events read, and nil unless an option departs from the default format.
+	format *format
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithSizeUnit declares the unit of the size column. The default is lfReader.KB.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithComma sets the field separator, e.g., '\t' for tab separated values or ';'. The default is ','.
+func WithComma(comma rune) ReaderOptionFunc {
+	return func(r *reader) {
+		r.comma = comma
+	}
 }
 
-func NewReader(r io.Reader) lfReader.Reader {
-	return &reader{
-		csvReader: csv.NewReader(r),
+// WithComment sets the character starting comment lines, which are skipped, e.g., '#'. Zero, the default, disables
+// comments.
+func WithComment(comment rune) ReaderOptionFunc {
+	return func(r *reader) {
+		r.comment = comment
 	}
 }
 
+// WithLazyQuotes tolerates quotes within unquoted fields and unescaped quotes within quoted fields, see
+// csv.Reader.LazyQuotes.
+func WithLazyQuotes(lazyQuotes bool) ReaderOptionFunc {
+	return func(r *reader) {
+		r.lazyQuotes = lazyQuotes
+	}
+}
+
+// WithStripBOM drops the UTF-8 byte order mark some tools, e.g., spreadsheets, write at the start of exports, which
+// would otherwise become part of the first column.
+func WithStripBOM(strip bool) ReaderOptionFunc {
+	return func(r *reader) {
+		r.stripBOM = strip
+	}
+}
+
+// WithEncoding declares the text encoding of the log. The default is UTF8.
+func WithEncoding(encoding Encoding) ReaderOptionFunc {
+	return func(r *reader) {
+		r.encoding = encoding
+	}
+}
+
+// WithTimestampLayout declares the layout of the timestamp column, see time.Parse. The default is time.UnixDate.
+func WithTimestampLayout(layout string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the time zone of timestamps whose layout has no zone. The default is UTC.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldUsername, to the names of the columns holding them.
+// The first record must then be a header naming every mapped column, while further columns are ignored.
+// Fields that are not mapped are read from the column named after them.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		sizeUnit: lfReader.KB,
+		comma:    ',',
+		encoding: UTF8,
+	}
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	rdr.lines = newLineReader(r)
+	r = rdr.lines
+	if rdr.stripBOM {
+		rdr.bom = newBOMReader(r)
+		r = rdr.bom
+	}
+	rdr.csvReader = csv.NewReader(rdr.encoding.decoder(r))
+	rdr.csvReader.Comma = rdr.comma
+	rdr.csvReader.Comment = rdr.comment
+	rdr.csvReader.LazyQuotes = rdr.lazyQuotes
+	if rdr.timestampLayout != "" || rdr.loc != nil || rdr.columns != nil {
+		rdr.format = newFormat(rdr.timestampLayout, rdr.loc)
+	}
+	return rdr
+}
+
 // Read reads one event from r.
 // If the record has an unexpected number of fields,
-// Read returns the record along with the error ErrFieldCount.
+// Read returns the record along with a *lfReader.RecordError wrapping ErrFieldCount.
+// Other malformed records are also reported as a *lfReader.RecordError.
 // Except for that case, Read always returns either a non-nil
 // record or a non-nil error, but not both.
 // If there is no data left to be read, Read returns nil, io.EOF.
 func (r *reader) Read() (e lfReader.Event, err error) {
-	record, err := r.csvReader.Read()
+	record, err := r.read()
+	if !r.pastHeader && r.columns != nil && err == nil {
+		r.pastHeader = true
+		if err = r.format.mapColumns(record, r.columns); err != nil {
+			return nil, err
+		}
+		record, err = r.read()
+	}
 	if !r.pastHeader && len(record) == fieldCount {
-		if record[indexTimestamp] == "timestamp" &&
-			record[indexUsername] == "username" &&
-			record[indexOperation] == "operation" &&
-			record[indexSize] == "size" {
+		r.pastHeader = true
+		if record[indexTimestamp] == lfReader.FieldTimestamp &&
+			record[indexUsername] == lfReader.FieldUsername &&
+			record[indexOperation] == lfReader.FieldOperation &&
+			record[indexSize] == lfReader.FieldSize {
 			// Skip header
-			record, err = r.csvReader.Read()
+			record, err = r.read()
 		}
 	}
-	e = event(record)
+	e = event{
+		record:   record,
+		location: r.location,
+		sizeUnit: r.sizeUnit,
+		format:   r.format,
+	}
 	return
 }
 
+// read reads one record from the underlying csv.Reader and keeps track of its location.
+func (r *reader) read() (record []string, err error) {
+	record, err = r.csvReader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		r.locate(parseErr.StartLine)
+		err = &lfReader.RecordError{
+			Source: r.location.Source,
+			Line:   r.location.Line,
+			Offset: r.location.Offset,
+			Err:    parseErr.Err,
+		}
+		return
+	}
+	if len(record) > 0 {
+		line, _ := r.csvReader.FieldPos(0)
+		r.locate(line)
+	}
+	return
+}
+
+// locate sets the location to the start of line, which is where records start.
+func (r *reader) locate(line int) {
+	r.location.Line = line
+	r.location.Offset = r.lines.start(line)
+	if line == 1 && r.bom != nil {
+		r.location.Offset += r.bom.stripped
+	}
+}
+
+// lineReader records the offsets at which the lines of r start, since csv.Reader only reports the offset it read up
+// to, which precedes the blank and comment lines before a record.
+type lineReader struct {
+	r      io.Reader
+	offset int64
+	// starts holds the offsets of the lines from first on.
+	first  int
+	starts []int64
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: r, first: 1, starts: []int64{0}}
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.starts = append(l.starts, l.offset+int64(i)+1)
+		}
+	}
+	l.offset += int64(n)
+	return n, err
+}
+
+// start returns the offset of line, forgetting those of the lines before it, which records have already passed.
+func (l *lineReader) start(line int) int64 {
+	i := line - l.first
+	if i < 0 || i >= len(l.starts) {
+		return l.offset
+	}
+	l.starts = l.starts[i:]
+	l.first = line
+	return l.starts[0]
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
 const fieldCount = 4
 const (
 	indexTimestamp = iota
@@ -48,41 +316,152 @@ const (
 	indexSize
 )
 
+// fieldNames maps each index to the name of the field it holds.
+var fieldNames = [fieldCount]string{
+	indexTimestamp: lfReader.FieldTimestamp,
+	indexUsername:  lfReader.FieldUsername,
+	indexOperation: lfReader.FieldOperation,
+	indexSize:      lfReader.FieldSize,
+}
+
+// format describes a CSV log whose layout departs from the default of the four fields in order with
+// time.UnixDate timestamps in UTC.
+type format struct {
+	// columns holds the column of each field, by the index of the field.
+	columns         [fieldCount]int
+	width           int
+	timestampLayout string
+	loc             *time.Location
+}
+
+func newFormat(timestampLayout string, loc *time.Location) *format {
+	if timestampLayout == "" {
+		timestampLayout = time.UnixDate
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &format{
+		columns:         [fieldCount]int{indexTimestamp, indexUsername, indexOperation, indexSize},
+		width:           fieldCount,
+		timestampLayout: timestampLayout,
+		loc:             loc,
+	}
+}
+
+// mapColumns finds the column of each field in header, which names the columns. Fields are looked up by their name in
+// columns, or by their own name when they are not mapped.
+func (f *format) mapColumns(header []string, columns map[string]string) error {
+	for name := range columns {
+		known := false
+		for _, field := range fieldNames {
+			known = known || name == field
+		}
+		if !known {
+			return fmt.Errorf("unknown field %q, expected timestamp, username, operation or size", name)
+		}
+	}
+
+	for index, field := range fieldNames {
+		name, ok := columns[field]
+		if !ok {
+			name = field
+		}
+		f.columns[index] = -1
+		for i, column := range header {
+			if column == name {
+				f.columns[index] = i
+				break
+			}
+		}
+		if f.columns[index] < 0 {
+			return fmt.Errorf("%w: %q", ErrColumnMissing, name)
+		}
+	}
+	f.width = len(header)
+	return nil
+}
+
 // event is the concrete implementation of reader.Event
 // Note: Even though the fields below are based on slice index we are safe because the csv.Reader detects and errors
 // on unexpected number of fields.
-type event []string
+type event struct {
+	record   []string
+	location lfReader.Location
+	sizeUnit lfReader.Size
+	// format is nil for logs in the default format.
+	format *format
+}
+
+// field returns the raw value at index or a *lfReader.RecordError when the record has an unexpected number of fields.
+func (e event) field(index int) (string, error) {
+	if e.format == nil {
+		if len(e.record) != fieldCount {
+			return "", e.error(-1, csv.ErrFieldCount)
+		}
+		return e.record[index], nil
+	}
+	if len(e.record) != e.format.width {
+		return "", e.error(-1, csv.ErrFieldCount)
+	}
+	return e.record[e.format.columns[index]], nil
+}
+
+// error wraps err in a *lfReader.RecordError describing the field at index. An index of -1 describes the whole record.
+func (e event) error(index int, err error) error {
+	recErr := &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Err:    err,
+	}
+	if index >= 0 {
+		recErr.Field = fieldNames[index]
+		recErr.Value, _ = e.field(index)
+	}
+	return recErr
+}
 
 func (e event) Timestamp() (timestamp time.Time, err error) {
-	if len(e) != fieldCount {
-		err = csv.ErrFieldCount
+	value, err := e.field(indexTimestamp)
+	if err != nil {
 		return
 	}
-	//TODO: Make format configurable
-	return time.Parse(time.UnixDate, e[indexTimestamp])
+	if e.format == nil {
+		timestamp, err = time.Parse(time.UnixDate, value)
+	} else {
+		timestamp, err = time.ParseInLocation(e.format.timestampLayout, value, e.format.loc)
+	}
+	if err != nil {
+		err = e.error(indexTimestamp, err)
+	}
+	return
 }
 
 func (e event) Username() (username string, err error) {
-	if len(e) != fieldCount {
-		err = csv.ErrFieldCount
-		return
-	}
-	return e[indexUsername], nil
+	return e.field(indexUsername)
 }
 
 func (e event) Operation() (op string, err error) {
-	if len(e) != fieldCount {
-		err = csv.ErrFieldCount
+	return e.field(indexOperation)
+}
+
+func (e event) Size() (size lfReader.Size, err error) {
+	value, err := e.field(indexSize)
+	if err != nil {
 		return
 	}
-	return e[indexOperation], nil
+	size, err = lfReader.ParseSize(value, e.unit())
+	if err != nil {
+		err = e.error(indexSize, err)
+	}
+	return
 }
 
-func (e event) Size() (size int, err error) {
-	if len(e) != fieldCount {
-		err = csv.ErrFieldCount
-		return
+// unit returns the unit of the size column, defaulting to lfReader.KB for events that were not created by a reader.
+func (e event) unit() lfReader.Size {
+	if e.sizeUnit == 0 {
+		return lfReader.KB
 	}
-	sizeStr := e[indexSize]
-	return strconv.Atoi(sizeStr)
+	return e.sizeUnit
 }
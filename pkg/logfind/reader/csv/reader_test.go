@@ -2,9 +2,12 @@ package csv
 
 import (
 	"encoding/csv"
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -14,27 +17,197 @@ func TestNewReader(t *testing.T) {
 		r := NewReader(input)
 		e, err := r.Read()
 		assert.Nil(t, err)
-		assert.Equal(t, event([]string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"}), e)
+		assert.Equal(t, event{
+			record:   []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"},
+			location: lfReader.Location{Line: 1},
+			sizeUnit: lfReader.KB,
+		}, e)
+	})
+
+	t.Run("skips header", func(t *testing.T) {
+		input := strings.NewReader("timestamp,username,operation,size\nSun Apr 12 22:10:38 UTC 2020,sarah94,download,34")
+		r := NewReader(input)
+		e, err := r.Read()
+		assert.Nil(t, err)
+		assert.Equal(t, event{
+			record:   []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"},
+			location: lfReader.Location{Line: 2, Offset: 34},
+			sizeUnit: lfReader.KB,
+		}, e)
+	})
+}
+
+func Test_reader_Read(t *testing.T) {
+	t.Run("respects size unit", func(t *testing.T) {
+		input := strings.NewReader("Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34")
+		r := NewReader(input, WithSizeUnit(lfReader.KiB))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		size, err := e.Size()
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Size(34*1024), size)
+	})
+
+	t.Run("reads decimal sizes and rejects negative ones", func(t *testing.T) {
+		r := NewReader(strings.NewReader("Sun Apr 12 22:10:38 UTC 2020,sarah94,download,1.5\nSun Apr 12 22:10:38 UTC 2020,sarah94,download,-1"))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		size, err := e.Size()
+		assert.NoError(t, err)
+		assert.Equal(t, 1500*lfReader.Byte, size)
+
+		e, err = r.Read()
+		assert.NoError(t, err)
+		_, err = e.Size()
+		var recErr *lfReader.RecordError
+		assert.ErrorAs(t, err, &recErr)
+		assert.Equal(t, lfReader.FieldSize, recErr.Field)
+	})
+
+	t.Run("respects timestamp layout and location", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		assert.NoError(t, err)
+		input := strings.NewReader("2020-04-12 18:10:38,sarah94,download,34")
+		r := NewReader(input, WithTimestampLayout("2006-01-02 15:04:05"), WithLocation(loc))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		timestamp, err := e.Timestamp()
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC).Equal(timestamp))
+	})
+
+	t.Run("respects columns", func(t *testing.T) {
+		input := strings.NewReader("bytes,user,host,action,timestamp\n34,sarah94,web1,download,Sun Apr 12 22:10:38 UTC 2020\n")
+		r := NewReader(input, WithColumns(map[string]string{
+			lfReader.FieldUsername:  "user",
+			lfReader.FieldOperation: "action",
+			lfReader.FieldSize:      "bytes",
+		}), WithSizeUnit(lfReader.Byte))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Record{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34}, rec)
+	})
+
+	t.Run("reports the value of a mapped column", func(t *testing.T) {
+		input := strings.NewReader("user,action,bytes,time\nsarah94,download,many,Sun Apr 12 22:10:38 UTC 2020\n")
+		r := NewReader(input, WithColumns(map[string]string{"username": "user", "operation": "action", "size": "bytes", "timestamp": "time"}))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Size()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, "many", recErr.Value)
+	})
+
+	t.Run("fails on column missing from header", func(t *testing.T) {
+		input := strings.NewReader("user,action,bytes\nsarah94,download,34\n")
+		r := NewReader(input, WithColumns(map[string]string{"username": "user", "operation": "action", "size": "bytes"}))
+		_, err := r.Read()
+		assert.ErrorIs(t, err, ErrColumnMissing)
+		assert.ErrorContains(t, err, `"timestamp"`)
+	})
+
+	t.Run("fails on unknown field", func(t *testing.T) {
+		r := NewReader(strings.NewReader("a,b\n"), WithColumns(map[string]string{"host": "a"}))
+		_, err := r.Read()
+		assert.ErrorContains(t, err, `unknown field "host"`)
+	})
+
+	t.Run("reports location", func(t *testing.T) {
+		input := strings.NewReader("timestamp,username,operation,size\n\nSun Apr 12 22:10:38 UTC 2020,sarah94,download,34")
+		r := NewReader(input)
+		_, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Location{Line: 3, Offset: 35}, r.(lfReader.Locator).Location())
+	})
+
+	t.Run("respects delimiter, comment and lazy quotes", func(t *testing.T) {
+		input := strings.NewReader("# exported 2020-04-13\nSun Apr 12 22:10:38 UTC 2020;sarah\"94;download;34\n")
+		r := NewReader(input, WithComma(';'), WithComment('#'), WithLazyQuotes(true))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		username, err := e.Username()
+		assert.NoError(t, err)
+		assert.Equal(t, `sarah"94`, username)
+		assert.Equal(t, lfReader.Location{Line: 2, Offset: 22}, r.(lfReader.Locator).Location())
+	})
+
+	t.Run("strips byte order mark", func(t *testing.T) {
+		input := iotest.OneByteReader(strings.NewReader("\ufefftimestamp,username,operation,size\nSun Apr 12 22:10:38 UTC 2020,sarah94,download,34\n"))
+		r := NewReader(input, WithStripBOM(true))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		username, err := e.Username()
+		assert.NoError(t, err)
+		assert.Equal(t, "sarah94", username)
+		assert.Equal(t, lfReader.Location{Line: 2, Offset: 37}, r.(lfReader.Locator).Location())
+	})
+
+	t.Run("respects encoding", func(t *testing.T) {
+		input := strings.NewReader("Sun Apr 12 22:10:38 UTC 2020,J\xfcrgen,download,34\nSun Apr 12 22:35:06 UTC 2020,\xfc\n")
+		r := NewReader(input, WithEncoding(Latin1))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		username, err := e.Username()
+		assert.NoError(t, err)
+		assert.Equal(t, "Jürgen", username)
+
+		// Offsets count the bytes of the log rather than of the text converted to UTF-8.
+		_, err = r.Read()
+		assert.ErrorIs(t, err, csv.ErrFieldCount)
+		assert.Equal(t, lfReader.Location{Line: 2, Offset: 48}, r.(lfReader.Locator).Location())
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		input := strings.NewReader("Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34\nSun Apr 12 22:35:06 UTC 2020,Maia86\n")
+		r := NewReader(input)
+		_, err := r.Read()
+		assert.NoError(t, err)
+
+		_, err = r.Read()
+		assert.ErrorIs(t, err, csv.ErrFieldCount)
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, &lfReader.RecordError{Line: 2, Offset: 49, Err: csv.ErrFieldCount}, recErr)
 	})
 }
 
 func Test_event_Timestamp(t *testing.T) {
 	t.Run("can parse timestamp as Unix Date", func(t *testing.T) {
-		e := event([]string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"})
+		e := event{record: []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"}}
 		gotTimestamp, err := e.Timestamp()
 		assert.NoError(t, err)
 		assert.Equal(t, time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), gotTimestamp)
 	})
 
 	t.Run("fails on unexpected timestamp format", func(t *testing.T) {
-		e := event([]string{"2022-01-01T00:00:00.000Z", "sarah94", "download", "34"})
+		e := event{record: []string{"2022-01-01T00:00:00.000Z", "sarah94", "download", "34"}}
 		gotTimestamp, err := e.Timestamp()
 		assert.Error(t, err)
 		assert.Empty(t, gotTimestamp)
 	})
 
+	t.Run("reports position of unexpected timestamp format", func(t *testing.T) {
+		e := event{
+			record:   []string{"2022-01-01T00:00:00.000Z", "sarah94", "download", "34"},
+			location: lfReader.Location{Source: "server_log.csv", Line: 3, Offset: 84},
+		}
+		_, err := e.Timestamp()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, "server_log.csv", recErr.Source)
+		assert.Equal(t, 3, recErr.Line)
+		assert.Equal(t, int64(84), recErr.Offset)
+		assert.Equal(t, lfReader.FieldTimestamp, recErr.Field)
+		assert.Equal(t, "2022-01-01T00:00:00.000Z", recErr.Value)
+		var parseErr *time.ParseError
+		assert.True(t, errors.As(err, &parseErr))
+	})
+
 	t.Run("respects expected field count", func(t *testing.T) {
-		e := event([]string{})
+		e := event{record: []string{}}
 		gotTimestamp, err := e.Timestamp()
 		assert.ErrorIs(t, err, csv.ErrFieldCount)
 		assert.Empty(t, gotTimestamp)
@@ -43,14 +216,14 @@ func Test_event_Timestamp(t *testing.T) {
 
 func Test_event_Username(t *testing.T) {
 	t.Run("can parse username", func(t *testing.T) {
-		e := event([]string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"})
+		e := event{record: []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"}}
 		gotUsername, err := e.Username()
 		assert.NoError(t, err)
 		assert.Equal(t, "sarah94", gotUsername)
 	})
 
 	t.Run("respects expected field count", func(t *testing.T) {
-		e := event([]string{})
+		e := event{record: []string{}}
 		gotUsername, err := e.Username()
 		assert.ErrorIs(t, err, csv.ErrFieldCount)
 		assert.Empty(t, gotUsername)
@@ -59,14 +232,14 @@ func Test_event_Username(t *testing.T) {
 
 func Test_event_Operation(t *testing.T) {
 	t.Run("can parse username", func(t *testing.T) {
-		e := event([]string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"})
+		e := event{record: []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"}}
 		gotOperation, err := e.Operation()
 		assert.NoError(t, err)
 		assert.Equal(t, "download", gotOperation)
 	})
 
 	t.Run("respects expected field count", func(t *testing.T) {
-		e := event([]string{})
+		e := event{record: []string{}}
 		gotOperation, err := e.Operation()
 		assert.ErrorIs(t, err, csv.ErrFieldCount)
 		assert.Empty(t, gotOperation)
@@ -75,16 +248,57 @@ func Test_event_Operation(t *testing.T) {
 
 func Test_event_Size(t *testing.T) {
 	t.Run("can parse username", func(t *testing.T) {
-		e := event([]string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"})
+		e := event{record: []string{"Sun Apr 12 22:10:38 UTC 2020", "sarah94", "download", "34"}}
 		gotSize, err := e.Size()
 		assert.NoError(t, err)
-		assert.Equal(t, 34, gotSize)
+		assert.Equal(t, 34*lfReader.KB, gotSize)
 	})
 
 	t.Run("respects expected field count", func(t *testing.T) {
-		e := event([]string{})
+		e := event{record: []string{}}
 		gotSize, err := e.Size()
 		assert.ErrorIs(t, err, csv.ErrFieldCount)
 		assert.Empty(t, gotSize)
 	})
 }
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		head string
+		want string
+	}{
+		{name: "csv by extension", file: "log.CSV", want: "csv"},
+		{name: "tsv by extension", file: "log.tsv", want: "tsv"},
+		{name: "csv by content", file: "log.txt", head: "timestamp,username,operation,size\n", want: "csv"},
+		{name: "tsv by content", head: "Sun Apr 12 22:10:38 UTC 2020\tsarah94\tdownload\t34\n", want: "tsv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lfReader.Detect(tt.file, []byte(tt.head))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("reads semicolon separated latin1", func(t *testing.T) {
+		input := "\ufefftimestamp;username;operation;size\nSun Apr 12 22:10:38 UTC 2020;J\xfcrgen;download;34\n"
+		r, err := lfReader.NewFormatReader(strings.NewReader(input), "csv", lfReader.FormatOptions{Delimiter: ';', Encoding: "ISO-8859-1"})
+		assert.NoError(t, err)
+		records, err := lfReader.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "Jürgen", Op: "download", Bytes: 34 * lfReader.KB}}, records)
+
+		_, err = lfReader.NewFormatReader(strings.NewReader(input), "csv", lfReader.FormatOptions{Encoding: "ebcdic"})
+		assert.ErrorIs(t, err, ErrEncodingUnknown)
+	})
+
+	t.Run("reads tsv", func(t *testing.T) {
+		r, err := lfReader.NewFormatReader(strings.NewReader("Sun Apr 12 22:10:38 UTC 2020\tsarah94\tdownload\t34\n"), "", lfReader.FormatOptions{})
+		assert.NoError(t, err)
+		records, err := lfReader.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34 * lfReader.KB}}, records)
+	})
+}
@@ -0,0 +1,152 @@
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the kind of value held by a column as inferred by InferSchema.
+type ColumnType string
+
+const (
+	TypeTimestamp = ColumnType("timestamp")
+	TypeInteger   = ColumnType("integer")
+	TypeFloat     = ColumnType("float")
+	TypeBoolean   = ColumnType("boolean")
+	TypeString    = ColumnType("string")
+	// TypeEmpty is used for columns whose sampled values were all empty.
+	TypeEmpty = ColumnType("empty")
+)
+
+// Column describes a column of a CSV log.
+type Column struct {
+	// Name is taken from the header when there is one, otherwise it is column1, column2, etc.
+	Name string
+	Type ColumnType
+	// Example is the first non-empty value sampled from the column.
+	Example string
+}
+
+// timestampLayouts are the layouts InferSchema recognizes as timestamps.
+var timestampLayouts = []string{
+	time.UnixDate,
+	time.RFC3339Nano,
+	time.RFC1123,
+	time.RFC1123Z,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// InferSchema reads up to sample records from r and infers the name and type of each column.
+// The first record is treated as a header when all of its values are text while a later record is not,
+// or when it has no records after it.
+func InferSchema(r io.Reader, sample int) ([]Column, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+
+	var records [][]string
+	for len(records) < sample {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var header []string
+	if isHeader(records) {
+		header, records = records[0], records[1:]
+	}
+
+	width := len(header)
+	for _, record := range records {
+		if len(record) > width {
+			width = len(record)
+		}
+	}
+
+	columns := make([]Column, width)
+	for i := range columns {
+		columns[i].Type = TypeEmpty
+		if i < len(header) && strings.TrimSpace(header[i]) != "" {
+			columns[i].Name = strings.TrimSpace(header[i])
+		} else {
+			columns[i].Name = fmt.Sprintf("column%d", i+1)
+		}
+	}
+	for _, record := range records {
+		for i, value := range record {
+			t := typeOf(value)
+			if t != TypeEmpty && columns[i].Example == "" {
+				columns[i].Example = value
+			}
+			columns[i].Type = mergeTypes(columns[i].Type, t)
+		}
+	}
+	return columns, nil
+}
+
+func isHeader(records [][]string) bool {
+	for _, value := range records[0] {
+		if typeOf(value) != TypeString {
+			return false
+		}
+	}
+	if len(records) == 1 {
+		return true
+	}
+	for _, record := range records[1:] {
+		for _, value := range record {
+			if t := typeOf(value); t != TypeString && t != TypeEmpty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(value string) ColumnType {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return TypeEmpty
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return TypeInteger
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return TypeFloat
+	}
+	if _, err := strconv.ParseBool(value); err == nil {
+		return TypeBoolean
+	}
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return TypeTimestamp
+		}
+	}
+	return TypeString
+}
+
+// mergeTypes returns the narrowest type able to hold values of both a and b.
+func mergeTypes(a, b ColumnType) ColumnType {
+	switch {
+	case a == b || b == TypeEmpty:
+		return a
+	case a == TypeEmpty:
+		return b
+	case a == TypeInteger && b == TypeFloat, a == TypeFloat && b == TypeInteger:
+		return TypeFloat
+	default:
+		return TypeString
+	}
+}
@@ -0,0 +1,47 @@
+package csv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	t.Run("uses header", func(t *testing.T) {
+		input := "timestamp,username,operation,size\nSun Apr 12 22:10:38 UTC 2020,sarah94,download,34\nSun Apr 12 22:35:06 UTC 2020,Maia86,download,75\n"
+		columns, err := InferSchema(strings.NewReader(input), 100)
+		assert.NoError(t, err)
+		assert.Equal(t, []Column{
+			{Name: "timestamp", Type: TypeTimestamp, Example: "Sun Apr 12 22:10:38 UTC 2020"},
+			{Name: "username", Type: TypeString, Example: "sarah94"},
+			{Name: "operation", Type: TypeString, Example: "download"},
+			{Name: "size", Type: TypeInteger, Example: "34"},
+		}, columns)
+	})
+
+	t.Run("names columns without header", func(t *testing.T) {
+		input := "2020-04-12,sarah94,1.5,true\n2020-04-13,,2,false\n"
+		columns, err := InferSchema(strings.NewReader(input), 100)
+		assert.NoError(t, err)
+		assert.Equal(t, []Column{
+			{Name: "column1", Type: TypeTimestamp, Example: "2020-04-12"},
+			{Name: "column2", Type: TypeString, Example: "sarah94"},
+			{Name: "column3", Type: TypeFloat, Example: "1.5"},
+			{Name: "column4", Type: TypeBoolean, Example: "true"},
+		}, columns)
+	})
+
+	t.Run("widens mixed columns to string", func(t *testing.T) {
+		input := "a,b\n1,x\nfoo,2\n"
+		columns, err := InferSchema(strings.NewReader(input), 100)
+		assert.NoError(t, err)
+		assert.Equal(t, TypeString, columns[0].Type)
+		assert.Equal(t, TypeString, columns[1].Type)
+	})
+
+	t.Run("fails on empty input", func(t *testing.T) {
+		_, err := InferSchema(strings.NewReader(""), 100)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is the type of sentinel errors, which can be declared as constants.
+type Error string
+
+var _ error = (*Error)(nil)
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// RecordError describes a log record that could not be read or interpreted.
+// Readers return it to pinpoint malformed records and Finders propagate it,
+// so it may be retrieved from either with errors.As.
+type RecordError struct {
+	// Source is the name of the log stream, e.g., a file path. It is empty when the stream is unnamed.
+	Source string
+	// Line is the 1-based line number on which the record starts, or 0 when unknown.
+	Line int
+	// Offset is the byte offset at which the record starts.
+	Offset int64
+	// Field is the name of the event field that failed, e.g., FieldTimestamp.
+	// It is empty when the record as a whole is malformed.
+	Field string
+	// Value is the raw value of Field as it appeared in the log stream.
+	Value string
+	// Err is the underlying cause, e.g., a *time.ParseError.
+	Err error
+}
+
+var _ error = (*RecordError)(nil)
+
+func (e *RecordError) Error() string {
+	var b strings.Builder
+	if e.Source != "" {
+		b.WriteString(e.Source)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
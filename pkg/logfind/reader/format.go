@@ -0,0 +1,243 @@
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrFormatUnknown    = errors.New("unknown log format")
+	ErrFormatUndetected = errors.New("could not detect the log format")
+)
+
+// SniffSize is the number of bytes at the start of a log that formats are detected from.
+const SniffSize = 4096
+
+// Confidence is how sure a DetectFunc is that a log is in its format.
+type Confidence int
+
+const (
+	// NoMatch means the log is not in the format.
+	NoMatch Confidence = iota
+	// WeakMatch means the start of the log fits the format, but the format is loose enough to fit logs in others too,
+	// e.g., CSV. It is outranked by ContentMatch.
+	WeakMatch
+	// ContentMatch means the start of the log fits the format.
+	ContentMatch
+	// ExtensionMatch means the file name has an extension of the format. It outranks ContentMatch.
+	ExtensionMatch
+	// SignatureMatch means the start of the log holds a marker unique to the format, e.g., a distinctive key. It
+	// outranks ExtensionMatch, since formats may share extensions, e.g., .json.
+	SignatureMatch
+)
+
+// DetectFunc judges whether a log is in a format from its name, e.g., a file path, and head, its first SniffSize
+// bytes or fewer. name is empty for unnamed logs such as stdin.
+type DetectFunc func(name string, head []byte) Confidence
+
+// FormatOptions are the settings of the user that readers apply where they concern their format.
+type FormatOptions struct {
+	// SizeUnit is the unit of sizes without one. Zero means the format's default, e.g., KB for CSV.
+	SizeUnit Size
+	// TimestampLayout is the layout of timestamps, see time.Parse. Empty means the format's default.
+	TimestampLayout string
+	// Location is the time zone of timestamps without one. Nil means UTC.
+	Location *time.Location
+	// Columns maps event fields, e.g., FieldUsername, to the names the log gives them, for formats with named fields.
+	Columns map[string]string
+	// LogFormat is the layout of the lines of formats configured with one, e.g., an nginx log_format string for access
+	// logs. Empty means the format's default.
+	LogFormat string
+	// Pattern is a regular expression whose named groups, e.g., (?P<username>\S+), extract the fields of events from
+	// free-form messages, for formats that have them, e.g., syslog. Empty means the format's default.
+	Pattern string
+	// Year is the year of the first timestamp of a log whose timestamps lack one, e.g., RFC 3164 syslog. Zero means
+	// the latest year that does not put it in the future.
+	Year int
+	// OperationRules derive the operation of events from their other fields, for formats without one, e.g.,
+	// "PUT,POST=upload;GET=download" for access logs. Empty means the format's default.
+	OperationRules string
+	// Delimiter separates the fields of delimited formats, e.g., ';' for csv. Zero means the format's default.
+	Delimiter rune
+	// Comment starts lines to skip, for delimited formats. Zero means there are no comments.
+	Comment rune
+	// LazyQuotes tolerates stray quotes in fields, for delimited formats.
+	LazyQuotes bool
+	// Encoding is the text encoding of the log, e.g., latin1, for formats that support others than UTF-8. Empty means
+	// UTF-8.
+	Encoding string
+}
+
+// NewFunc returns a Reader over the log r in a format. When r has a Name method, e.g., an *os.File, it names the log.
+// It fails when opts are invalid for the format, e.g., a malformed pattern.
+type NewFunc func(r io.Reader, opts FormatOptions) (Reader, error)
+
+type format struct {
+	name   string
+	detect DetectFunc
+	newFn  NewFunc
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []format
+)
+
+// Register makes a format available by name to NewFormatReader and Detect. It is meant to be called from the init
+// func of the package implementing the format, so importing that package is enough to read logs in its format.
+// Registering a name twice panics.
+func Register(name string, detect DetectFunc, newFn NewFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	for _, f := range formats {
+		if f.name == name {
+			panic(fmt.Sprintf("reader: format %q registered twice", name))
+		}
+	}
+	formats = append(formats, format{name: name, detect: detect, newFn: newFn})
+}
+
+// Formats returns the names of the registered formats in order.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect returns the name of the registered format that is most confident it fits the log with the given name and
+// head, see DetectFunc. Ties go to the format registered first.
+func Detect(name string, head []byte) (string, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	best, bestConfidence := "", NoMatch
+	for _, f := range formats {
+		if c := f.detect(name, head); c > bestConfidence {
+			best, bestConfidence = f.name, c
+		}
+	}
+	if best == "" {
+		return "", ErrFormatUndetected
+	}
+	return best, nil
+}
+
+func lookupFormat(name string) (NewFunc, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, f := range formats {
+		if f.name == name {
+			return f.newFn, true
+		}
+	}
+	return nil, false
+}
+
+// HasExtension returns ExtensionMatch when the extension of name is one of exts, e.g., ".csv", ignoring case,
+// and NoMatch otherwise. It helps implement DetectFuncs.
+func HasExtension(name string, exts ...string) Confidence {
+	ext := filepath.Ext(name)
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return ExtensionMatch
+		}
+	}
+	return NoMatch
+}
+
+// NewFormatReader returns a Reader over the log r in the named format. When formatName is empty the format is
+// detected with Detect, from the name of r, when it has a Name method, and its first bytes. Detection is deferred
+// to the first Read so that logs which are still being written, e.g., followed ones, are not waited for; invalid
+// opts are then reported by the first Read too.
+func NewFormatReader(r io.Reader, formatName string, opts FormatOptions) (Reader, error) {
+	if formatName != "" {
+		newFn, ok := lookupFormat(formatName)
+		if !ok {
+			return nil, fmt.Errorf("%w %q, expected one of %s", ErrFormatUnknown, formatName, strings.Join(Formats(), ", "))
+		}
+		return newFn(r, opts)
+	}
+	return &detectingReader{r: r, opts: opts}, nil
+}
+
+// detectingReader detects the format of a log on the first Read.
+type detectingReader struct {
+	r    io.Reader
+	opts FormatOptions
+	// rdr is the reader of the detected format, err the failure to detect it.
+	rdr Reader
+	err error
+}
+
+var _ Locator = (*detectingReader)(nil)
+
+func (d *detectingReader) Read() (Event, error) {
+	if d.rdr == nil && d.err == nil {
+		d.rdr, d.err = d.detect()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.rdr.Read()
+}
+
+func (d *detectingReader) detect() (Reader, error) {
+	var name string
+	if named, ok := d.r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	// A single read is sniffed rather than waiting for SniffSize bytes, which a followed log may never reach.
+	br := bufio.NewReaderSize(d.r, SniffSize)
+	_, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	head, _ := br.Peek(br.Buffered())
+
+	formatName, err := Detect(name, head)
+	if err != nil {
+		if name != "" {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, err
+	}
+	newFn, _ := lookupFormat(formatName)
+	return newFn(namedReader{Reader: br, name: name}, d.opts)
+}
+
+// Location reports the location of the most recently read event when the reader of the detected format is a Locator.
+func (d *detectingReader) Location() Location {
+	if l, ok := d.rdr.(Locator); ok {
+		return l.Location()
+	}
+	return Location{}
+}
+
+// namedReader keeps the name of a log that has been wrapped, e.g., in a bufio.Reader.
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (n namedReader) Name() string {
+	return n.name
+}
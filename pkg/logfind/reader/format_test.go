@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineReader yields a Record per line of its input, naming the user after the line and the operation after the
+// source of the log, so tests can tell which reader was chosen and what it was given.
+func lineReader(op string) NewFunc {
+	return func(r io.Reader, opts FormatOptions) (Reader, error) {
+		b, _ := io.ReadAll(r)
+		source := ""
+		if named, ok := r.(interface{ Name() string }); ok {
+			source = named.Name()
+		}
+		var records []Record
+		for _, line := range strings.Fields(string(b)) {
+			records = append(records, Record{Time: time.Unix(0, 0), User: line, Op: op + ":" + source, Bytes: opts.SizeUnit})
+		}
+		return NewSliceReader(records), nil
+	}
+}
+
+type namedBuffer struct {
+	*bytes.Buffer
+	name string
+}
+
+func (n namedBuffer) Name() string {
+	return n.name
+}
+
+func init() {
+	Register("test-angle", func(name string, head []byte) Confidence {
+		if c := HasExtension(name, ".angle"); c != NoMatch {
+			return c
+		}
+		if bytes.HasPrefix(head, []byte("<")) {
+			return ContentMatch
+		}
+		return NoMatch
+	}, lineReader("angle"))
+	Register("test-bang", func(name string, head []byte) Confidence {
+		if bytes.HasPrefix(head, []byte("!")) {
+			return ContentMatch
+		}
+		return NoMatch
+	}, lineReader("bang"))
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		head string
+		want string
+	}{
+		{name: "by extension", file: "log.ANGLE", head: "!", want: "test-angle"},
+		{name: "by content", file: "log.txt", head: "!a", want: "test-bang"},
+		{name: "by content without name", head: "<a", want: "test-angle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.file, []byte(tt.head))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("fails when nothing matches", func(t *testing.T) {
+		_, err := Detect("log.txt", []byte("?"))
+		assert.ErrorIs(t, err, ErrFormatUndetected)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	assert.Contains(t, Formats(), "test-angle")
+	assert.Panics(t, func() {
+		Register("test-angle", nil, nil)
+	})
+}
+
+func TestNewFormatReader(t *testing.T) {
+	t.Run("detects the format on first read", func(t *testing.T) {
+		r, err := NewFormatReader(namedBuffer{bytes.NewBufferString("!a !b"), "app.log"}, "", FormatOptions{SizeUnit: KB})
+		assert.NoError(t, err)
+		records, err := ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []Record{
+			{Time: time.Unix(0, 0), User: "!a", Op: "bang:app.log", Bytes: KB},
+			{Time: time.Unix(0, 0), User: "!b", Op: "bang:app.log", Bytes: KB},
+		}, records)
+	})
+
+	t.Run("respects format", func(t *testing.T) {
+		r, err := NewFormatReader(strings.NewReader("!a"), "test-angle", FormatOptions{})
+		assert.NoError(t, err)
+		records, err := ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "angle:", records[0].Op)
+	})
+
+	t.Run("reads empty logs", func(t *testing.T) {
+		r, err := NewFormatReader(strings.NewReader(""), "", FormatOptions{})
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("fails on undetected format", func(t *testing.T) {
+		r, err := NewFormatReader(namedBuffer{bytes.NewBufferString("?"), "app.log"}, "", FormatOptions{})
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.ErrorIs(t, err, ErrFormatUndetected)
+		assert.ErrorContains(t, err, "app.log")
+	})
+
+	t.Run("fails on unknown format", func(t *testing.T) {
+		_, err := NewFormatReader(strings.NewReader(""), "xml", FormatOptions{})
+		assert.ErrorIs(t, err, ErrFormatUnknown)
+	})
+}
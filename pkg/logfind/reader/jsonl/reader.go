@@ -0,0 +1,243 @@
+// Package jsonl reads events written as one JSON object per line, e.g., by the jsonl writer:
+//
+//	{"timestamp":"2020-04-15T12:03:00Z","username":"jeff22","operation":"upload","size":45000}
+//
+// Timestamps are RFC 3339 strings or numbers of seconds since the Unix epoch. Sizes are numbers, in bytes unless
+// told otherwise, or strings with a unit such as "45kB".
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"math"
+	"time"
+)
+
+func init() {
+	lfReader.Register("jsonl", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.TimestampLayout != "" {
+			readerOpts = append(readerOpts, WithTimestampLayout(opts.TimestampLayout))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// detect matches files named .jsonl, .ndjson or .json, or starting with a JSON object.
+func detect(name string, head []byte) lfReader.Confidence {
+	if c := lfReader.HasExtension(name, ".jsonl", ".ndjson", ".json"); c != lfReader.NoMatch {
+		return c
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) {
+		return lfReader.ContentMatch
+	}
+	return lfReader.NoMatch
+}
+
+const (
+	ErrFieldMissing = lfReader.Error("field missing")
+)
+
+// maxLineSize is the size of the longest line the reader accepts.
+const maxLineSize = 1 << 20
+
+type reader struct {
+	scanner  *bufio.Scanner
+	location lfReader.Location
+	next     int64
+
+	sizeUnit        lfReader.Size
+	timestampLayout string
+	loc             *time.Location
+	columns         map[string]string
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithSizeUnit declares the unit of sizes given as numbers. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithTimestampLayout declares the layout of timestamps given as strings, see time.Parse. The default is time.RFC3339Nano.
+func WithTimestampLayout(layout string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the time zone of timestamps whose layout has no zone. The default is UTC.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldUsername, to the keys holding them.
+// Fields that are not mapped are read from the key named after them.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, skipping blank lines.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		scanner:         bufio.NewScanner(r),
+		sizeUnit:        lfReader.Byte,
+		timestampLayout: time.RFC3339Nano,
+		loc:             time.UTC,
+	}
+	rdr.scanner.Buffer(nil, maxLineSize)
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the event on the next non-blank line. A line that is not a JSON object is reported as a
+// *lfReader.RecordError, after which reading continues with the next line.
+func (r *reader) Read() (lfReader.Event, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		r.location.Line++
+		r.location.Offset = r.next
+		r.next += int64(len(line)) + 1
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return nil, &lfReader.RecordError{
+				Source: r.location.Source,
+				Line:   r.location.Line,
+				Offset: r.location.Offset,
+				Err:    err,
+			}
+		}
+		return event{fields: fields, location: r.location, r: r}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+type event struct {
+	fields   map[string]json.RawMessage
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+// raw returns the raw JSON value of field.
+func (e event) raw(field string) (json.RawMessage, error) {
+	key := field
+	if column, ok := e.r.columns[field]; ok {
+		key = column
+	}
+	value, ok := e.fields[key]
+	if !ok || string(value) == "null" {
+		return nil, e.error(field, nil, ErrFieldMissing)
+	}
+	return value, nil
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field string, value json.RawMessage, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  string(value),
+		Err:    err,
+	}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	value, err := e.raw(lfReader.FieldTimestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		t, err := time.ParseInLocation(e.r.timestampLayout, s, e.r.loc)
+		if err != nil {
+			return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+		}
+		return t, nil
+	}
+	var seconds float64
+	if err := json.Unmarshal(value, &seconds); err != nil {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, value, fmt.Errorf("expected a string or number"))
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+}
+
+func (e event) Username() (string, error) {
+	return e.string(lfReader.FieldUsername)
+}
+
+func (e event) Operation() (string, error) {
+	return e.string(lfReader.FieldOperation)
+}
+
+func (e event) string(field string) (string, error) {
+	value, err := e.raw(field)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", e.error(field, value, fmt.Errorf("expected a string"))
+	}
+	return s, nil
+}
+
+func (e event) Size() (lfReader.Size, error) {
+	value, err := e.raw(lfReader.FieldSize)
+	if err != nil {
+		return 0, err
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		size, err := lfReader.ParseSize(s, e.r.sizeUnit)
+		if err != nil {
+			return 0, e.error(lfReader.FieldSize, value, err)
+		}
+		return size, nil
+	}
+	size, err := lfReader.ParseSize(string(value), e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
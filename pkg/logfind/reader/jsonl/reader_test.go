@@ -0,0 +1,84 @@
+package jsonl
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","operation":"download","size":34000}
+
+{"timestamp":1586729706,"username":"Maia86","operation":"upload","size":"75kB"}
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads events", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 4, 12, 22, 15, 6, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75000},
+		}, records)
+	})
+
+	t.Run("reports location", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		_, err := r.Read()
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Location{Line: 3, Offset: 95}, r.(lfReader.Locator).Location())
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("respects options", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader(`{"time":"2020-04-12 18:10:38","user":"sarah94","operation":"download","size":34}`),
+			WithColumns(map[string]string{lfReader.FieldTimestamp: "time", lfReader.FieldUsername: "user"}),
+			WithTimestampLayout("2006-01-02 15:04:05"),
+			WithLocation(loc),
+			WithSizeUnit(lfReader.KB),
+		)
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC).Equal(rec.Time))
+		assert.Equal(t, "sarah94", rec.User)
+		assert.Equal(t, 34*lfReader.KB, rec.Bytes)
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader("not json\n{\"username\":\"jeff22\",\"size\":true}\n"))
+		_, err := r.Read()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 1, recErr.Line)
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+		_, err = e.Size()
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, lfReader.FieldSize, recErr.Field)
+		assert.Equal(t, "true", recErr.Value)
+		assert.Equal(t, 2, recErr.Line)
+	})
+}
+
+func TestDetect(t *testing.T) {
+	format, err := lfReader.Detect("events.ndjson", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "jsonl", format)
+
+	format, err = lfReader.Detect("", []byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "jsonl", format)
+}
@@ -0,0 +1,338 @@
+// Package logfmt reads events written as key=value pairs, one event per line, as many Go services log them:
+//
+//	ts=2020-04-15T12:03:00Z username=jeff22 operation=upload size=45000 msg="upload done"
+//
+// Values containing spaces, quotes or equals signs are double quoted, with backslash escapes as in Go strings.
+// A key without a value, e.g., `debug`, has an empty value. Timestamps are RFC 3339 strings or numbers of seconds
+// since the Unix epoch, and are read from the timestamp, time or ts key unless told otherwise. Sizes are in bytes
+// unless told otherwise or given with a unit such as 45kB. The keys that are not read as fields of the event are
+// exposed as its labels, see lfReader.Labeler.
+package logfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	lfReader.Register("logfmt", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.TimestampLayout != "" {
+			readerOpts = append(readerOpts, WithTimestampLayout(opts.TimestampLayout))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// detect matches files named .logfmt, or whose first line is made of at least two key=value pairs.
+func detect(name string, head []byte) lfReader.Confidence {
+	if c := lfReader.HasExtension(name, ".logfmt"); c != lfReader.NoMatch {
+		return c
+	}
+	line, _, _ := strings.Cut(strings.TrimLeft(string(head), " \t\r\n"), "\n")
+	pairs, err := parse(strings.TrimSuffix(line, "\r"))
+	if err != nil || len(pairs) < 2 {
+		return lfReader.NoMatch
+	}
+	for _, p := range pairs {
+		if !p.hasValue {
+			return lfReader.NoMatch
+		}
+	}
+	return lfReader.ContentMatch
+}
+
+const (
+	ErrFieldMissing = lfReader.Error("field missing")
+)
+
+// timestampKeys are the keys the timestamp is read from when it is not mapped to another, in order of preference.
+var timestampKeys = []string{lfReader.FieldTimestamp, "time", "ts"}
+
+// maxLineSize is the size of the longest line the reader accepts.
+const maxLineSize = 1 << 20
+
+type reader struct {
+	scanner  *bufio.Scanner
+	location lfReader.Location
+	next     int64
+
+	sizeUnit        lfReader.Size
+	timestampLayout string
+	loc             *time.Location
+	columns         map[string]string
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithSizeUnit declares the unit of sizes without one. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithTimestampLayout declares the layout of timestamps that are not numbers, see time.Parse. The default is
+// time.RFC3339Nano.
+func WithTimestampLayout(layout string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the time zone of timestamps whose layout has no zone. The default is UTC.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldUsername, to the keys holding them.
+// Fields that are not mapped are read from the key named after them.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, skipping blank lines.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		scanner:         bufio.NewScanner(r),
+		sizeUnit:        lfReader.Byte,
+		timestampLayout: time.RFC3339Nano,
+		loc:             time.UTC,
+	}
+	rdr.scanner.Buffer(nil, maxLineSize)
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the event on the next non-blank line. A line that is not made of key=value pairs, e.g., one with an
+// unterminated quote, is reported as a *lfReader.RecordError, after which reading continues with the next line.
+func (r *reader) Read() (lfReader.Event, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		r.location.Line++
+		r.location.Offset = r.next
+		r.next += int64(len(line)) + 1
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		pairs, err := parse(line)
+		if err != nil {
+			return nil, &lfReader.RecordError{
+				Source: r.location.Source,
+				Line:   r.location.Line,
+				Offset: r.location.Offset,
+				Err:    err,
+			}
+		}
+		fields := make(map[string]string, len(pairs))
+		for _, p := range pairs {
+			fields[p.key] = p.value
+		}
+		return event{fields: fields, location: r.location, r: r}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+// pair is a key and its value.
+type pair struct {
+	key, value string
+	// hasValue is false for a key without an equals sign.
+	hasValue bool
+}
+
+// parse splits a logfmt line into its pairs.
+func parse(line string) ([]pair, error) {
+	var pairs []pair
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected %q at column %d, expected a key", line[i], i+1)
+		}
+		p := pair{key: line[start:i]}
+		if i == len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, fmt.Errorf("unexpected '\"' at column %d", i+1)
+			}
+			pairs = append(pairs, p)
+			continue
+		}
+		i++
+		p.hasValue = true
+
+		if i < len(line) && line[i] == '"' {
+			value, n, err := unquote(line[i:])
+			if err != nil {
+				return nil, fmt.Errorf("value of %s at column %d: %w", p.key, i+1, err)
+			}
+			p.value = value
+			i += n
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			p.value = line[start:i]
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// unquote unquotes the double quoted string at the start of s and returns it along with the number of bytes it spans.
+func unquote(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, errors.New("invalid escape")
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, errors.New("unterminated quote")
+}
+
+type event struct {
+	fields   map[string]string
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+var _ lfReader.Labeler = event{}
+
+// key returns the key holding field and whether the event has it.
+func (e event) key(field string) (string, bool) {
+	if column, ok := e.r.columns[field]; ok {
+		_, found := e.fields[column]
+		return column, found
+	}
+	if field == lfReader.FieldTimestamp {
+		for _, key := range timestampKeys {
+			if _, ok := e.fields[key]; ok {
+				return key, true
+			}
+		}
+	}
+	_, found := e.fields[field]
+	return field, found
+}
+
+// value returns the value of field.
+func (e event) value(field string) (string, error) {
+	key, ok := e.key(field)
+	if !ok {
+		return "", e.error(field, "", ErrFieldMissing)
+	}
+	return e.fields[key], nil
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field, value string, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	value, err := e.value(lfReader.FieldTimestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation(e.r.timestampLayout, value, e.r.loc)
+	if err == nil {
+		return t, nil
+	}
+	seconds, numErr := strconv.ParseFloat(value, 64)
+	if numErr != nil {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+}
+
+func (e event) Username() (string, error) {
+	return e.value(lfReader.FieldUsername)
+}
+
+func (e event) Operation() (string, error) {
+	return e.value(lfReader.FieldOperation)
+}
+
+func (e event) Size() (lfReader.Size, error) {
+	value, err := e.value(lfReader.FieldSize)
+	if err != nil {
+		return 0, err
+	}
+	size, err := lfReader.ParseSize(value, e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
+
+// Labels returns the keys of the line that are not read as fields of the event.
+func (e event) Labels() map[string]string {
+	labels := make(map[string]string, len(e.fields))
+	for key, value := range e.fields {
+		labels[key] = value
+	}
+	for _, field := range []string{lfReader.FieldTimestamp, lfReader.FieldUsername, lfReader.FieldOperation, lfReader.FieldSize} {
+		if key, ok := e.key(field); ok {
+			delete(labels, key)
+		}
+	}
+	return labels
+}
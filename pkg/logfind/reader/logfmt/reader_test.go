@@ -0,0 +1,142 @@
+package logfmt
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `ts=2020-04-12T22:10:38Z username=sarah94 operation=download size=34000 level=info msg="download done"
+
+time=1586729706 username=Maia86 operation=upload size=75kB debug
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads events", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 4, 12, 22, 15, 6, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75000},
+		}, records)
+	})
+
+	t.Run("exposes other keys as labels", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"level": "info", "msg": "download done"}, e.(lfReader.Labeler).Labels())
+
+		e, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"debug": ""}, e.(lfReader.Labeler).Labels())
+	})
+
+	t.Run("reports location", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		_, err := r.Read()
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, lfReader.Location{Line: 3, Offset: 103}, r.(lfReader.Locator).Location())
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("respects options", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader(`at="2020-04-12 18:10:38" user=sarah94 operation=download size=34 ts=ignored`),
+			WithColumns(map[string]string{lfReader.FieldTimestamp: "at", lfReader.FieldUsername: "user"}),
+			WithTimestampLayout("2006-01-02 15:04:05"),
+			WithLocation(loc),
+			WithSizeUnit(lfReader.KB),
+		)
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC).Equal(rec.Time))
+		assert.Equal(t, "sarah94", rec.User)
+		assert.Equal(t, 34*lfReader.KB, rec.Bytes)
+		assert.Equal(t, map[string]string{"ts": "ignored"}, e.(lfReader.Labeler).Labels())
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader("username=\"jeff22\nusername=jeff22 size=lots\n"))
+		_, err := r.Read()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 1, recErr.Line)
+		assert.ErrorContains(t, err, "unterminated quote")
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+		_, err = e.Size()
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, lfReader.FieldSize, recErr.Field)
+		assert.Equal(t, "lots", recErr.Value)
+		assert.Equal(t, 2, recErr.Line)
+	})
+}
+
+func Test_parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []pair
+		wantErr string
+	}{
+		{
+			name: "plain values",
+			line: "a=1 b=two\tc=",
+			want: []pair{{"a", "1", true}, {"b", "two", true}, {"c", "", true}},
+		},
+		{
+			name: "quoted values with escapes",
+			line: `msg="say \"hi\"\n" path="C:\\logs" eq="a=b"`,
+			want: []pair{{"msg", "say \"hi\"\n", true}, {"path", `C:\logs`, true}, {"eq", "a=b", true}},
+		},
+		{
+			name: "keys without values",
+			line: "debug a=1 verbose",
+			want: []pair{{"debug", "", false}, {"a", "1", true}, {"verbose", "", false}},
+		},
+		{name: "missing key", line: "a=1 =2", wantErr: "expected a key"},
+		{name: "unterminated quote", line: `a="1`, wantErr: "unterminated quote"},
+		{name: "invalid escape", line: `a="\q"`, wantErr: "invalid escape"},
+		{name: "quote in key", line: `a"b=1`, wantErr: "unexpected '\"'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.line)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	format, err := lfReader.Detect("events.logfmt", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "logfmt", format)
+
+	format, err = lfReader.Detect("", []byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "logfmt", format)
+
+	format, err = lfReader.Detect("", []byte(`ts=2020-04-12T22:10:38Z msg="a, b" username=sarah94`))
+	assert.NoError(t, err)
+	assert.Equal(t, "logfmt", format)
+}
@@ -0,0 +1,327 @@
+// Package loki reads events pushed in the JSON format of Loki's push API, e.g., by Promtail or Grafana Agent.
+//
+// A push request holds streams of log lines sharing a set of labels:
+//
+//	{"streams": [{"stream": {"operation": "upload"}, "values": [["1586919006000000000", "username=jeff22 size=45"]]}]}
+//
+// The fields of an event are taken from its stream's labels and from the key=value pairs, or JSON object, of its line,
+// the labels taking precedence. Its timestamp is the entry's timestamp in nanoseconds since the Unix epoch.
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PushPath is the path on which Loki receives push requests.
+const PushPath = "/loki/api/v1/push"
+
+const (
+	ErrFieldMissing = lfReader.Error("field missing")
+	ErrClosed       = lfReader.Error("receiver closed")
+)
+
+// receiverBuffer is the number of pushes a Receiver holds before further pushes wait for them to be read.
+const receiverBuffer = 64
+
+// PushRequest is the body of a push request.
+type PushRequest struct {
+	Streams []Stream `json:"streams"`
+}
+
+// Stream is a set of labels along with the entries logged under them.
+type Stream struct {
+	Labels map[string]string `json:"stream"`
+	// Values are pairs of a timestamp, in nanoseconds since the Unix epoch, and a log line.
+	Values [][2]string `json:"values"`
+}
+
+type options struct {
+	sizeUnit lfReader.Size
+}
+
+// ReaderOptionFunc customizes a reader created by NewReader or NewReceiver.
+type ReaderOptionFunc func(*options)
+
+// WithSizeUnit declares the unit of size values without a unit. The default is lfReader.KB.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(o *options) {
+		o.sizeUnit = unit
+	}
+}
+
+func newOptions(opts []ReaderOptionFunc) options {
+	o := options{sizeUnit: lfReader.KB}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type reader struct {
+	r      io.Reader
+	opts   options
+	events []lfReader.Event
+	read   bool
+}
+
+// NewReader returns a lfReader.Reader over the events of a single push request read from r,
+// e.g., one saved to a file.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	return &reader{r: r, opts: newOptions(opts)}
+}
+
+func (r *reader) Read() (lfReader.Event, error) {
+	if !r.read {
+		r.read = true
+		var err error
+		if r.events, err = decode(r.r, r.opts); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.events) == 0 {
+		return nil, io.EOF
+	}
+	e := r.events[0]
+	r.events = r.events[1:]
+	return e, nil
+}
+
+// Receiver is an http.Handler accepting push requests and a lfReader.Reader over the events pushed to it, in the
+// order they arrive. Read waits for events until Close is called. Pushes wait while the events already received
+// have not been read, so a slow consumer slows down its clients rather than growing without bound.
+type Receiver struct {
+	opts options
+	// pushes holds the events of each accepted push, so that a push is accepted whole or not at all.
+	pushes chan []lfReader.Event
+	// pending are the events of the push being read.
+	pending   []lfReader.Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReceiver returns a Receiver, to be mounted on PushPath.
+func NewReceiver(opts ...ReaderOptionFunc) *Receiver {
+	return &Receiver{
+		opts:   newOptions(opts),
+		pushes: make(chan []lfReader.Event, receiverBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+// ServeHTTP accepts a push request in JSON, optionally gzip encoded. The protobuf encoding is not supported. Once the
+// Receiver is closed, pushes are rejected with 503 Service Unavailable and none of their events are read.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "only JSON push requests are supported", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	events, err := decode(body, rc.opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// done is checked on its own first, since a select picks at random among the cases that are ready.
+	select {
+	case <-rc.done:
+		http.Error(w, ErrClosed.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	if len(events) > 0 {
+		select {
+		case rc.pushes <- events:
+		case <-rc.done:
+			http.Error(w, ErrClosed.Error(), http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Read returns the next pushed event, waiting for one if needed. Once the Receiver is closed and every
+// event it accepted has been read, Read returns io.EOF.
+func (rc *Receiver) Read() (lfReader.Event, error) {
+	for len(rc.pending) == 0 {
+		select {
+		case rc.pending = <-rc.pushes:
+		case <-rc.done:
+			select {
+			case rc.pending = <-rc.pushes:
+			default:
+				return nil, io.EOF
+			}
+		}
+	}
+	e := rc.pending[0]
+	rc.pending = rc.pending[1:]
+	return e, nil
+}
+
+// Close stops the Receiver from accepting pushes.
+func (rc *Receiver) Close() error {
+	rc.closeOnce.Do(func() {
+		close(rc.done)
+	})
+	return nil
+}
+
+// decode reads a push request from r and returns its events.
+func decode(r io.Reader, opts options) ([]lfReader.Event, error) {
+	var req PushRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+
+	var events []lfReader.Event
+	for _, stream := range req.Streams {
+		for _, value := range stream.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q: %w", value[0], err)
+			}
+			events = append(events, event{
+				timestamp: time.Unix(0, ns).UTC(),
+				labels:    stream.Labels,
+				fields:    parseLine(value[1]),
+				sizeUnit:  opts.sizeUnit,
+			})
+		}
+	}
+	return events, nil
+}
+
+// parseLine extracts the fields of a log line written as a JSON object or as key=value pairs, e.g.,
+// `username=jeff22 operation="upload" size=45`.
+func parseLine(line string) map[string]string {
+	fields := make(map[string]string)
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		var obj map[string]interface{}
+		if json.Unmarshal([]byte(line), &obj) == nil {
+			for k, v := range obj {
+				if s, ok := v.(string); ok {
+					fields[k] = s
+				} else {
+					fields[k] = fmt.Sprint(v)
+				}
+			}
+			return fields
+		}
+	}
+
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " \t")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			break
+		}
+		key := line[:eq]
+		if strings.ContainsAny(key, " \t") {
+			// Not a pair, skip the word.
+			line = line[strings.IndexAny(key, " \t"):]
+			continue
+		}
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			if unquoted, rest, ok := unquote(line); ok {
+				value, line = unquoted, rest
+			} else {
+				value, line = line[1:], ""
+			}
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			value, line = line[:end], line[end:]
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// unquote unquotes the double quoted string at the start of s and returns the rest of s.
+func unquote(s string) (value, rest string, ok bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err == nil
+		}
+	}
+	return "", "", false
+}
+
+// event is an entry of a pushed stream.
+type event struct {
+	timestamp time.Time
+	labels    map[string]string
+	fields    map[string]string
+	sizeUnit  lfReader.Size
+}
+
+// field returns the value of name from the labels or, failing that, the fields of the line.
+func (e event) field(name string) (string, error) {
+	if value, ok := e.labels[name]; ok {
+		return value, nil
+	}
+	if value, ok := e.fields[name]; ok {
+		return value, nil
+	}
+	return "", &lfReader.RecordError{Field: name, Err: ErrFieldMissing}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	return e.timestamp, nil
+}
+
+func (e event) Username() (string, error) {
+	return e.field(lfReader.FieldUsername)
+}
+
+func (e event) Operation() (string, error) {
+	return e.field(lfReader.FieldOperation)
+}
+
+func (e event) Size() (lfReader.Size, error) {
+	value, err := e.field(lfReader.FieldSize)
+	if err != nil {
+		return 0, err
+	}
+	size, err := lfReader.ParseSize(value, e.sizeUnit)
+	if err != nil {
+		return 0, &lfReader.RecordError{Field: lfReader.FieldSize, Value: value, Err: err}
+	}
+	return size, nil
+}
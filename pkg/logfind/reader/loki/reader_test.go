@@ -0,0 +1,165 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const push = `{"streams": [
+	{"stream": {"operation": "upload", "job": "server"}, "values": [
+		["1586920006000000000", "username=jeff22 size=45"],
+		["1586992706000000000", "msg=\"big one\" username=\"jeff22\" size=75kB"]
+	]},
+	{"stream": {"job": "server"}, "values": [
+		["1586729438000000000", "{\"username\": \"sarah94\", \"operation\": \"download\", \"size\": 34}"]
+	]}
+]}`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads events", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(push)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 04, 15, 03, 06, 46, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 45 * lfReader.KB},
+			{Time: time.Date(2020, 04, 15, 23, 18, 26, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 75 * lfReader.KB},
+			{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34 * lfReader.KB},
+		}, records)
+	})
+
+	t.Run("reports missing fields", func(t *testing.T) {
+		e, err := NewReader(strings.NewReader(`{"streams": [{"stream": {}, "values": [["0", "username=jeff22"]]}]}`)).Read()
+		assert.NoError(t, err)
+		_, err = e.Operation()
+		var recErr *lfReader.RecordError
+		assert.ErrorAs(t, err, &recErr)
+		assert.Equal(t, lfReader.FieldOperation, recErr.Field)
+		assert.ErrorIs(t, err, ErrFieldMissing)
+	})
+
+	t.Run("reports malformed sizes", func(t *testing.T) {
+		e, err := NewReader(strings.NewReader(`{"streams": [{"stream": {}, "values": [["0", "size=big"]]}]}`)).Read()
+		assert.NoError(t, err)
+		_, err = e.Size()
+		var recErr *lfReader.RecordError
+		assert.ErrorAs(t, err, &recErr)
+		assert.Equal(t, "big", recErr.Value)
+	})
+
+	t.Run("fails on malformed timestamp", func(t *testing.T) {
+		_, err := NewReader(strings.NewReader(`{"streams": [{"stream": {}, "values": [["yesterday", ""]]}]}`)).Read()
+		assert.Error(t, err)
+	})
+}
+
+func TestReceiver(t *testing.T) {
+	t.Run("receives pushes", func(t *testing.T) {
+		rc := NewReceiver()
+		srv := httptest.NewServer(rc)
+		defer srv.Close()
+
+		resp, err := http.Post(srv.URL+PushPath, "application/json", strings.NewReader(push))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		var gzipped bytes.Buffer
+		gz := gzip.NewWriter(&gzipped)
+		gz.Write([]byte(`{"streams": [{"stream": {"username": "Maia86", "operation": "download"}, "values": [["1586730906000000000", "size=75"]]}]}`))
+		gz.Close()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+PushPath, &gzipped)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		rc.Close()
+		records, err := lfReader.ReadAll(rc)
+		assert.NoError(t, err)
+		assert.Len(t, records, 4)
+		assert.Equal(t, "Maia86", records[3].User)
+
+		resp, err = http.Post(srv.URL+PushPath, "application/json", strings.NewReader(push))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		_, err = rc.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("rejects waiting pushes whole when closed", func(t *testing.T) {
+		rc := NewReceiver()
+		serve := func() int {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, PushPath, strings.NewReader(push))
+			req.Header.Set("Content-Type", "application/json")
+			rc.ServeHTTP(rec, req)
+			return rec.Code
+		}
+		for i := 0; i < receiverBuffer; i++ {
+			assert.Equal(t, http.StatusNoContent, serve())
+		}
+
+		status := make(chan int)
+		go func() {
+			status <- serve()
+		}()
+		time.Sleep(10 * time.Millisecond)
+		rc.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, <-status)
+
+		records, err := lfReader.ReadAll(rc)
+		assert.NoError(t, err)
+		assert.Len(t, records, receiverBuffer*3)
+	})
+
+	t.Run("waits for events", func(t *testing.T) {
+		rc := NewReceiver()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, PushPath, strings.NewReader(push))
+			req.Header.Set("Content-Type", "application/json")
+			rc.ServeHTTP(rec, req)
+		}()
+		e, err := rc.Read()
+		assert.NoError(t, err)
+		username, _ := e.Username()
+		assert.Equal(t, "jeff22", username)
+	})
+
+	for name, tt := range map[string]struct {
+		method      string
+		contentType string
+		body        string
+		status      int
+	}{
+		"get":      {method: http.MethodGet, contentType: "application/json", status: http.StatusMethodNotAllowed},
+		"protobuf": {method: http.MethodPost, contentType: "application/x-protobuf", body: push, status: http.StatusUnsupportedMediaType},
+		"garbage":  {method: http.MethodPost, contentType: "application/json", body: `{"streams": `, status: http.StatusBadRequest},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, PushPath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			NewReceiver().ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+
+	t.Run("ends after close", func(t *testing.T) {
+		rc := NewReceiver()
+		rc.Close()
+		_, err := rc.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
@@ -0,0 +1,39 @@
+package reader
+
+import "io"
+
+type multiReader struct {
+	readers []Reader
+}
+
+var _ Locator = (*multiReader)(nil)
+
+// MultiReader returns a Reader that is the logical concatenation of the given readers.
+// They are read sequentially and once all have returned io.EOF, Read returns io.EOF.
+// Errors other than io.EOF are returned as is and reading continues with the same reader.
+func MultiReader(readers ...Reader) Reader {
+	r := make([]Reader, len(readers))
+	copy(r, readers)
+	return &multiReader{readers: r}
+}
+
+func (m *multiReader) Read() (event Event, err error) {
+	for len(m.readers) > 0 {
+		event, err = m.readers[0].Read()
+		if err != io.EOF {
+			return
+		}
+		m.readers = m.readers[1:]
+	}
+	return nil, io.EOF
+}
+
+// Location reports the location of the most recently read event when the current reader is a Locator.
+func (m *multiReader) Location() Location {
+	if len(m.readers) > 0 {
+		if l, ok := m.readers[0].(Locator); ok {
+			return l.Location()
+		}
+	}
+	return Location{}
+}
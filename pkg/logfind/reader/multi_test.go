@@ -0,0 +1,62 @@
+package reader
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type stubEvent string
+
+func (s stubEvent) Timestamp() (time.Time, error) { return time.Time{}, nil }
+func (s stubEvent) Username() (string, error)     { return string(s), nil }
+func (s stubEvent) Operation() (string, error)    { return "upload", nil }
+func (s stubEvent) Size() (Size, error)           { return KB, nil }
+
+type stubReader struct {
+	source string
+	events []stubEvent
+	i      int
+}
+
+func (s *stubReader) Read() (Event, error) {
+	if s.i >= len(s.events) {
+		return nil, io.EOF
+	}
+	s.i++
+	return s.events[s.i-1], nil
+}
+
+func (s *stubReader) Location() Location {
+	return Location{Source: s.source, Line: s.i}
+}
+
+func TestMultiReader(t *testing.T) {
+	t.Run("concatenates readers", func(t *testing.T) {
+		r := MultiReader(
+			&stubReader{source: "a.csv", events: []stubEvent{"a1", "a2"}},
+			&stubReader{source: "b.csv"},
+			&stubReader{source: "c.csv", events: []stubEvent{"c1"}},
+		)
+
+		var got []Event
+		var locations []Location
+		for {
+			e, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			got = append(got, e)
+			locations = append(locations, r.(Locator).Location())
+		}
+		assert.Equal(t, []Event{stubEvent("a1"), stubEvent("a2"), stubEvent("c1")}, got)
+		assert.Equal(t, []Location{{Source: "a.csv", Line: 1}, {Source: "a.csv", Line: 2}, {Source: "c.csv", Line: 1}}, locations)
+	})
+
+	t.Run("is empty without readers", func(t *testing.T) {
+		_, err := MultiReader().Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
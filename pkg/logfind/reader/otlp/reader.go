@@ -0,0 +1,388 @@
+// Package otlp reads logs exported by OpenTelemetry as OTLP JSON, e.g., by the file exporter of the Collector: one
+// or more export requests, each holding resourceLogs → scopeLogs → logRecords:
+//
+//	{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"files"}}]},
+//	  "scopeLogs":[{"scope":{"name":"transfers"},"logRecords":[{"timeUnixNano":"1586729438000000000",
+//	  "body":{"stringValue":"download done"},"attributes":[{"key":"username","value":{"stringValue":"sarah94"}},
+//	  {"key":"operation","value":{"stringValue":"download"}},{"key":"size","value":{"intValue":"34000"}}]}]}]}]}
+//
+// The timestamp of an event is the timeUnixNano of its log record, or its observedTimeUnixNano when unset. Its other
+// fields are read from attributes named after them, or as told by WithColumns, looked up on the log record, then its
+// scope and then its resource. The username is also read from the user.name and enduser.id attributes. The remaining
+// attributes, along with the body and severity of the record, are exposed as labels, see lfReader.Labeler.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	lfReader.Register("otlp", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// detect matches logs whose first JSON object starts with a resourceLogs key, whatever their file name.
+func detect(_ string, head []byte) lfReader.Confidence {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if !bytes.HasPrefix(head, []byte("{")) {
+		return lfReader.NoMatch
+	}
+	if bytes.HasPrefix(bytes.TrimLeft(head[1:], " \t\r\n"), []byte(`"resourceLogs"`)) {
+		return lfReader.SignatureMatch
+	}
+	return lfReader.NoMatch
+}
+
+const (
+	ErrFieldMissing = lfReader.Error("attribute missing")
+)
+
+// The prefixes of attribute keys that restrict their lookup to one level, e.g., resource:service.name.
+const (
+	levelRecord   = "log:"
+	levelScope    = "scope:"
+	levelResource = "resource:"
+)
+
+// defaultKeys are the attribute keys fields are read from when they are not mapped to others, in order of preference.
+var defaultKeys = map[string][]string{
+	lfReader.FieldUsername:  {lfReader.FieldUsername, "user.name", "enduser.id"},
+	lfReader.FieldOperation: {lfReader.FieldOperation},
+	lfReader.FieldSize:      {lfReader.FieldSize},
+}
+
+// exportRequest is the JSON encoding of an OTLP ExportLogsServiceRequest.
+type exportRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Attributes []keyValue `json:"attributes"`
+			} `json:"scope"`
+			LogRecords []logRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type logRecord struct {
+	TimeUnixNano         uint64String `json:"timeUnixNano"`
+	ObservedTimeUnixNano uint64String `json:"observedTimeUnixNano"`
+	SeverityText         string       `json:"severityText"`
+	Body                 anyValue     `json:"body"`
+	Attributes           []keyValue   `json:"attributes"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is the JSON encoding of an OTLP AnyValue, of which exactly one field is set.
+type anyValue struct {
+	StringValue *string         `json:"stringValue"`
+	IntValue    json.RawMessage `json:"intValue"`
+	DoubleValue json.RawMessage `json:"doubleValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	BytesValue  *string         `json:"bytesValue"`
+	ArrayValue  json.RawMessage `json:"arrayValue"`
+	KvlistValue json.RawMessage `json:"kvlistValue"`
+}
+
+// String returns the value as text. Integers, which are encoded as strings or numbers, and doubles are returned as
+// written, and arrays and key-value lists as JSON.
+func (v anyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return strings.Trim(string(v.IntValue), `"`)
+	case v.DoubleValue != nil:
+		return strings.Trim(string(v.DoubleValue), `"`)
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		return string(v.ArrayValue)
+	case v.KvlistValue != nil:
+		return string(v.KvlistValue)
+	}
+	return ""
+}
+
+// uint64String is a uint64 encoded as a JSON string, as proto3 encodes 64 bit integers, or a number.
+type uint64String uint64
+
+func (u *uint64String) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid uint64 %s", data)
+	}
+	*u = uint64String(n)
+	return nil
+}
+
+type reader struct {
+	dec      *json.Decoder
+	location lfReader.Location
+	// pending holds the events of the last decoded request that have not been read yet.
+	pending []lfReader.Event
+	// done is set once the input is exhausted or can no longer be decoded.
+	done bool
+
+	sizeUnit lfReader.Size
+	columns  map[string]string
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithSizeUnit declares the unit of sizes without one. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldUsername, to the attribute keys holding them. A key
+// prefixed with log:, scope: or resource:, e.g., resource:service.name, is only looked up at that level.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, which holds export requests one after another, e.g.,
+// one per line. When r has a Name method, e.g., an *os.File, its name is used as the source of the events'
+// lfReader.Location, whose offset is that of the request holding the event.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		dec:      json.NewDecoder(r),
+		sizeUnit: lfReader.Byte,
+	}
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the next log record. A request that is not valid OTLP JSON is reported as a *lfReader.RecordError, after
+// which reading continues with the next request unless the JSON itself is malformed.
+func (r *reader) Read() (lfReader.Event, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return nil, io.EOF
+		}
+		if err := r.decode(); err != nil {
+			return nil, err
+		}
+	}
+	e := r.pending[0]
+	r.pending = r.pending[1:]
+	return e, nil
+}
+
+// decode decodes the next request into pending.
+func (r *reader) decode() error {
+	// The offset of the request is that of its first byte rather than of whitespace before it.
+	r.location.Offset = r.dec.InputOffset()
+	if r.dec.More() {
+		if err := r.skipSpace(); err != nil {
+			r.done = true
+			return err
+		}
+	}
+
+	var req exportRequest
+	err := r.dec.Decode(&req)
+	if err == io.EOF {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			r.done = true
+		}
+		return &lfReader.RecordError{Source: r.location.Source, Offset: r.location.Offset, Err: err}
+	}
+
+	for _, rl := range req.ResourceLogs {
+		resource := attributes(rl.Resource.Attributes)
+		for _, sl := range rl.ScopeLogs {
+			scope := attributes(sl.Scope.Attributes)
+			for i := range sl.LogRecords {
+				rec := &sl.LogRecords[i]
+				r.pending = append(r.pending, event{
+					record:   rec,
+					levels:   [3]map[string]string{attributes(rec.Attributes), scope, resource},
+					location: r.location,
+					r:        r,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// skipSpace advances the offset past the whitespace before the next request.
+func (r *reader) skipSpace() error {
+	buffered, err := io.ReadAll(io.LimitReader(r.dec.Buffered(), 1<<16))
+	if err != nil {
+		return err
+	}
+	r.location.Offset += int64(len(buffered) - len(bytes.TrimLeft(buffered, " \t\r\n")))
+	return nil
+}
+
+// attributes returns the values of attrs by key.
+func attributes(attrs []keyValue) map[string]string {
+	values := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		values[kv.Key] = kv.Value.String()
+	}
+	return values
+}
+
+// Location returns the location of the request holding the most recently decoded events.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+type event struct {
+	record *logRecord
+	// levels are the attributes of the record, its scope and its resource, in the order they are looked up.
+	levels   [3]map[string]string
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+var _ lfReader.Labeler = event{}
+
+// lookup returns the level and name of the attribute holding field, and whether there is one.
+func (e event) lookup(field string) (level int, name string, ok bool) {
+	keys := defaultKeys[field]
+	if column, mapped := e.r.columns[field]; mapped {
+		keys = []string{column}
+	}
+	for _, key := range keys {
+		for i, prefix := range []string{levelRecord, levelScope, levelResource} {
+			if strings.HasPrefix(key, prefix) {
+				name := strings.TrimPrefix(key, prefix)
+				_, ok := e.levels[i][name]
+				return i, name, ok
+			}
+		}
+		for i, attrs := range e.levels {
+			if _, ok := attrs[key]; ok {
+				return i, key, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// value returns the value of field.
+func (e event) value(field string) (string, error) {
+	level, name, ok := e.lookup(field)
+	if !ok {
+		return "", e.error(field, "", ErrFieldMissing)
+	}
+	return e.levels[level][name], nil
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field, value string, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	ns := e.record.TimeUnixNano
+	if ns == 0 {
+		ns = e.record.ObservedTimeUnixNano
+	}
+	if ns == 0 {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, "", errors.New("neither timeUnixNano nor observedTimeUnixNano is set"))
+	}
+	return time.Unix(0, int64(ns)).UTC(), nil
+}
+
+func (e event) Username() (string, error) {
+	return e.value(lfReader.FieldUsername)
+}
+
+func (e event) Operation() (string, error) {
+	return e.value(lfReader.FieldOperation)
+}
+
+func (e event) Size() (lfReader.Size, error) {
+	value, err := e.value(lfReader.FieldSize)
+	if err != nil {
+		return 0, err
+	}
+	size, err := lfReader.ParseSize(value, e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
+
+// Labels returns the attributes that are not read as fields of the event, those of the record taking precedence over
+// those of its scope and then resource, along with the body and severity of the record when set.
+func (e event) Labels() map[string]string {
+	labels := make(map[string]string)
+	for i := len(e.levels) - 1; i >= 0; i-- {
+		for key, value := range e.levels[i] {
+			labels[key] = value
+		}
+	}
+	for _, field := range []string{lfReader.FieldUsername, lfReader.FieldOperation, lfReader.FieldSize} {
+		if level, name, ok := e.lookup(field); ok && e.shadowed(level, name) {
+			delete(labels, name)
+		}
+	}
+	if body := e.record.Body.String(); body != "" {
+		labels["body"] = body
+	}
+	if e.record.SeverityText != "" {
+		labels["severity"] = e.record.SeverityText
+	}
+	return labels
+}
+
+// shadowed reports whether the attribute name at level is the one exposed as label, rather than one of a lower level.
+func (e event) shadowed(level int, name string) bool {
+	for i := 0; i < level; i++ {
+		if _, ok := e.levels[i][name]; ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package otlp
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/jsonl"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"files"}},{"key":"user.name","value":{"stringValue":"svc"}}]},"scopeLogs":[{"scope":{"name":"transfers","attributes":[{"key":"tier","value":{"stringValue":"hot"}}]},"logRecords":[{"timeUnixNano":"1586729438000000000","severityText":"INFO","body":{"stringValue":"download done"},"attributes":[{"key":"user.name","value":{"stringValue":"sarah94"}},{"key":"operation","value":{"stringValue":"download"}},{"key":"size","value":{"intValue":"34000"}}]},{"observedTimeUnixNano":1586729706000000000,"attributes":[{"key":"operation","value":{"stringValue":"upload"}},{"key":"size","value":{"intValue":75000}},{"key":"retried","value":{"boolValue":true}}]}]}]}]}
+{
+  "resourceLogs": [{
+    "resource": {"attributes": [{"key": "user.name", "value": {"stringValue": "jeff22"}}]},
+    "scopeLogs": [{"logRecords": [{
+      "timeUnixNano": "1586735970000000000",
+      "attributes": [
+        {"key": "operation", "value": {"stringValue": "download"}},
+        {"key": "size", "value": {"doubleValue": 1.5}},
+        {"key": "tags", "value": {"arrayValue": {"values": [{"stringValue": "a"}]}}}
+      ]
+    }]}]
+  }]
+}
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads log records of every request", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(input), WithSizeUnit(lfReader.KB)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000 * lfReader.KB},
+			{Time: time.Date(2020, 4, 12, 22, 15, 6, 0, time.UTC), User: "svc", Op: "upload", Bytes: 75000 * lfReader.KB},
+			{Time: time.Date(2020, 4, 12, 23, 59, 30, 0, time.UTC), User: "jeff22", Op: "download", Bytes: 1500},
+		}, records)
+	})
+
+	t.Run("exposes other attributes as labels", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"service.name": "files",
+			"tier":         "hot",
+			"body":         "download done",
+			"severity":     "INFO",
+		}, e.(lfReader.Labeler).Labels())
+
+		e, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"service.name": "files",
+			"tier":         "hot",
+			"retried":      "true",
+		}, e.(lfReader.Labeler).Labels())
+		assert.Equal(t, int64(0), r.(lfReader.Locator).Location().Offset)
+
+		e, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"tags": `{"values": [{"stringValue": "a"}]}`}, e.(lfReader.Labeler).Labels())
+		assert.Equal(t, int64(strings.Index(input, "\n{")+1), r.(lfReader.Locator).Location().Offset)
+	})
+
+	t.Run("respects columns", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input), WithColumns(map[string]string{
+			lfReader.FieldUsername:  "resource:user.name",
+			lfReader.FieldOperation: "scope:tier",
+		}))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.Equal(t, "svc", rec.User)
+		assert.Equal(t, "hot", rec.Op)
+		labels := e.(lfReader.Labeler).Labels()
+		assert.Equal(t, "sarah94", labels["user.name"])
+		assert.Equal(t, "download", labels["operation"])
+		assert.NotContains(t, labels, "tier")
+
+		e, err = NewReader(strings.NewReader(input), WithColumns(map[string]string{
+			lfReader.FieldUsername: "log:service.name",
+		})).Read()
+		assert.NoError(t, err)
+		_, err = e.Username()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+	})
+
+	t.Run("reports missing attributes as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{}]}]}]}`))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.ErrorContains(t, err, "neither timeUnixNano nor observedTimeUnixNano is set")
+		_, err = e.Username()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, lfReader.FieldUsername, recErr.Field)
+	})
+
+	t.Run("reports malformed requests as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader(`{"resourceLogs":{}}
+{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"1"}]}]}]}
+{"resourceLogs":[`))
+		var recErr *lfReader.RecordError
+		_, err := r.Read()
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, int64(0), recErr.Offset)
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		ts, err := e.Timestamp()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Unix(0, 1).UTC(), ts)
+
+		_, err = r.Read()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+func TestDetect(t *testing.T) {
+	format, err := lfReader.Detect("logs.json", []byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "otlp", format)
+
+	format, err = lfReader.Detect("logs.json", []byte(`{"timestamp":"2020-04-12T22:10:38Z"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "jsonl", format)
+}
@@ -14,9 +14,40 @@ type Reader interface {
 // Note: This is intentionally not a concrete type in order to prevent the need for
 // type conversions within Reader implementations as the result of, for instance,
 // using struct tags to unmarshal log events.
+//
+// Size is always reported in bytes regardless of the unit the log stream records it in.
 type Event interface {
 	Timestamp() (time.Time, error)
 	Username() (string, error)
 	Operation() (string, error)
-	Size() (int, error)
+	Size() (Size, error)
+}
+
+// The names of the fields exposed by Event. These are used to describe which part of an event failed to parse.
+const (
+	FieldTimestamp = "timestamp"
+	FieldUsername  = "username"
+	FieldOperation = "operation"
+	FieldSize      = "size"
+)
+
+// Location describes where in a log stream an event was read from.
+type Location struct {
+	// Source is the name of the log stream, e.g., a file path. It is empty when the stream is unnamed.
+	Source string
+	// Line is the 1-based line number on which the event starts, or 0 when unknown.
+	Line int
+	// Offset is the byte offset at which the event starts.
+	Offset int64
+}
+
+// Locator is implemented by Readers that are able to report the Location of the most recently read event.
+type Locator interface {
+	Location() Location
+}
+
+// Labeler is implemented by Events that carry fields beyond those of Event, e.g., the other keys of a logfmt line.
+type Labeler interface {
+	// Labels returns the names and values of the extra fields.
+	Labels() map[string]string
 }
@@ -0,0 +1,93 @@
+package reader
+
+import (
+	"io"
+	"time"
+)
+
+// Record is an Event whose fields have already been parsed. It is useful for keeping events in memory,
+// e.g., to query the same log repeatedly without reading it again.
+type Record struct {
+	Time  time.Time
+	User  string
+	Op    string
+	Bytes Size
+}
+
+var _ Event = Record{}
+
+// NewRecord parses every field of e into a Record.
+func NewRecord(e Event) (rec Record, err error) {
+	if rec.Time, err = e.Timestamp(); err != nil {
+		return
+	}
+	if rec.User, err = e.Username(); err != nil {
+		return
+	}
+	if rec.Op, err = e.Operation(); err != nil {
+		return
+	}
+	rec.Bytes, err = e.Size()
+	return
+}
+
+func (r Record) Timestamp() (time.Time, error) {
+	return r.Time, nil
+}
+
+func (r Record) Username() (string, error) {
+	return r.User, nil
+}
+
+func (r Record) Operation() (string, error) {
+	return r.Op, nil
+}
+
+func (r Record) Size() (Size, error) {
+	return r.Bytes, nil
+}
+
+// ReadAll reads every event of r into memory. It stops at the first error other than io.EOF, so wrap r with
+// logfind.NewFilterReader to skip malformed records.
+func ReadAll(r Reader) (records []Record, err error) {
+	for {
+		var e Event
+		e, err = r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec Record
+		if rec, err = NewRecord(e); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+type sliceReader struct {
+	records []Record
+	i       int
+}
+
+var _ Locator = (*sliceReader)(nil)
+
+// NewSliceReader returns a Reader over records held in memory.
+func NewSliceReader(records []Record) Reader {
+	return &sliceReader{records: records}
+}
+
+func (s *sliceReader) Read() (Event, error) {
+	if s.i >= len(s.records) {
+		return nil, io.EOF
+	}
+	s.i++
+	return s.records[s.i-1], nil
+}
+
+// Location reports the 1-based index of the most recently read record as its line.
+func (s *sliceReader) Location() Location {
+	return Location{Line: s.i}
+}
@@ -0,0 +1,41 @@
+package reader
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadAll(t *testing.T) {
+	t.Run("reads every event", func(t *testing.T) {
+		records, err := ReadAll(&stubReader{events: []stubEvent{"a1", "a2"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []Record{
+			{User: "a1", Op: "upload", Bytes: KB},
+			{User: "a2", Op: "upload", Bytes: KB},
+		}, records)
+	})
+}
+
+func TestNewSliceReader(t *testing.T) {
+	t.Run("yields records in order", func(t *testing.T) {
+		records := []Record{
+			{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34 * KB},
+			{Time: time.Date(2020, 04, 12, 22, 35, 06, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75 * KB},
+		}
+		r := NewSliceReader(records)
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, records[0], e)
+		assert.Equal(t, Location{Line: 1}, r.(Locator).Location())
+
+		e, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, records[1], e)
+
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
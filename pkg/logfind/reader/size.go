@@ -0,0 +1,127 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Size is an amount of data in bytes.
+type Size int64
+
+// Decimal (SI) and binary (IEC) multiples of a Byte.
+const (
+	Byte Size = 1
+
+	KB Size = 1000 * Byte
+	MB      = 1000 * KB
+	GB      = 1000 * MB
+	TB      = 1000 * GB
+	PB      = 1000 * TB
+
+	KiB Size = 1024 * Byte
+	MiB      = 1024 * KiB
+	GiB      = 1024 * MiB
+	TiB      = 1024 * GiB
+	PiB      = 1024 * TiB
+)
+
+// sizeUnits maps the lower case unit suffixes understood by ParseSize to their multiple.
+//
+// Note: kB and KB are both treated as 1000 bytes. Use KiB for 1024 bytes.
+var sizeUnits = map[string]Size{
+	"b":   Byte,
+	"k":   KB,
+	"kb":  KB,
+	"m":   MB,
+	"mb":  MB,
+	"g":   GB,
+	"gb":  GB,
+	"t":   TB,
+	"tb":  TB,
+	"p":   PB,
+	"pb":  PB,
+	"ki":  KiB,
+	"kib": KiB,
+	"mi":  MiB,
+	"mib": MiB,
+	"gi":  GiB,
+	"gib": GiB,
+	"ti":  TiB,
+	"tib": TiB,
+	"pi":  PiB,
+	"pib": PiB,
+}
+
+// ParseSize parses a human readable size such as 50kB, 1.5MB or 512KiB. Units are case-insensitive and may be
+// separated from the number by spaces. A number without a unit is multiplied by unit, e.g., KB for a log whose
+// sizes are recorded in kB. Negative sizes are invalid.
+func ParseSize(s string, unit Size) (Size, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return unicode.IsLetter(r)
+	})
+	number, suffix := trimmed, ""
+	if i >= 0 {
+		number, suffix = strings.TrimSpace(trimmed[:i]), strings.ToLower(trimmed[i:])
+	}
+
+	if suffix != "" {
+		var ok bool
+		if unit, ok = sizeUnits[suffix]; !ok {
+			return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, trimmed[i:])
+		}
+	}
+
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("invalid size %q: negative", s)
+		}
+		if n != 0 && (n*int64(unit))/int64(unit) != n {
+			return 0, fmt.Errorf("invalid size %q: out of range", s)
+		}
+		return Size(n) * unit, nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("invalid size %q: negative", s)
+	}
+	bytes := math.Round(f * float64(unit))
+	// float64(math.MaxInt64) rounds up to 2^63, which is out of range itself.
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: out of range", s)
+	}
+	return Size(bytes), nil
+}
+
+// humanUnits are the units used by Size.String from largest to smallest.
+var humanUnits = []struct {
+	size   Size
+	suffix string
+}{
+	{PB, "PB"},
+	{TB, "TB"},
+	{GB, "GB"},
+	{MB, "MB"},
+	{KB, "kB"},
+}
+
+// String formats s in the largest decimal unit it fills, with up to three decimal places, e.g., 34kB or 1.024MB.
+func (s Size) String() string {
+	abs := s
+	if abs < 0 {
+		abs = -abs
+	}
+	for _, u := range humanUnits {
+		if abs >= u.size {
+			return strconv.FormatFloat(math.Round(float64(s)/float64(u.size)*1000)/1000, 'f', -1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		unit Size
+		want Size
+	}{
+		{in: "50", unit: KB, want: 50000},
+		{in: "50", unit: Byte, want: 50},
+		{in: "50kB", unit: Byte, want: 50000},
+		{in: "50KB", unit: Byte, want: 50000},
+		{in: "50KiB", unit: Byte, want: 51200},
+		{in: "1.5MB", unit: Byte, want: 1500000},
+		{in: "512 KiB", unit: Byte, want: 524288},
+		{in: "2gib", unit: Byte, want: 2147483648},
+		{in: "0.5k", unit: Byte, want: 500},
+		{in: "10b", unit: KB, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in, tt.unit)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, in := range []string{"", "MB", "1.5XB", "fifty", "9223372036854775807kB", "8192.0PiB", "9223372036854775807.0", "-1", "-1.5MB"} {
+		t.Run("fails on "+in, func(t *testing.T) {
+			_, err := ParseSize(in, Byte)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSize_String(t *testing.T) {
+	tests := []struct {
+		in   Size
+		want string
+	}{
+		{in: 0, want: "0B"},
+		{in: 999, want: "999B"},
+		{in: 34 * KB, want: "34kB"},
+		{in: 1024 * KB, want: "1.024MB"},
+		{in: 1536, want: "1.536kB"},
+		{in: 1234567, want: "1.235MB"},
+		{in: 3 * TB, want: "3TB"},
+		{in: -2 * KB, want: "-2kB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.in.String())
+		})
+	}
+}
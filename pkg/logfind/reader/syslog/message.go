@@ -0,0 +1,195 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rfc3164Layout is the layout of RFC 3164 timestamps, which have no year.
+const rfc3164Layout = "Jan _2 15:04:05"
+
+// nilValue marks an absent field of an RFC 5424 header.
+const nilValue = "-"
+
+// message is a parsed syslog message.
+type message struct {
+	// priority is the PRI of the message, -1 when absent as in files written by some daemons.
+	priority int
+	// rfc5424 is set for messages in RFC 5424 format, otherwise they are in RFC 3164 format.
+	rfc5424 bool
+	// timestamp is the raw timestamp, and yearless is set when it lacks a year.
+	timestamp string
+	yearless  bool
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+	// params are the parameters of the structured data elements, keyed by name.
+	params map[string]string
+	msg    string
+}
+
+// parseMessage parses a line holding a syslog message in RFC 5424 or RFC 3164 format, optionally preceded by the
+// octet count of RFC 6587 framing.
+func parseMessage(line string) (message, error) {
+	m := message{priority: -1}
+	if i := strings.IndexByte(line, ' '); i > 0 && isDigits(line[:i]) && strings.HasPrefix(line[i+1:], "<") {
+		line = line[i+1:]
+	}
+
+	if strings.HasPrefix(line, "<") {
+		end := strings.IndexByte(line, '>')
+		if end < 2 || end > 4 || !isDigits(line[1:end]) {
+			return m, errors.New("invalid priority")
+		}
+		m.priority, _ = strconv.Atoi(line[1:end])
+		if m.priority > 191 {
+			return m, fmt.Errorf("priority %d out of range", m.priority)
+		}
+		line = line[end+1:]
+	}
+
+	if strings.HasPrefix(line, "1 ") {
+		m.rfc5424 = true
+		return m, m.parse5424(line[2:])
+	}
+	return m, m.parse3164(line)
+}
+
+// parse5424 parses the rest of an RFC 5424 message after its version.
+func (m *message) parse5424(s string) error {
+	var header [5]string
+	for i := range header {
+		var ok bool
+		header[i], s, ok = strings.Cut(s, " ")
+		if !ok && i < len(header)-1 || header[i] == "" {
+			return errors.New("truncated RFC 5424 header")
+		}
+	}
+	m.timestamp, m.hostname, m.appName, m.procID, m.msgID = header[0], header[1], header[2], header[3], header[4]
+
+	var err error
+	if s == "" {
+		return nil
+	}
+	if strings.HasPrefix(s, nilValue) {
+		s = s[len(nilValue):]
+	} else if s, err = m.parseStructuredData(s); err != nil {
+		return err
+	}
+	if s != "" && s[0] != ' ' {
+		return errors.New("expected a space after the structured data")
+	}
+	m.msg = strings.TrimPrefix(strings.TrimPrefix(s, " "), "\ufeff")
+	return nil
+}
+
+// parseStructuredData parses the structured data elements at the start of s and returns the rest of s.
+func (m *message) parseStructuredData(s string) (string, error) {
+	if !strings.HasPrefix(s, "[") {
+		return "", errors.New("expected structured data")
+	}
+	m.params = make(map[string]string)
+	for strings.HasPrefix(s, "[") {
+		end := strings.IndexAny(s, " ]")
+		if end < 2 {
+			return "", errors.New("invalid structured data element")
+		}
+		s = s[end:]
+		for strings.HasPrefix(s, " ") {
+			eq := strings.Index(s, `="`)
+			if eq < 2 {
+				return "", errors.New("invalid structured data parameter")
+			}
+			name := s[1:eq]
+			value, rest, err := unescapeParam(s[eq+2:])
+			if err != nil {
+				return "", fmt.Errorf("structured data parameter %s: %w", name, err)
+			}
+			m.params[name] = value
+			s = rest
+		}
+		if !strings.HasPrefix(s, "]") {
+			return "", errors.New("unterminated structured data element")
+		}
+		s = s[1:]
+	}
+	return s, nil
+}
+
+// unescapeParam reads a parameter value up to its closing quote, in which `\"`, `\\` and `\]` are escapes, and returns
+// it along with the rest of s.
+func unescapeParam(s string) (value, rest string, err error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("unterminated value")
+}
+
+// parse3164 parses the rest of an RFC 3164 message after its priority, e.g.,
+// `Apr 12 22:10:38 sftp1 sftp-server[4242]: close "/a.txt" bytes read 0 written 45000`.
+// High precision timestamps in RFC 3339 format, as some daemons write, are accepted in place of the RFC 3164 ones.
+func (m *message) parse3164(s string) error {
+	var ok bool
+	if len(s) > len(rfc3164Layout) && s[3] == ' ' && s[6] == ' ' && s[9] == ':' {
+		m.timestamp, m.yearless = s[:len(rfc3164Layout)], true
+		if s[len(rfc3164Layout)] != ' ' {
+			return errors.New("expected a space after the timestamp")
+		}
+		s = s[len(rfc3164Layout)+1:]
+	} else if m.timestamp, s, ok = strings.Cut(s, " "); !ok || m.timestamp == "" {
+		return errors.New("missing timestamp")
+	}
+
+	if m.hostname, s, ok = strings.Cut(s, " "); !ok || m.hostname == "" {
+		return errors.New("missing hostname")
+	}
+
+	// The tag is the name of the program, optionally followed by its process id in brackets, and ends with a colon.
+	tagEnd := strings.IndexAny(s, ":[ ")
+	if tagEnd <= 0 || s[tagEnd] == ' ' {
+		m.msg = s
+		return nil
+	}
+	m.appName = s[:tagEnd]
+	rest := s[tagEnd:]
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return errors.New("unterminated process id")
+		}
+		m.procID, rest = rest[1:end], rest[end+1:]
+	}
+	rest = strings.TrimPrefix(rest, ":")
+	m.msg = strings.TrimPrefix(rest, " ")
+	return nil
+}
+
+// parseTimestamp parses the timestamp of an RFC 5424 message, or an RFC 3164 one that has a year.
+func (m *message) parseTimestamp() (time.Time, error) {
+	if m.rfc5424 && m.timestamp == nilValue {
+		return time.Time{}, errors.New("no timestamp")
+	}
+	return time.Parse(time.RFC3339Nano, m.timestamp)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
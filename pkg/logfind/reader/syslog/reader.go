@@ -0,0 +1,353 @@
+// Package syslog reads syslog messages, one per line, in RFC 5424 or RFC 3164 format, e.g., as written by rsyslog:
+//
+//	<134>1 2020-04-12T22:10:38Z sftp1 sftp-server 4242 - [xfer@32473 user="sarah94" op="download" bytes="34000"] done
+//	<134>Apr 12 22:10:38 sftp1 sftp-server[4242]: sarah94 download 34000
+//
+// The fields of an event are extracted from the parameters of the message's structured data and, when a pattern is
+// given, from the named groups of the pattern matched against its free-form text, see WithPattern. The other header
+// fields, parameters and groups, along with the text, are exposed as labels, see lfReader.Labeler.
+//
+// RFC 3164 timestamps have no year. The first is dated in the year given by WithYear or else the latest year that does
+// not put it in the future, and the year is advanced whenever a January timestamp follows a December one.
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	lfReader.Register("syslog", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.Pattern != "" {
+			pattern, err := regexp.Compile(opts.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrPatternInvalid, err)
+			}
+			readerOpts = append(readerOpts, WithPattern(pattern))
+		}
+		if opts.Year != 0 {
+			readerOpts = append(readerOpts, WithYear(opts.Year))
+		}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// detect matches files named syslog or messages, e.g., /var/log/syslog.1, or whose first line is a syslog message.
+func detect(name string, head []byte) lfReader.Confidence {
+	base := strings.ToLower(filepath.Base(name))
+	for _, prefix := range []string{"syslog", "messages"} {
+		if base == prefix || strings.HasPrefix(base, prefix+".") {
+			return lfReader.ExtensionMatch
+		}
+	}
+	line, _, _ := strings.Cut(strings.TrimLeft(string(head), " \t\r\n"), "\n")
+	m, err := parseMessage(strings.TrimSuffix(line, "\r"))
+	if err != nil {
+		return lfReader.NoMatch
+	}
+	if m.yearless {
+		if _, err = time.Parse(rfc3164Layout, m.timestamp); err == nil {
+			return lfReader.ContentMatch
+		}
+	} else if _, err = m.parseTimestamp(); err == nil && (m.rfc5424 || m.priority >= 0) {
+		return lfReader.ContentMatch
+	}
+	return lfReader.NoMatch
+}
+
+const (
+	ErrFieldMissing   = lfReader.Error("field missing")
+	ErrPatternInvalid = lfReader.Error("invalid pattern")
+)
+
+// maxLineSize is the size of the longest line the reader accepts.
+const maxLineSize = 1 << 20
+
+type reader struct {
+	scanner  *bufio.Scanner
+	location lfReader.Location
+	next     int64
+
+	pattern  *regexp.Regexp
+	sizeUnit lfReader.Size
+	loc      *time.Location
+	columns  map[string]string
+
+	// year is the year of RFC 3164 timestamps, zero until the first is read, and month the month of the last one.
+	year  int
+	month time.Month
+	// reference is the time RFC 3164 timestamps are not dated after when no year is given.
+	reference time.Time
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithPattern extracts fields from the free-form text of messages with the named groups of pattern, e.g.,
+// `(?P<username>\S+) (?P<operation>upload|download) (?P<size>\d+)`. Groups are named after the fields they hold
+// unless mapped otherwise with WithColumns.
+func WithPattern(pattern *regexp.Regexp) ReaderOptionFunc {
+	return func(r *reader) {
+		r.pattern = pattern
+	}
+}
+
+// WithYear sets the year of the first RFC 3164 timestamp. By default it is the latest year that does not put the
+// timestamp in the future.
+func WithYear(year int) ReaderOptionFunc {
+	return func(r *reader) {
+		r.year = year
+	}
+}
+
+// WithReferenceTime sets the time that RFC 3164 timestamps are not dated after when no year is given. The default is
+// the time the reader is created.
+func WithReferenceTime(t time.Time) ReaderOptionFunc {
+	return func(r *reader) {
+		r.reference = t
+	}
+}
+
+// WithSizeUnit declares the unit of sizes without one. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithLocation sets the time zone of RFC 3164 timestamps, which have none. The default is UTC.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldUsername, to the structured data parameters or
+// pattern groups holding them. Fields that are not mapped are read from the parameter or group named after them.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, skipping blank lines.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		scanner:   bufio.NewScanner(r),
+		sizeUnit:  lfReader.Byte,
+		loc:       time.UTC,
+		reference: time.Now(),
+	}
+	rdr.scanner.Buffer(nil, maxLineSize)
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the event on the next non-blank line. A line that is not a syslog message is reported as a
+// *lfReader.RecordError, after which reading continues with the next line.
+func (r *reader) Read() (lfReader.Event, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		r.location.Line++
+		r.location.Offset = r.next
+		r.next += int64(len(line)) + 1
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		m, err := parseMessage(line)
+		if err != nil {
+			return nil, &lfReader.RecordError{
+				Source: r.location.Source,
+				Line:   r.location.Line,
+				Offset: r.location.Offset,
+				Err:    err,
+			}
+		}
+		e := event{message: m, location: r.location, r: r}
+		if m.yearless {
+			e.time, e.timeErr = r.date(m.timestamp)
+		} else {
+			e.time, e.timeErr = m.parseTimestamp()
+		}
+		if r.pattern != nil {
+			e.groups = r.match(m.msg)
+		}
+		return e, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// date dates an RFC 3164 timestamp, which has no year, following the timestamps before it.
+func (r *reader) date(timestamp string) (time.Time, error) {
+	t, err := time.ParseInLocation(rfc3164Layout, timestamp, r.loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch {
+	case r.year == 0:
+		r.year = r.reference.In(r.loc).Year()
+		if withYear(t, r.year).After(r.reference.Add(24 * time.Hour)) {
+			r.year--
+		}
+	case r.month == time.December && t.Month() == time.January:
+		r.year++
+	}
+	r.month = t.Month()
+	return withYear(t, r.year), nil
+}
+
+func withYear(t time.Time, year int) time.Time {
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// match returns the values of the named groups of the pattern matched against msg, or nil when it does not match.
+func (r *reader) match(msg string) map[string]string {
+	submatches := r.pattern.FindStringSubmatch(msg)
+	if submatches == nil {
+		return nil
+	}
+	groups := make(map[string]string)
+	for i, name := range r.pattern.SubexpNames() {
+		if name != "" && i < len(submatches) {
+			groups[name] = submatches[i]
+		}
+	}
+	return groups
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+type event struct {
+	message
+	// time is the parsed timestamp, or timeErr the failure to parse it.
+	time    time.Time
+	timeErr error
+	// groups are the values of the named groups of the pattern, nil when there is none or it did not match.
+	groups   map[string]string
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+var _ lfReader.Labeler = event{}
+
+// name returns the name of the parameter or group holding field.
+func (e event) name(field string) string {
+	if name, ok := e.r.columns[field]; ok {
+		return name
+	}
+	return field
+}
+
+// value returns the value of field, taken from the pattern groups or else the structured data parameters.
+func (e event) value(field string) (string, error) {
+	name := e.name(field)
+	if value, ok := e.groups[name]; ok {
+		return value, nil
+	}
+	if value, ok := e.params[name]; ok {
+		return value, nil
+	}
+	return "", e.error(field, "", ErrFieldMissing)
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field, value string, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+func (e event) Timestamp() (time.Time, error) {
+	if e.timeErr != nil {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, e.timestamp, e.timeErr)
+	}
+	return e.time, nil
+}
+
+func (e event) Username() (string, error) {
+	return e.value(lfReader.FieldUsername)
+}
+
+func (e event) Operation() (string, error) {
+	return e.value(lfReader.FieldOperation)
+}
+
+func (e event) Size() (lfReader.Size, error) {
+	value, err := e.value(lfReader.FieldSize)
+	if err != nil {
+		return 0, err
+	}
+	size, err := lfReader.ParseSize(value, e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
+
+// Labels returns the header fields that are present, the text of the message, and the structured data parameters
+// and pattern groups that are not read as fields of the event.
+func (e event) Labels() map[string]string {
+	labels := make(map[string]string)
+	for name, value := range e.params {
+		labels[name] = value
+	}
+	for name, value := range e.groups {
+		labels[name] = value
+	}
+	for _, field := range []string{lfReader.FieldUsername, lfReader.FieldOperation, lfReader.FieldSize} {
+		delete(labels, e.name(field))
+	}
+
+	if e.priority >= 0 {
+		labels["facility"] = strconv.Itoa(e.priority / 8)
+		labels["severity"] = strconv.Itoa(e.priority % 8)
+	}
+	for name, value := range map[string]string{
+		"hostname": e.hostname,
+		"app_name": e.appName,
+		"procid":   e.procID,
+		"msgid":    e.msgID,
+		"message":  e.msg,
+	} {
+		if value != "" && value != nilValue {
+			labels[name] = value
+		}
+	}
+	return labels
+}
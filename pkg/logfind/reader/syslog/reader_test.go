@@ -0,0 +1,181 @@
+package syslog
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/logfmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rfc5424Input = `<134>1 2020-04-12T22:10:38.5Z sftp1 sftp-server 4242 xfer [xfer@32473 username="sarah94" operation="download" size="34000" path="/a \"b\".txt"][meta x="1"] done
+
+<134>1 2020-04-12T22:15:06-04:00 sftp1 sftp-server - - [xfer@32473 username="Maia86" operation="upload" size="75kB"]
+`
+
+const rfc3164Input = `<134>Dec 31 23:59:58 sftp1 sftp-server[4242]: sarah94 download 34000 bytes
+Jan  1 00:00:03 sftp1 sftp-server[4242]: Maia86 upload 75000 bytes
+`
+
+var pattern = regexp.MustCompile(`^(?P<user>\S+) (?P<operation>\S+) (?P<size>\d+) bytes$`)
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads RFC 5424 structured data", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(rfc5424Input)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 5e8, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 4, 13, 2, 15, 6, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75000},
+		}, normalize(records))
+	})
+
+	t.Run("exposes other fields as labels", func(t *testing.T) {
+		e, err := NewReader(strings.NewReader(rfc5424Input)).Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"facility": "16",
+			"severity": "6",
+			"hostname": "sftp1",
+			"app_name": "sftp-server",
+			"procid":   "4242",
+			"msgid":    "xfer",
+			"message":  "done",
+			"path":     `/a "b".txt`,
+			"x":        "1",
+		}, e.(lfReader.Labeler).Labels())
+	})
+
+	t.Run("reads RFC 3164 with pattern across the new year", func(t *testing.T) {
+		r := NewReader(strings.NewReader(rfc3164Input),
+			WithPattern(pattern),
+			WithColumns(map[string]string{lfReader.FieldUsername: "user"}),
+			WithYear(2019),
+		)
+		records, err := lfReader.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2019, 12, 31, 23, 59, 58, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 1, 1, 0, 0, 3, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75000},
+		}, records)
+	})
+
+	t.Run("dates RFC 3164 timestamps before the reference time", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader(rfc3164Input), WithReferenceTime(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)), WithLocation(loc))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		timestamp, err := e.Timestamp()
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2021, 1, 1, 4, 59, 58, 0, time.UTC).Equal(timestamp), timestamp)
+
+		r = NewReader(strings.NewReader(rfc3164Input), WithReferenceTime(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)))
+		e, err = r.Read()
+		assert.NoError(t, err)
+		timestamp, err = e.Timestamp()
+		assert.NoError(t, err)
+		assert.Equal(t, 2021, timestamp.Year())
+	})
+
+	t.Run("reports location", func(t *testing.T) {
+		r := NewReader(strings.NewReader(rfc5424Input))
+		_, err := r.Read()
+		assert.NoError(t, err)
+		_, err = r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r.(lfReader.Locator).Location().Line)
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader("<999>1 - - - - - -\n<13>1 - host app - - - no fields\n"), WithPattern(pattern))
+		_, err := r.Read()
+		var recErr *lfReader.RecordError
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 1, recErr.Line)
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, lfReader.FieldTimestamp, recErr.Field)
+		_, err = e.Username()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+	})
+}
+
+// normalize converts the times of records to UTC.
+func normalize(records []lfReader.Record) []lfReader.Record {
+	for i := range records {
+		records[i].Time = records[i].Time.UTC()
+	}
+	return records
+}
+
+func Test_parseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    message
+		wantErr string
+	}{
+		{
+			name: "RFC 5424 with octet count and BOM",
+			line: "63 <165>1 2003-10-11T22:14:15.003Z mymachine su - ID47 - \ufeff'su root' failed",
+			want: message{priority: 165, rfc5424: true, timestamp: "2003-10-11T22:14:15.003Z", hostname: "mymachine", appName: "su", procID: "-", msgID: "ID47", msg: "'su root' failed"},
+		},
+		{
+			name: "RFC 5424 escapes",
+			line: `<165>1 - h a p m [id a="\]\\\"\n"]`,
+			want: message{priority: 165, rfc5424: true, timestamp: "-", hostname: "h", appName: "a", procID: "p", msgID: "m", params: map[string]string{"a": `]\"\n`}},
+		},
+		{
+			name: "RFC 3164",
+			line: "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			want: message{priority: 34, timestamp: "Oct 11 22:14:15", yearless: true, hostname: "mymachine", appName: "su", msg: "'su root' failed for lonvick on /dev/pts/8"},
+		},
+		{
+			name: "RFC 3164 without priority and with high precision timestamp",
+			line: "2020-04-12T22:10:38.123+02:00 host kernel[0]: boot",
+			want: message{priority: -1, timestamp: "2020-04-12T22:10:38.123+02:00", hostname: "host", appName: "kernel", procID: "0", msg: "boot"},
+		},
+		{
+			name: "RFC 3164 without tag",
+			line: "Apr  2 22:10:38 host just text",
+			want: message{priority: -1, timestamp: "Apr  2 22:10:38", yearless: true, hostname: "host", msg: "just text"},
+		},
+		{name: "invalid priority", line: "<x>1 - - - - - -", wantErr: "invalid priority"},
+		{name: "truncated header", line: "<1>1 - host", wantErr: "truncated"},
+		{name: "unterminated element", line: `<1>1 - h a p m [id a="1"`, wantErr: "unterminated structured data element"},
+		{name: "unterminated value", line: `<1>1 - h a p m [id a="1]`, wantErr: "unterminated value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMessage(tt.line)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, head := range []string{rfc5424Input, rfc3164Input, "Apr 12 22:10:38 sftp1 sshd[1]: Accepted publickey for jeff22, port 22\n"} {
+		format, err := lfReader.Detect("", []byte(head))
+		assert.NoError(t, err)
+		assert.Equal(t, "syslog", format, head)
+	}
+
+	format, err := lfReader.Detect("/var/log/messages.1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "syslog", format)
+}
@@ -0,0 +1,459 @@
+// Package w3c reads logs in the W3C Extended Log File Format, as written by IIS and its FTP service:
+//
+//	#Version: 1.0
+//	#Date: 2020-04-12 22:10:38
+//	#Fields: date time c-ip cs-username cs-method cs-uri-stem sc-status sc-bytes cs-bytes
+//	2020-04-12 22:10:38 10.0.0.2 sarah94 RETR /files/report.pdf 226 34000 0
+//
+// The #Fields directive names the fields of the lines after it, and may change within a log. The date of a line is
+// taken from its date field or else from the last #Date directive, advancing a day whenever the time of day goes
+// backwards. Times are in UTC unless told otherwise.
+//
+// The username of an event is its cs-username, "-" for anonymous requests. Its operation is derived from cs-method
+// and cs-uri-stem by rules, by default STOR and PUT being uploads and RETR and GET downloads, see access.Rule. Its size
+// is the bytes sent by the client, cs-bytes, for uploads and by the server, sc-bytes, otherwise. The other fields of
+// a line are exposed as its labels, see lfReader.Labeler.
+package w3c
+
+import (
+	"bufio"
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/access"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func init() {
+	lfReader.Register("w3c", detect, func(r io.Reader, opts lfReader.FormatOptions) (lfReader.Reader, error) {
+		readerOpts := []ReaderOptionFunc{WithColumns(opts.Columns)}
+		if opts.OperationRules != "" {
+			rules, err := access.ParseRules(opts.OperationRules)
+			if err != nil {
+				return nil, err
+			}
+			readerOpts = append(readerOpts, WithRules(rules))
+		}
+		if opts.SizeUnit != 0 {
+			readerOpts = append(readerOpts, WithSizeUnit(opts.SizeUnit))
+		}
+		if opts.TimestampLayout != "" {
+			readerOpts = append(readerOpts, WithTimestampLayout(opts.TimestampLayout))
+		}
+		if opts.Location != nil {
+			readerOpts = append(readerOpts, WithLocation(opts.Location))
+		}
+		return NewReader(r, readerOpts...), nil
+	})
+}
+
+// directives are the directives a log may start with.
+var directives = []string{"#Version:", "#Fields:", "#Software:", "#Date:", "#Start-Date:", "#Remark:"}
+
+// detect matches files named like IIS logs, e.g., u_ex200412.log, or starting with a directive.
+func detect(name string, head []byte) lfReader.Confidence {
+	base := strings.ToLower(filepath.Base(name))
+	if strings.HasPrefix(base, "u_ex") && strings.HasSuffix(base, ".log") {
+		return lfReader.ExtensionMatch
+	}
+	s := strings.TrimLeft(string(head), " \t\r\n")
+	for _, d := range directives {
+		if strings.HasPrefix(s, d) {
+			return lfReader.ContentMatch
+		}
+	}
+	return lfReader.NoMatch
+}
+
+const (
+	ErrFieldMissing  = lfReader.Error("field missing")
+	ErrFieldsMissing = lfReader.Error("no #Fields directive")
+	ErrFieldCount    = lfReader.Error("wrong number of fields")
+)
+
+// DefaultRules treat FTP STOR, STOU and APPE as well as HTTP PUT and POST as uploads, and FTP RETR and HTTP GET
+// as downloads.
+var DefaultRules = []access.Rule{
+	{Methods: []string{"STOR", "STOU", "APPE", "PUT", "POST"}, Operation: "upload"},
+	{Methods: []string{"RETR", "GET"}, Operation: "download"},
+}
+
+// The fields of the W3C format that events are read from.
+const (
+	fieldDate     = "date"
+	fieldTime     = "time"
+	fieldUsername = "cs-username"
+	fieldMethod   = "cs-method"
+	fieldURIStem  = "cs-uri-stem"
+	fieldSent     = "sc-bytes"
+	fieldReceived = "cs-bytes"
+)
+
+// The layouts of the date and time fields and of the #Date directive.
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// maxLineSize is the size of the longest line the reader accepts.
+const maxLineSize = 1 << 20
+
+type reader struct {
+	scanner  *bufio.Scanner
+	location lfReader.Location
+	next     int64
+
+	// fields are those named by the last #Fields directive.
+	fields []string
+	// date is the date of the last #Date directive, advanced by a day whenever the time of day went backwards since,
+	// and clock the time of day of the last line.
+	date  time.Time
+	clock string
+
+	rules           []access.Rule
+	sizeUnit        lfReader.Size
+	timestampLayout string
+	loc             *time.Location
+	columns         map[string]string
+}
+
+var _ lfReader.Locator = (*reader)(nil)
+
+// ReaderOptionFunc customizes a reader created by NewReader.
+type ReaderOptionFunc func(*reader)
+
+// WithRules replaces the rules deriving operations from cs-method and cs-uri-stem. The default is DefaultRules.
+func WithRules(rules []access.Rule) ReaderOptionFunc {
+	return func(r *reader) {
+		r.rules = rules
+	}
+}
+
+// WithSizeUnit declares the unit of sizes. The default is lfReader.Byte.
+func WithSizeUnit(unit lfReader.Size) ReaderOptionFunc {
+	return func(r *reader) {
+		r.sizeUnit = unit
+	}
+}
+
+// WithTimestampLayout declares the layout of the timestamps of the field lfReader.FieldTimestamp is mapped to with
+// WithColumns, see time.Parse. The default is time.RFC3339.
+func WithTimestampLayout(layout string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the time zone of dates and times. The default is UTC, as the format prescribes.
+func WithLocation(loc *time.Location) ReaderOptionFunc {
+	return func(r *reader) {
+		r.loc = loc
+	}
+}
+
+// WithColumns maps the fields of an event, e.g., lfReader.FieldSize, to the fields of the log holding them, e.g.,
+// sc-bytes. Mapping lfReader.FieldOperation reads the operation from a field rather than deriving it from cs-method.
+func WithColumns(columns map[string]string) ReaderOptionFunc {
+	return func(r *reader) {
+		r.columns = columns
+	}
+}
+
+// NewReader returns a lfReader.Reader that reads events from r, skipping blank lines.
+// When r has a Name method, e.g., an *os.File, its name is used as the source of the events' lfReader.Location.
+func NewReader(r io.Reader, opts ...ReaderOptionFunc) lfReader.Reader {
+	rdr := &reader{
+		scanner:         bufio.NewScanner(r),
+		rules:           DefaultRules,
+		sizeUnit:        lfReader.Byte,
+		timestampLayout: time.RFC3339,
+		loc:             time.UTC,
+	}
+	rdr.scanner.Buffer(nil, maxLineSize)
+	if named, ok := r.(interface{ Name() string }); ok {
+		rdr.location.Source = named.Name()
+	}
+	for _, opt := range opts {
+		opt(rdr)
+	}
+	return rdr
+}
+
+// Read reads the event on the next line that is neither blank nor a directive. A line that does not have the fields
+// of the last #Fields directive, or follows none, is reported as a *lfReader.RecordError, after which reading continues
+// with the next line.
+func (r *reader) Read() (lfReader.Event, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSuffix(r.scanner.Text(), "\r")
+		r.location.Line++
+		r.location.Offset = r.next
+		r.next += int64(len(r.scanner.Text())) + 1
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if err := r.directive(line); err != nil {
+				return nil, r.error(err)
+			}
+			continue
+		}
+
+		if r.fields == nil {
+			return nil, r.error(ErrFieldsMissing)
+		}
+		values, err := split(line)
+		if err != nil {
+			return nil, r.error(err)
+		}
+		if len(values) != len(r.fields) {
+			return nil, r.error(ErrFieldCount)
+		}
+		e := event{values: make(map[string]string, len(values)), location: r.location, r: r}
+		for i, value := range values {
+			e.values[r.fields[i]] = value
+		}
+		if _, ok := e.values[fieldDate]; !ok {
+			e.date = r.dateOf(e.values[fieldTime])
+		}
+		return e, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Location returns the location of the most recently read event.
+func (r *reader) Location() lfReader.Location {
+	return r.location
+}
+
+// error wraps err in a *lfReader.RecordError locating the current line.
+func (r *reader) error(err error) error {
+	return &lfReader.RecordError{
+		Source: r.location.Source,
+		Line:   r.location.Line,
+		Offset: r.location.Offset,
+		Err:    err,
+	}
+}
+
+// directive applies the #Fields and #Date directives and ignores others.
+func (r *reader) directive(line string) error {
+	name, value, _ := strings.Cut(line, ":")
+	value = strings.TrimSpace(value)
+	switch name {
+	case "#Fields":
+		r.fields = strings.Fields(value)
+		if len(r.fields) == 0 {
+			r.fields = nil
+			return ErrFieldsMissing
+		}
+	case "#Date":
+		t, err := time.ParseInLocation(dateTimeLayout, value, r.loc)
+		if err != nil {
+			return err
+		}
+		r.date, r.clock = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, r.loc), ""
+	}
+	return nil
+}
+
+// dateOf returns the date of a line without a date field whose time of day is clock, advancing the date of the last
+// #Date directive when the time of day goes backwards, as it does past midnight.
+func (r *reader) dateOf(clock string) time.Time {
+	if r.date.IsZero() {
+		return r.date
+	}
+	// Times of day in the same layout order lexically.
+	if r.clock != "" && clock < r.clock {
+		r.date = r.date.AddDate(0, 0, 1)
+	}
+	r.clock = clock
+	return r.date
+}
+
+// split splits a line into its values, which are separated by whitespace unless quoted, doubled quotes escaping a
+// quote.
+func split(line string) ([]string, error) {
+	var values []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		if line[i] != '"' {
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			values = append(values, line[start:i])
+			continue
+		}
+
+		var b strings.Builder
+		for i++; ; i++ {
+			if i == len(line) {
+				return nil, errors.New("unterminated quote")
+			}
+			if line[i] == '"' {
+				if i+1 < len(line) && line[i+1] == '"' {
+					b.WriteByte('"')
+					i++
+					continue
+				}
+				i++
+				break
+			}
+			b.WriteByte(line[i])
+		}
+		values = append(values, b.String())
+	}
+	return values, nil
+}
+
+type event struct {
+	values map[string]string
+	// date is the date of the last #Date directive for lines without a date field.
+	date     time.Time
+	location lfReader.Location
+	// r holds the options of the reader.
+	r *reader
+}
+
+var _ lfReader.Labeler = event{}
+
+// value returns the value of the named field of the log, reporting it missing as field of the event.
+func (e event) value(field, name string) (string, error) {
+	value, ok := e.values[name]
+	if !ok {
+		return "", e.error(field, "", ErrFieldMissing)
+	}
+	return value, nil
+}
+
+// mapped returns the name of the field of the log holding field, name unless mapped otherwise.
+func (e event) mapped(field, name string) string {
+	if column, ok := e.r.columns[field]; ok {
+		return column
+	}
+	return name
+}
+
+// error wraps err in a *lfReader.RecordError describing field, whose raw value is value.
+func (e event) error(field, value string, err error) error {
+	return &lfReader.RecordError{
+		Source: e.location.Source,
+		Line:   e.location.Line,
+		Offset: e.location.Offset,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+// Timestamp combines the date and time fields, or parses the field lfReader.FieldTimestamp is mapped to.
+func (e event) Timestamp() (time.Time, error) {
+	if column, ok := e.r.columns[lfReader.FieldTimestamp]; ok {
+		value, err := e.value(lfReader.FieldTimestamp, column)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t, err := time.ParseInLocation(e.r.timestampLayout, value, e.r.loc)
+		if err != nil {
+			return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+		}
+		return t, nil
+	}
+
+	clock, err := e.value(lfReader.FieldTimestamp, fieldTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date, ok := e.values[fieldDate]
+	if !ok {
+		if e.date.IsZero() {
+			return time.Time{}, e.error(lfReader.FieldTimestamp, clock, errors.New("no date field or #Date directive"))
+		}
+		date = e.date.Format(dateLayout)
+	}
+	value := date + " " + clock
+	t, err := time.ParseInLocation(dateTimeLayout, value, e.r.loc)
+	if err != nil {
+		return time.Time{}, e.error(lfReader.FieldTimestamp, value, err)
+	}
+	return t, nil
+}
+
+func (e event) Username() (string, error) {
+	return e.value(lfReader.FieldUsername, e.mapped(lfReader.FieldUsername, fieldUsername))
+}
+
+// Operation reads the operation from the field it is mapped to or derives it from cs-method and cs-uri-stem.
+func (e event) Operation() (string, error) {
+	if column, ok := e.r.columns[lfReader.FieldOperation]; ok {
+		return e.value(lfReader.FieldOperation, column)
+	}
+	method, err := e.value(lfReader.FieldOperation, fieldMethod)
+	if err != nil {
+		return "", err
+	}
+	// IIS 6 FTP logs prefix methods with the session number, e.g., [1]STOR.
+	if strings.HasPrefix(method, "[") {
+		if end := strings.IndexByte(method, ']'); end > 0 {
+			method = method[end+1:]
+		}
+	}
+	return access.Operation(e.r.rules, method, e.values[fieldURIStem]), nil
+}
+
+// Size returns the bytes sent by the client for uploads and by the server otherwise, unless mapped to another field.
+// "-" is read as zero.
+func (e event) Size() (lfReader.Size, error) {
+	name, ok := e.r.columns[lfReader.FieldSize]
+	if !ok {
+		name = fieldSent
+		if op, err := e.Operation(); err == nil && op == "upload" {
+			name = fieldReceived
+		}
+		if _, has := e.values[name]; !has {
+			name = map[string]string{fieldSent: fieldReceived, fieldReceived: fieldSent}[name]
+		}
+	}
+	value, err := e.value(lfReader.FieldSize, name)
+	if err != nil {
+		return 0, err
+	}
+	if value == "-" {
+		return 0, nil
+	}
+	size, err := lfReader.ParseSize(value, e.r.sizeUnit)
+	if err != nil {
+		return 0, e.error(lfReader.FieldSize, value, err)
+	}
+	return size, nil
+}
+
+// Labels returns the fields of the line other than those holding the username, timestamp and, when mapped, the
+// operation and size.
+func (e event) Labels() map[string]string {
+	labels := make(map[string]string, len(e.values))
+	for name, value := range e.values {
+		labels[name] = value
+	}
+	delete(labels, e.mapped(lfReader.FieldUsername, fieldUsername))
+	if column, ok := e.r.columns[lfReader.FieldTimestamp]; ok {
+		delete(labels, column)
+	} else {
+		delete(labels, fieldDate)
+		delete(labels, fieldTime)
+	}
+	for _, field := range []string{lfReader.FieldOperation, lfReader.FieldSize} {
+		if column, ok := e.r.columns[field]; ok {
+			delete(labels, column)
+		}
+	}
+	return labels
+}
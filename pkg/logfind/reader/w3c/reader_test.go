@@ -0,0 +1,128 @@
+package w3c
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/access"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	_ "github.com/kyleishie/logfind/pkg/logfind/reader/syslog"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `#Software: Microsoft Internet Information Services 10.0
+#Version: 1.0
+#Date: 2020-04-12 22:00:00
+#Fields: date time c-ip cs-username cs-method cs-uri-stem sc-status sc-bytes cs-bytes
+2020-04-12 22:10:38 10.0.0.2 sarah94 RETR /files/report.pdf 226 34000 120
+2020-04-12 22:15:06 10.0.0.3 Maia86 STOR /files/photo.jpg 226 90 75000
+
+#Date: 2020-04-12 23:59:00
+#Fields: time cs-username cs-method cs-uri-stem sc-bytes cs(User-Agent)
+23:59:30 jeff22 [3]RETR /files/a.txt 1000 "FileZilla ""3.0"""
+00:00:15 jeff22 DELE /files/a.txt - -
+`
+
+func TestNewReader(t *testing.T) {
+	t.Run("reads events across directives", func(t *testing.T) {
+		records, err := lfReader.ReadAll(NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, []lfReader.Record{
+			{Time: time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34000},
+			{Time: time.Date(2020, 4, 12, 22, 15, 6, 0, time.UTC), User: "Maia86", Op: "upload", Bytes: 75000},
+			{Time: time.Date(2020, 4, 12, 23, 59, 30, 0, time.UTC), User: "jeff22", Op: "download", Bytes: 1000},
+			{Time: time.Date(2020, 4, 13, 0, 0, 15, 0, time.UTC), User: "jeff22", Op: "dele", Bytes: 0},
+		}, records)
+	})
+
+	t.Run("exposes other fields as labels", func(t *testing.T) {
+		r := NewReader(strings.NewReader(input))
+		for i := 0; i < 2; i++ {
+			_, err := r.Read()
+			assert.NoError(t, err)
+		}
+		e, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"cs-method":      "[3]RETR",
+			"cs-uri-stem":    "/files/a.txt",
+			"sc-bytes":       "1000",
+			"cs(User-Agent)": `FileZilla "3.0"`,
+		}, e.(lfReader.Labeler).Labels())
+		assert.Equal(t, 10, r.(lfReader.Locator).Location().Line)
+	})
+
+	t.Run("respects options", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader("#Fields: x-time cs-username x-action x-size\n2020-04-12T18:10:38 sarah94 fetch 34\n"),
+			WithColumns(map[string]string{
+				lfReader.FieldTimestamp: "x-time",
+				lfReader.FieldOperation: "x-action",
+				lfReader.FieldSize:      "x-size",
+			}),
+			WithTimestampLayout("2006-01-02T15:04:05"),
+			WithLocation(loc),
+			WithSizeUnit(lfReader.KB),
+		)
+		e, err := r.Read()
+		assert.NoError(t, err)
+		rec, err := lfReader.NewRecord(e)
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2020, 4, 12, 22, 10, 38, 0, time.UTC).Equal(rec.Time))
+		assert.Equal(t, "fetch", rec.Op)
+		assert.Equal(t, 34*lfReader.KB, rec.Bytes)
+		assert.Empty(t, e.(lfReader.Labeler).Labels())
+	})
+
+	t.Run("respects rules", func(t *testing.T) {
+		rules, err := access.ParseRules("* /inbox/=upload")
+		assert.NoError(t, err)
+		r := NewReader(strings.NewReader("#Fields: date time cs-username cs-method cs-uri-stem cs-bytes\n2020-04-12 22:10:38 a MKD /inbox/x 0\n"), WithRules(rules))
+		e, err := r.Read()
+		assert.NoError(t, err)
+		op, err := e.Operation()
+		assert.NoError(t, err)
+		assert.Equal(t, "upload", op)
+	})
+
+	t.Run("reports malformed records as RecordError", func(t *testing.T) {
+		r := NewReader(strings.NewReader("2020-04-12 22:10:38 a\n#Fields: time cs-username\n22:10:38\n22:10:38 \"a\n22:10:38 a\n"))
+		var recErr *lfReader.RecordError
+		_, err := r.Read()
+		assert.ErrorIs(t, err, ErrFieldsMissing)
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 1, recErr.Line)
+
+		_, err = r.Read()
+		assert.ErrorIs(t, err, ErrFieldCount)
+		assert.True(t, errors.As(err, &recErr))
+		assert.Equal(t, 3, recErr.Line)
+
+		_, err = r.Read()
+		assert.ErrorContains(t, err, "unterminated quote")
+
+		e, err := r.Read()
+		assert.NoError(t, err)
+		_, err = e.Timestamp()
+		assert.ErrorContains(t, err, "no date field or #Date directive")
+		_, err = e.Operation()
+		assert.ErrorIs(t, err, ErrFieldMissing)
+
+		_, err = r.Read()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+func TestDetect(t *testing.T) {
+	format, err := lfReader.Detect("logs/W3SVC1/u_ex200412.log", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "w3c", format)
+
+	format, err = lfReader.Detect("", []byte(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "w3c", format)
+}
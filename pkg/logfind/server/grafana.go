@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metrics that may start a Grafana target. A target without one counts events.
+const (
+	// MetricCount counts the events in each time bucket.
+	MetricCount = "count"
+	// MetricSize sums the sizes, in bytes, of the events in each time bucket.
+	MetricSize = "size"
+	// MetricUsers counts the distinct users in each time bucket.
+	MetricUsers = "users"
+)
+
+const (
+	// maxBuckets caps the number of time buckets of a series when Grafana does not send maxDataPoints.
+	maxBuckets = 10000
+	// maxAnnotations caps the number of events returned by /annotations.
+	maxAnnotations = 1000
+)
+
+// NewGrafanaHandler returns an http.Handler implementing Grafana's simple JSON datasource protocol over the events of
+// source, so lf queries can be charted in Grafana panels:
+//
+//	GET  /             Answers 200 so Grafana can test the datasource.
+//	POST /search       Suggests targets: the metrics and a count per username and operation.
+//	POST /query        Time-bucketed series for "timeserie" targets, the matching events for "table" targets.
+//	POST /annotations  The matching events as annotations.
+//
+// Targets are a metric, count, size or users, followed by an lf filter expression, e.g., `size -u jeff22 -op upload`.
+// Annotation queries are filter expressions without a metric.
+func NewGrafanaHandler(source Source, opts ...HandlerOptionFunc) http.Handler {
+	h := newHandler(source, []string{http.MethodGet, http.MethodHead, http.MethodPost}, opts)
+	h.mux.HandleFunc("/", h.grafanaTest)
+	h.mux.HandleFunc("/search", h.grafanaSearch)
+	h.mux.HandleFunc("/query", h.grafanaQuery)
+	h.mux.HandleFunc("/annotations", h.grafanaAnnotations)
+	return h
+}
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (h *handler) grafanaTest(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *handler) grafanaSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	rdr, closeFn, err := h.source.Open(ctx)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer closeFn()
+
+	usernames := make(map[string]bool)
+	operations := make(map[string]bool)
+	fr, err := logfind.NewFilterReader(rdr, logfind.WithErrorPolicy(logfind.Skip))
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	for {
+		e, err := fr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		rec, err := reader.NewRecord(e)
+		if err != nil {
+			continue
+		}
+		usernames[rec.User] = true
+		operations[rec.Op] = true
+	}
+
+	targets := []string{MetricCount, MetricSize, MetricUsers}
+	for _, username := range sortedKeys(usernames) {
+		targets = append(targets, MetricCount+" -u "+quoteArg(username))
+	}
+	for _, operation := range sortedKeys(operations) {
+		targets = append(targets, MetricCount+" -op "+quoteArg(operation))
+	}
+
+	suggestions := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if strings.HasPrefix(target, req.Target) {
+			suggestions = append(suggestions, target)
+		}
+	}
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+type grafanaSeries struct {
+	Target string `json:"target"`
+	// Datapoints are pairs of a value and a time in milliseconds.
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+func (h *handler) grafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Range         grafanaRange `json:"range"`
+		IntervalMs    int64        `json:"intervalMs"`
+		MaxDataPoints int          `json:"maxDataPoints"`
+		Targets       []struct {
+			Target string `json:"target"`
+			Type   string `json:"type"`
+		} `json:"targets"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if !req.Range.From.Before(req.Range.To) {
+		writeError(w, http.StatusBadRequest, logfind.ErrTimeRangeInvalid)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	resp := make([]interface{}, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		metric, q, err := ParseTarget(target.Target)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("target %q: %w", target.Target, err))
+			return
+		}
+		records, err := h.records(ctx, q, req.Range)
+		if err != nil {
+			writeError(w, statusOf(err), fmt.Errorf("target %q: %w", target.Target, err))
+			return
+		}
+
+		if target.Type == "table" {
+			resp = append(resp, grafanaEventTable(records, req.MaxDataPoints))
+			continue
+		}
+		interval := bucketInterval(req.Range, time.Duration(req.IntervalMs)*time.Millisecond, req.MaxDataPoints)
+		resp = append(resp, grafanaSeries{
+			Target:     target.Target,
+			Datapoints: bucket(records, metric, req.Range, interval),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type grafanaAnnotation struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+func (h *handler) grafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Range      grafanaRange    `json:"range"`
+		Annotation json.RawMessage `json:"annotation"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	var annotation struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(req.Annotation, &annotation); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q, err := query.ParseExpression(annotation.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	records, err := h.records(ctx, q, req.Range)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	if len(records) > maxAnnotations {
+		records = records[:maxAnnotations]
+	}
+	resp := make([]grafanaAnnotation, 0, len(records))
+	for _, rec := range records {
+		resp = append(resp, grafanaAnnotation{
+			Annotation: req.Annotation,
+			Time:       rec.Time.UnixMilli(),
+			Title:      rec.User + " " + rec.Op,
+			Text:       rec.Bytes.String(),
+			Tags:       []string{rec.User, rec.Op},
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ParseTarget splits a Grafana target into its metric, defaulting to MetricCount, and the Query of its filter expression.
+func ParseTarget(target string) (metric string, q *query.Query, err error) {
+	metric = MetricCount
+	expr := strings.TrimSpace(target)
+	first := expr
+	if i := strings.IndexAny(expr, " \t"); i >= 0 {
+		first = expr[:i]
+	}
+	switch first {
+	case MetricCount, MetricSize, MetricUsers:
+		metric = first
+		expr = expr[len(first):]
+	}
+	q, err = query.ParseExpression(expr)
+	return
+}
+
+// records returns the events matching q within rng, in the order they were read.
+func (h *handler) records(ctx context.Context, q *query.Query, rng grafanaRange) ([]reader.Record, error) {
+	opts, err := queryOptions(q, nil)
+	if err != nil {
+		return nil, err
+	}
+	rdr, closeFn, err := h.source.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	fr, err := logfind.NewFilterReader(rdr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	all, err := reader.ReadAll(fr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The range is applied here rather than as an option so that it narrows any time range of the expression.
+	var records []reader.Record
+	for _, rec := range all {
+		if !rec.Time.Before(rng.From) && rec.Time.Before(rng.To) {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// bucketInterval returns interval, widened when needed so that rng holds at most maxDataPoints, or maxBuckets, buckets.
+func bucketInterval(rng grafanaRange, interval time.Duration, maxDataPoints int) time.Duration {
+	if maxDataPoints <= 0 || maxDataPoints > maxBuckets {
+		maxDataPoints = maxBuckets
+	}
+	span := rng.To.Sub(rng.From)
+	if min := (span + time.Duration(maxDataPoints) - 1) / time.Duration(maxDataPoints); interval < min {
+		interval = min
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval.Truncate(time.Millisecond)
+}
+
+// bucket computes metric for each interval of rng. Buckets are aligned to multiples of interval and every bucket is
+// present, so empty buckets chart as zero rather than as gaps.
+func bucket(records []reader.Record, metric string, rng grafanaRange, interval time.Duration) [][2]float64 {
+	start := rng.From.Truncate(interval)
+	n := int((rng.To.Sub(start) + interval - 1) / interval)
+
+	values := make([]float64, n)
+	users := make([]map[string]bool, n)
+	for _, rec := range records {
+		i := int(rec.Time.Sub(start) / interval)
+		switch metric {
+		case MetricCount:
+			values[i]++
+		case MetricSize:
+			values[i] += float64(rec.Bytes)
+		case MetricUsers:
+			if users[i] == nil {
+				users[i] = make(map[string]bool)
+			}
+			users[i][rec.User] = true
+			values[i] = float64(len(users[i]))
+		}
+	}
+
+	datapoints := make([][2]float64, n)
+	for i := range datapoints {
+		datapoints[i] = [2]float64{values[i], float64(start.Add(time.Duration(i) * interval).UnixMilli())}
+	}
+	return datapoints
+}
+
+// grafanaEventTable lists records as a table, at most limit of them when limit is positive.
+func grafanaEventTable(records []reader.Record, limit int) grafanaTable {
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	table := grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{Text: "Time", Type: "time"},
+			{Text: reader.FieldUsername, Type: "string"},
+			{Text: reader.FieldOperation, Type: "string"},
+			{Text: reader.FieldSize, Type: "number"},
+		},
+		Rows: make([][]interface{}, 0, len(records)),
+	}
+	for _, rec := range records {
+		table.Rows = append(table.Rows, []interface{}{rec.Time.UnixMilli(), rec.User, rec.Op, int64(rec.Bytes)})
+	}
+	return table
+}
+
+// decode decodes the JSON body of r into v, answering with an error when that fails or r is not a POST.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// quoteArg quotes s for an lf filter expression when it would otherwise not be read back as one argument.
+func quoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, h http.Handler, target, body string) (status int, respBody string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	b, err := io.ReadAll(rec.Result().Body)
+	assert.NoError(t, err)
+	return rec.Code, string(b)
+}
+
+func TestGrafanaHandler(t *testing.T) {
+	h := NewGrafanaHandler(newSource(input + "Wed Apr 15 23:30:00 UTC 2020,jeff 22,upload,5\n"))
+
+	t.Run("answers tests", func(t *testing.T) {
+		status, _, _ := get(t, h, "/")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("suggests targets", func(t *testing.T) {
+		status, body := post(t, h, "/search", `{"target": ""}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["count","size","users","count -u Maia86","count -u 'jeff 22'","count -u jeff22","count -u sarah94","count -op download","count -op upload"]`+"\n", body)
+
+		status, body = post(t, h, "/search", `{"target": "count -op"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `["count -op download","count -op upload"]`+"\n", body)
+	})
+
+	t.Run("buckets series", func(t *testing.T) {
+		status, body := post(t, h, "/query", `{
+			"range": {"from": "2020-04-12T00:00:00Z", "to": "2020-04-16T00:00:00Z"},
+			"intervalMs": 86400000,
+			"maxDataPoints": 100,
+			"targets": [
+				{"target": "-op download", "refId": "A", "type": "timeserie"},
+				{"target": "size -u jeff22", "refId": "B", "type": "timeserie"},
+				{"target": "users", "refId": "C", "type": "timeserie"}
+			]
+		}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[`+
+			`{"target":"-op download","datapoints":[[2,1586649600000],[0,1586736000000],[0,1586822400000],[0,1586908800000]]},`+
+			`{"target":"size -u jeff22","datapoints":[[0,1586649600000],[0,1586736000000],[0,1586822400000],[120000,1586908800000]]},`+
+			`{"target":"users","datapoints":[[2,1586649600000],[0,1586736000000],[0,1586822400000],[2,1586908800000]]}`+
+			`]`+"\n", body)
+	})
+
+	t.Run("narrows the expression's time range", func(t *testing.T) {
+		status, body := post(t, h, "/query", `{
+			"range": {"from": "2020-04-15T00:00:00Z", "to": "2020-04-15T12:00:00Z"},
+			"intervalMs": 21600000,
+			"targets": [{"target": "count --minTimestamp=2020-04-01", "type": "timeserie"}]
+		}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"target":"count --minTimestamp=2020-04-01","datapoints":[[1,1586908800000],[0,1586930400000]]}]`+"\n", body)
+	})
+
+	t.Run("widens intervals to maxDataPoints", func(t *testing.T) {
+		status, body := post(t, h, "/query", `{
+			"range": {"from": "2020-04-12T00:00:00Z", "to": "2020-04-16T00:00:00Z"},
+			"intervalMs": 1000,
+			"maxDataPoints": 2,
+			"targets": [{"target": "count"}]
+		}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"target":"count","datapoints":[[2,1586649600000],[3,1586822400000]]}]`+"\n", body)
+	})
+
+	t.Run("lists tables", func(t *testing.T) {
+		status, body := post(t, h, "/query", `{
+			"range": {"from": "2020-04-15T00:00:00Z", "to": "2020-04-16T00:00:00Z"},
+			"maxDataPoints": 2,
+			"targets": [{"target": "-op upload", "type": "table"}]
+		}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"type":"table","columns":[{"text":"Time","type":"time"},{"text":"username","type":"string"},{"text":"operation","type":"string"},{"text":"size","type":"number"}],`+
+			`"rows":[[1586920006000,"jeff22","upload",45000],[1586992706000,"jeff22","upload",75000]]}]`+"\n", body)
+	})
+
+	t.Run("annotates events", func(t *testing.T) {
+		status, body := post(t, h, "/annotations", `{
+			"range": {"from": "2020-04-15T00:00:00Z", "to": "2020-04-16T00:00:00Z"},
+			"annotation": {"name": "uploads", "query": "-u jeff22 --minSize=50"}
+		}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `[{"annotation":{"name":"uploads","query":"-u jeff22 --minSize=50"},"time":1586992706000,"title":"jeff22 upload","text":"75kB","tags":["jeff22","upload"]}]`+"\n", body)
+	})
+
+	for name, tt := range map[string]struct {
+		target string
+		body   string
+		status int
+	}{
+		"invalid body":        {target: "/query", body: `{`, status: http.StatusBadRequest},
+		"invalid range":       {target: "/query", body: `{"range": {"from": "2020-04-16T00:00:00Z", "to": "2020-04-15T00:00:00Z"}}`, status: http.StatusBadRequest},
+		"invalid target":      {target: "/query", body: `{"range": {"from": "2020-04-15T00:00:00Z", "to": "2020-04-16T00:00:00Z"}, "targets": [{"target": "count --colour=red"}]}`, status: http.StatusBadRequest},
+		"server side file":    {target: "/query", body: `{"range": {"from": "2020-04-15T00:00:00Z", "to": "2020-04-16T00:00:00Z"}, "targets": [{"target": "count --username-file=/etc/passwd"}]}`, status: http.StatusBadRequest},
+		"invalid annotation":  {target: "/annotations", body: `{"annotation": {"query": "-u 'jeff22"}}`, status: http.StatusBadRequest},
+		"get instead of post": {target: "/search", status: http.StatusMethodNotAllowed},
+	} {
+		t.Run("fails on "+name, func(t *testing.T) {
+			var status int
+			if tt.body == "" {
+				status, _, _ = get(t, h, tt.target)
+			} else {
+				status, _ = post(t, h, tt.target, tt.body)
+			}
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	metric, q, err := ParseTarget("size -u jeff22")
+	assert.NoError(t, err)
+	assert.Equal(t, MetricSize, metric)
+	assert.Equal(t, "jeff22", q.Username)
+
+	metric, q, err = ParseTarget("-op upload")
+	assert.NoError(t, err)
+	assert.Equal(t, MetricCount, metric)
+	assert.Equal(t, "upload", q.Operation)
+
+	_, _, err = ParseTarget("sizes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,359 @@
+// Package server exposes logfind queries as a JSON HTTP API.
+//
+// Every endpoint accepts the filter parameters of lf as URL query parameters, e.g.,
+// `/count?username=jeff22&op=upload&on=2020-04-15`. See query.FromValues.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/stats"
+	"github.com/kyleishie/logfind/pkg/logfind/writer/jsonl"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source opens the logs to query. It is called once per request so that every request sees the logs as they are
+// at that moment. The returned func releases whatever was opened.
+type Source func() (r reader.Reader, closeFn func(), err error)
+
+const (
+	ErrFileParamsUnsupported = logfind.Error("username-file and exclude-username-file are not supported")
+)
+
+// DefaultTimeout is the time a request may take unless changed with WithTimeout.
+const DefaultTimeout = 30 * time.Second
+
+// flushEvery is the number of events /find writes between flushes of the response.
+const flushEvery = 100
+
+type handler struct {
+	source  Source
+	timeout time.Duration
+	methods []string
+	mux     *http.ServeMux
+}
+
+// HandlerOptionFunc customizes a handler created by NewHandler or NewGrafanaHandler.
+type HandlerOptionFunc func(*handler)
+
+// WithTimeout limits the time a request may take. A request that runs out of time fails with
+// http.StatusGatewayTimeout, or, once /find has started streaming, ends early.
+func WithTimeout(timeout time.Duration) HandlerOptionFunc {
+	return func(h *handler) {
+		h.timeout = timeout
+	}
+}
+
+// NewHandler returns an http.Handler serving the events of source on the following endpoints:
+//
+//	GET /count      {"count": 3}, counting events, users or operations as chosen by the count parameter.
+//	GET /find       The matching events as JSON lines, see jsonl.Event, at most limit of them when given.
+//	GET /aggregate  {"by": "user", "groups": [{"name": "jeff22", "events": 3, "size": 151000}]}, grouped by user, operation, day or hour.
+//
+// Errors are reported as {"error": "..."} with a 4xx or 5xx status.
+func NewHandler(source Source, opts ...HandlerOptionFunc) http.Handler {
+	h := newHandler(source, []string{http.MethodGet, http.MethodHead}, opts)
+	h.mux.HandleFunc("/count", h.count)
+	h.mux.HandleFunc("/find", h.find)
+	h.mux.HandleFunc("/aggregate", h.aggregate)
+	return h
+}
+
+// newHandler returns a handler without endpoints that accepts the given methods.
+func newHandler(source Source, methods []string, opts []HandlerOptionFunc) *handler {
+	h := &handler{
+		source:  source,
+		timeout: DefaultTimeout,
+		methods: methods,
+		mux:     http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, method := range h.methods {
+		if r.Method == method {
+			h.mux.ServeHTTP(w, r)
+			return
+		}
+	}
+	w.Header().Set("Allow", strings.Join(h.methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+// CountResponse is the body of a successful /count request.
+type CountResponse struct {
+	Count int `json:"count"`
+	// Skipped is the number of malformed records skipped under the skip-and-report error policy.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+func (h *handler) count(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	cc := logfind.CountConcern(pop(values, "count", string(logfind.Event)))
+	switch cc {
+	case logfind.Event, logfind.Operation, logfind.User:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid count %q, expected event, operation or user", cc))
+		return
+	}
+
+	var diagnostics logfind.Diagnostics
+	opts, err := options(values, &diagnostics)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts = append(opts, logfind.WithCountConcern(cc))
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	rdr, closeFn, err := h.source.Open(ctx)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer closeFn()
+
+	count, _, err := logfind.NewFinder(rdr).Find(opts...)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CountResponse{Count: count, Skipped: diagnostics.Skipped})
+}
+
+func (h *handler) find(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	limit := -1
+	if v := pop(values, "limit", ""); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+	}
+
+	opts, err := options(values, nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	rdr, closeFn, err := h.source.Open(ctx)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer closeFn()
+
+	fr, err := logfind.NewFilterReader(rdr, opts...)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	// Read the first match before writing anything, so errors in the query or the first records still get a status.
+	e, err := fr.Read()
+	if err != nil && err != io.EOF {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	jw := jsonl.NewWriter(w)
+	for n := 0; err == nil && n != limit; n++ {
+		if err = jw.Write(e); err != nil {
+			break
+		}
+		if (n+1)%flushEvery == 0 {
+			if err = jw.Flush(); err != nil {
+				break
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		e, err = fr.Read()
+	}
+	if err != nil && err != io.EOF {
+		// The status has already been sent, so the failure is reported as the last line of the stream.
+		jw.Flush()
+		json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+		return
+	}
+	jw.Flush()
+}
+
+// Group is a group of events in an AggregateResponse.
+type Group struct {
+	Name   string `json:"name"`
+	Events int    `json:"events"`
+	// Size is in bytes.
+	Size int64 `json:"size"`
+}
+
+// AggregateResponse is the body of a successful /aggregate request.
+type AggregateResponse struct {
+	By     string  `json:"by"`
+	Groups []Group `json:"groups"`
+	// Skipped is the number of malformed records skipped under the skip-and-report error policy.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+func (h *handler) aggregate(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	by := pop(values, "by", "user")
+
+	var diagnostics logfind.Diagnostics
+	opts, err := options(values, &diagnostics)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	rdr, closeFn, err := h.source.Open(ctx)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer closeFn()
+
+	fr, err := logfind.NewFilterReader(rdr, opts...)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	ranked, err := stats.Group(fr, by)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	resp := AggregateResponse{
+		By:      by,
+		Groups:  make([]Group, 0, len(ranked)),
+		Skipped: diagnostics.Skipped,
+	}
+	for _, g := range ranked {
+		resp.Groups = append(resp.Groups, Group{Name: g.Name, Events: g.Events, Size: int64(g.Size)})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Open opens the source and returns a reader that stops with the error of ctx once ctx is done,
+// so that slow queries give up when they run out of time.
+func (s Source) Open(ctx context.Context) (reader.Reader, func(), error) {
+	r, closeFn, err := s()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &contextReader{ctx: ctx, r: r}, closeFn, nil
+}
+
+// contextReader fails with the error of ctx once it is done.
+type contextReader struct {
+	ctx context.Context
+	r   reader.Reader
+}
+
+func (c *contextReader) Read() (reader.Event, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.r.Read()
+}
+
+func (c *contextReader) Location() reader.Location {
+	if l, ok := c.r.(reader.Locator); ok {
+		return l.Location()
+	}
+	return reader.Location{}
+}
+
+// options translates the filter parameters in values into logfind.FinderOptionFuncs, collecting diagnostics into d
+// when it is not nil.
+func options(values url.Values, d *logfind.Diagnostics) ([]logfind.FinderOptionFunc, error) {
+	q, err := query.FromValues(values)
+	if err != nil {
+		return nil, err
+	}
+	return queryOptions(q, d)
+}
+
+// queryOptions translates q into logfind.FinderOptionFuncs like options. Queries naming files on the server,
+// e.g., with username-file, are refused.
+func queryOptions(q *query.Query, d *logfind.Diagnostics) ([]logfind.FinderOptionFunc, error) {
+	if q.UsernameFile != "" || q.ExcludeUsernameFile != "" {
+		return nil, ErrFileParamsUnsupported
+	}
+	opts, err := q.Options()
+	if err != nil {
+		return nil, err
+	}
+	if d != nil {
+		opts = append(opts, logfind.WithDiagnostics(d))
+	}
+	return opts, nil
+}
+
+// pop removes the parameter name from values and returns its value, or def when it is absent.
+func pop(values url.Values, name, def string) string {
+	if !values.Has(name) {
+		return def
+	}
+	value := values.Get(name)
+	values.Del(name)
+	return value
+}
+
+// statusOf maps an error that occurred while answering a request to an HTTP status.
+func statusOf(err error) int {
+	var lfErr logfind.Error
+	var recErr *logfind.RecordError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &recErr):
+		// Malformed logs are the server's fault, even when the record error wraps a sentinel of a reader.
+		return http.StatusInternalServerError
+	case errors.As(err, &lfErr):
+		// The sentinel errors of logfind describe invalid queries.
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/jsonl"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+Wed Apr 15 03:06:46 UTC 2020,jeff22,upload,45
+Wed Apr 15 23:18:26 UTC 2020,jeff22,upload,75
+`
+
+func newSource(input string) Source {
+	return func() (reader.Reader, func(), error) {
+		return csv.NewReader(strings.NewReader(input)), func() {}, nil
+	}
+}
+
+func get(t *testing.T, h http.Handler, target string) (status int, contentType, body string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	b, err := io.ReadAll(rec.Result().Body)
+	assert.NoError(t, err)
+	return rec.Code, rec.Header().Get("Content-Type"), string(b)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		target      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "counts events",
+			target:      "/count?u=jeff22&op=upload&on=2020-04-15",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"count":2}` + "\n",
+		},
+		{
+			name:        "counts users",
+			target:      "/count?count=user",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"count":3}` + "\n",
+		},
+		{
+			name:        "reports skipped records",
+			input:       input + "Wed Apr 15 23:30:00 UTC 2020,jeff22,upload,big\n",
+			target:      "/count?on-error=skip-and-report",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"count":4,"skipped":1}` + "\n",
+		},
+		{
+			name:        "finds events",
+			target:      "/find?operation=download",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body: `{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","operation":"download","size":34000}
+{"timestamp":"2020-04-12T22:35:06Z","username":"Maia86","operation":"download","size":75000}
+`,
+		},
+		{
+			name:        "limits found events",
+			target:      "/find?limit=1",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body:        `{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","operation":"download","size":34000}` + "\n",
+		},
+		{
+			name:        "reports errors after streaming started",
+			input:       input + "Wed Apr 15 23:30:00 UTC 2020,jeff22,upload,big\n",
+			target:      "/find?u=Maia86",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			body: `{"timestamp":"2020-04-12T22:35:06Z","username":"Maia86","operation":"download","size":75000}
+{"error":"6: size: invalid size \"big\": unknown unit \"big\""}
+`,
+		},
+		{
+			name:        "aggregates by day",
+			target:      "/aggregate?by=day&minSize=40",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"by":"day","groups":[{"name":"2020-04-12","events":1,"size":75000},{"name":"2020-04-15","events":2,"size":120000}]}` + "\n",
+		},
+		{
+			name:        "aggregates by user by default",
+			target:      "/aggregate",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"by":"user","groups":[{"name":"jeff22","events":2,"size":120000},{"name":"Maia86","events":1,"size":75000},{"name":"sarah94","events":1,"size":34000}]}` + "\n",
+		},
+		{
+			name:   "fails on unknown parameter",
+			target: "/count?colour=red",
+			status: http.StatusBadRequest,
+			body:   `{"error":"unknown parameter \"colour\""}` + "\n",
+		},
+		{
+			name:   "fails on invalid count",
+			target: "/count?count=bytes",
+			status: http.StatusBadRequest,
+			body:   `{"error":"invalid count \"bytes\", expected event, operation or user"}` + "\n",
+		},
+		{
+			name:   "fails on invalid limit",
+			target: "/find?limit=-1",
+			status: http.StatusBadRequest,
+			body:   `{"error":"invalid limit \"-1\""}` + "\n",
+		},
+		{
+			name:   "fails on invalid grouping",
+			target: "/aggregate?by=week",
+			status: http.StatusBadRequest,
+			body:   `{"error":"invalid grouping, expected user, operation, day or hour"}` + "\n",
+		},
+		{
+			name:   "fails on invalid time range",
+			target: "/find?minTimestamp=2020-04-16&maxTimestamp=2020-04-15",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "refuses server side files",
+			target: "/count?username-file=/etc/passwd",
+			status: http.StatusBadRequest,
+			body:   `{"error":"username-file and exclude-username-file are not supported"}` + "\n",
+		},
+		{
+			name:   "fails on malformed record",
+			input:  input + "Wed Apr 15 23:30:00 UTC 2020,jeff22,upload,big\n",
+			target: "/count",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "fails on unknown endpoint",
+			target: "/delete",
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.input
+			if in == "" {
+				in = input
+			}
+			status, contentType, body := get(t, NewHandler(newSource(in)), tt.target)
+			assert.Equal(t, tt.status, status)
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, contentType)
+			}
+			if tt.body != "" {
+				assert.Equal(t, tt.body, body)
+			}
+		})
+	}
+
+	t.Run("fails on record missing a field", func(t *testing.T) {
+		// The RecordError wraps a reader sentinel, which must not be mistaken for an invalid query.
+		source := func() (reader.Reader, func(), error) {
+			return jsonl.NewReader(strings.NewReader(`{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","size":34}`)), func() {}, nil
+		}
+		status, _, body := get(t, NewHandler(source), "/count?op=upload")
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Contains(t, body, "operation")
+	})
+
+	t.Run("rejects other methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewHandler(newSource(input)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/count", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		slow := func() (reader.Reader, func(), error) {
+			return &slowReader{r: csv.NewReader(strings.NewReader(input))}, func() {}, nil
+		}
+		status, _, body := get(t, NewHandler(slow, WithTimeout(5*time.Millisecond)), "/count")
+		assert.Equal(t, http.StatusGatewayTimeout, status)
+		assert.Equal(t, `{"error":"context deadline exceeded"}`+"\n", body)
+	})
+
+	t.Run("serves over http", func(t *testing.T) {
+		srv := httptest.NewServer(NewHandler(newSource(input)))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/count?u=jeff22")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"count":2}`+"\n", string(body))
+	})
+}
+
+// slowReader delays every read, simulating a large log.
+type slowReader struct {
+	r reader.Reader
+}
+
+func (s *slowReader) Read() (reader.Event, error) {
+	time.Sleep(5 * time.Millisecond)
+	return s.r.Read()
+}
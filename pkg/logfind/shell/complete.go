@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"sort"
+	"strings"
+)
+
+// valueFlags maps the flags whose values are completed to the values they complete with.
+var valueFlags = map[string]func(s *Shell) []string{
+	"u":         func(s *Shell) []string { return s.usernames },
+	"username":  func(s *Shell) []string { return s.usernames },
+	"op":        func(s *Shell) []string { return s.operations },
+	"operation": func(s *Shell) []string { return s.operations },
+}
+
+// commandArgs lists the fixed arguments of commands that take one.
+var commandArgs = map[string][]string{
+	"count": {"event", "user", "operation"},
+	"group": {"user", "operation", "day", "hour"},
+}
+
+// Complete returns the word being typed at the end of before along with the words it could be completed to.
+// Command names, field names, filter flags and the usernames and operations of the loaded log are completed.
+func (s *Shell) Complete(before string) (word string, candidates []string) {
+	fields := strings.Fields(before)
+	if len(fields) > 0 && !strings.HasSuffix(before, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	if len(fields) == 0 {
+		var names []string
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+		return word, withPrefix(names, word)
+	}
+
+	switch cmd := fields[0]; cmd {
+	case "filter":
+		return word, s.completeFilter(fields[len(fields)-1], word)
+	default:
+		if len(fields) == 1 {
+			return word, withPrefix(commandArgs[cmd], word)
+		}
+		return word, nil
+	}
+}
+
+func (s *Shell) completeFilter(previous, word string) []string {
+	// The value of a flag given as its own argument, e.g., -u jeff22.
+	if values, ok := valueFlags[strings.TrimLeft(previous, "-")]; ok && strings.HasPrefix(previous, "-") && !strings.Contains(previous, "=") {
+		return withPrefix(values(s), word)
+	}
+
+	if !strings.HasPrefix(word, "-") {
+		return nil
+	}
+
+	// The value of a flag given in the same argument, e.g., --username=jeff22.
+	if name, value, found := strings.Cut(word, "="); found {
+		values, ok := valueFlags[strings.TrimLeft(name, "-")]
+		if !ok {
+			return nil
+		}
+		var candidates []string
+		for _, v := range withPrefix(values(s), value) {
+			candidates = append(candidates, name+"="+v)
+		}
+		return candidates
+	}
+
+	var flags []string
+	for _, name := range query.FlagNames() {
+		if len(name) <= 2 {
+			flags = append(flags, "-"+name)
+		} else {
+			flags = append(flags, "--"+name)
+		}
+	}
+	return withPrefix(flags, word)
+}
+
+func withPrefix(words []string, prefix string) []string {
+	var matches []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			matches = append(matches, w)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
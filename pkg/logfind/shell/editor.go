@@ -0,0 +1,263 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxHistory is the number of lines kept by an Editor's history.
+const maxHistory = 1000
+
+// Editor reads command lines. When Interactive is set it expects a terminal in raw mode, see MakeRaw, and offers
+// line editing, history navigation with the arrow keys and tab completion. Otherwise it simply reads lines,
+// which suits input that is piped in.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	Prompt      string
+	Interactive bool
+
+	// Complete is called with the text before the cursor when tab is pressed. See Shell.Complete.
+	Complete func(before string) (word string, candidates []string)
+
+	history []string
+}
+
+// NewEditor returns an Editor that reads from in and echoes to out.
+func NewEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{
+		in:     bufio.NewReader(in),
+		out:    out,
+		Prompt: "lf> ",
+	}
+}
+
+// AddHistory appends line to the history unless it is empty or repeats the previous line.
+func (e *Editor) AddHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// LoadHistory adds the lines of the file at path to the history. A missing file is not an error.
+func (e *Editor) LoadHistory(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		e.AddHistory(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// SaveHistory writes the history to the file at path, one line per entry.
+func (e *Editor) SaveHistory(path string) error {
+	return os.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+}
+
+// ReadLine reads the next line without its line ending. It returns io.EOF once the input is exhausted or,
+// when interactive, Ctrl-D is pressed on an empty line.
+func (e *Editor) ReadLine() (string, error) {
+	if !e.Interactive {
+		line, err := e.in.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	return e.edit()
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	e       *Editor
+	buf     []rune
+	pos     int
+	histPos int
+	// pending holds the line being typed while browsing the history.
+	pending []rune
+}
+
+func (e *Editor) edit() (string, error) {
+	l := &lineState{e: e, histPos: len(e.history)}
+	l.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(l.buf), nil
+		case 0x03: // Ctrl-C abandons the line.
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", nil
+		case 0x04: // Ctrl-D ends the input on an empty line and deletes otherwise.
+			if len(l.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.delete()
+		case 0x7f, 0x08:
+			l.backspace()
+		case 0x01:
+			l.pos = 0
+		case 0x05:
+			l.pos = len(l.buf)
+		case 0x0b: // Ctrl-K kills to the end of the line.
+			l.buf = l.buf[:l.pos]
+		case 0x15: // Ctrl-U kills to the start of the line.
+			l.buf = l.buf[l.pos:]
+			l.pos = 0
+		case '\t':
+			l.complete()
+		case 0x1b:
+			l.escape()
+		default:
+			if unicode.IsPrint(r) {
+				l.insert(r)
+			}
+		}
+		l.refresh()
+	}
+}
+
+func (l *lineState) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(l.e.Prompt)
+	b.WriteString(string(l.buf))
+	b.WriteString("\x1b[K")
+	if back := len(l.buf) - l.pos; back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	fmt.Fprint(l.e.out, b.String())
+}
+
+func (l *lineState) insert(runes ...rune) {
+	buf := make([]rune, 0, len(l.buf)+len(runes))
+	buf = append(buf, l.buf[:l.pos]...)
+	buf = append(buf, runes...)
+	l.buf = append(buf, l.buf[l.pos:]...)
+	l.pos += len(runes)
+}
+
+func (l *lineState) backspace() {
+	if l.pos == 0 {
+		return
+	}
+	l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+	l.pos--
+}
+
+func (l *lineState) delete() {
+	if l.pos == len(l.buf) {
+		return
+	}
+	l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+}
+
+// escape handles the ANSI escape sequences sent by the arrow, home, end and delete keys.
+func (l *lineState) escape() {
+	next, _, err := l.e.in.ReadRune()
+	if err != nil || next != '[' && next != 'O' {
+		return
+	}
+	code, _, err := l.e.in.ReadRune()
+	if err != nil {
+		return
+	}
+	switch code {
+	case 'A':
+		l.browse(-1)
+	case 'B':
+		l.browse(1)
+	case 'C':
+		if l.pos < len(l.buf) {
+			l.pos++
+		}
+	case 'D':
+		if l.pos > 0 {
+			l.pos--
+		}
+	case 'H':
+		l.pos = 0
+	case 'F':
+		l.pos = len(l.buf)
+	case '3':
+		if tilde, _, err := l.e.in.ReadRune(); err == nil && tilde == '~' {
+			l.delete()
+		}
+	}
+}
+
+// browse moves delta entries through the history.
+func (l *lineState) browse(delta int) {
+	next := l.histPos + delta
+	if next < 0 || next > len(l.e.history) {
+		return
+	}
+	if l.histPos == len(l.e.history) {
+		l.pending = l.buf
+	}
+	l.histPos = next
+	if next == len(l.e.history) {
+		l.buf = l.pending
+	} else {
+		l.buf = []rune(l.e.history[next])
+	}
+	l.pos = len(l.buf)
+}
+
+func (l *lineState) complete() {
+	if l.e.Complete == nil {
+		return
+	}
+	word, candidates := l.e.Complete(string(l.buf[:l.pos]))
+	switch len(candidates) {
+	case 0:
+		fmt.Fprint(l.e.out, "\a")
+	case 1:
+		completion := strings.TrimPrefix(candidates[0], word)
+		if !strings.HasSuffix(candidates[0], "=") {
+			completion += " "
+		}
+		l.insert([]rune(completion)...)
+	default:
+		if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+			l.insert([]rune(strings.TrimPrefix(prefix, word))...)
+			return
+		}
+		fmt.Fprintf(l.e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
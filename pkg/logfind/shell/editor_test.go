@@ -0,0 +1,112 @@
+package shell
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditor_ReadLine(t *testing.T) {
+	t.Run("reads lines when not interactive", func(t *testing.T) {
+		e := NewEditor(strings.NewReader("count\r\nstats"), io.Discard)
+		line, err := e.ReadLine()
+		assert.NoError(t, err)
+		assert.Equal(t, "count", line)
+		line, err = e.ReadLine()
+		assert.NoError(t, err)
+		assert.Equal(t, "stats", line)
+		_, err = e.ReadLine()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "typing", input: "count\r", want: "count"},
+		{name: "backspace", input: "countx\x7f\r", want: "count"},
+		{name: "cursor movement", input: "cont\x1b[D\x1b[Du\r", want: "count"},
+		{name: "home and end", input: "ount\x01c\x05 user\r", want: "count user"},
+		{name: "kill line", input: "count user\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x0b\r", want: "count"},
+		{name: "delete key", input: "ccount\x01\x1b[3~\r", want: "count"},
+		{name: "history", input: "\x1b[A\x1b[A\r", want: "filter -u jeff22"},
+		{name: "history and back", input: "gr\x1b[A\x1b[B\r", want: "gr"},
+		{name: "unique completion", input: "gro\t\r", want: "group "},
+		{name: "common prefix completion", input: "filter -u M\t\r", want: "filter -u Maia8"},
+		{name: "ctrl-c", input: "count\x03", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEditor(strings.NewReader(tt.input), io.Discard)
+			e.Interactive = true
+			e.Complete = func(before string) (string, []string) {
+				fields := strings.Fields(before)
+				word := fields[len(fields)-1]
+				var candidates []string
+				for _, c := range []string{"group", "Maia86", "Maia87"} {
+					if strings.HasPrefix(c, word) {
+						candidates = append(candidates, c)
+					}
+				}
+				return word, candidates
+			}
+			e.AddHistory("filter -u jeff22")
+			e.AddHistory("count")
+
+			line, err := e.ReadLine()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, line)
+		})
+	}
+
+	t.Run("ctrl-d ends input on empty line", func(t *testing.T) {
+		e := NewEditor(strings.NewReader("\x04"), io.Discard)
+		e.Interactive = true
+		_, err := e.ReadLine()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("lists ambiguous completions", func(t *testing.T) {
+		var out bytes.Buffer
+		e := NewEditor(strings.NewReader("f\t\r"), &out)
+		e.Interactive = true
+		e.Complete = func(before string) (string, []string) {
+			return "f", []string{"filter", "find"}
+		}
+		line, err := e.ReadLine()
+		assert.NoError(t, err)
+		assert.Equal(t, "fi", line)
+
+		out.Reset()
+		e = NewEditor(strings.NewReader("fi\t\r"), &out)
+		e.Interactive = true
+		e.Complete = func(before string) (string, []string) {
+			return "fi", []string{"filter", "find"}
+		}
+		_, err = e.ReadLine()
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "\r\nfilter  find\r\n")
+	})
+}
+
+func TestEditor_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	assert.NoError(t, os.WriteFile(path, []byte("count\ncount\n\nstats\n"), 0o600))
+
+	e := NewEditor(strings.NewReader(""), io.Discard)
+	assert.NoError(t, e.LoadHistory(path))
+	assert.Equal(t, []string{"count", "stats"}, e.history)
+
+	e.AddHistory("group user")
+	assert.NoError(t, e.SaveHistory(path))
+	saved, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "count\nstats\ngroup user\n", string(saved))
+
+	assert.NoError(t, e.LoadHistory(filepath.Join(t.TempDir(), "missing")))
+}
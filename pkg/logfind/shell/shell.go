@@ -0,0 +1,300 @@
+// Package shell implements an interactive prompt for exploring a log that has been loaded into memory.
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/stats"
+	"github.com/kyleishie/logfind/pkg/logfind/writer"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ErrQuit is returned by Execute when the user asks to leave the shell.
+const ErrQuit = logfind.Error("quit")
+
+// defaultPageSize is the number of events printed by find and more unless told otherwise.
+const defaultPageSize = 20
+
+// Shell executes commands against a log held in memory. Filters added with the filter command apply to every
+// command that follows until they are removed, so an investigation can be narrowed down step by step.
+type Shell struct {
+	records []reader.Record
+	out     io.Writer
+
+	filters []filter
+
+	// page is the reader of the last find, kept so more can continue where it stopped.
+	page reader.Reader
+
+	usernames  []string
+	operations []string
+
+	history []string
+}
+
+type filter struct {
+	expr string
+	opts []logfind.FinderOptionFunc
+}
+
+// New returns a Shell over records that prints to out.
+func New(records []reader.Record, out io.Writer) *Shell {
+	s := &Shell{
+		records: records,
+		out:     out,
+	}
+
+	usernames := make(map[string]bool)
+	operations := make(map[string]bool)
+	for _, rec := range records {
+		usernames[rec.User] = true
+		operations[rec.Op] = true
+	}
+	s.usernames = sortedKeys(usernames)
+	s.operations = sortedKeys(operations)
+	return s
+}
+
+// command is a shell command. args is the rest of the line after the command name.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(s *Shell, args string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"filter", "<lf flags>", "Narrow down the events, e.g., filter -u jeff22 --on 2020-04-15.", (*Shell).filter},
+		{"filters", "", "List the active filters.", (*Shell).listFilters},
+		{"undo", "", "Remove the most recent filter.", (*Shell).undo},
+		{"clear", "", "Remove every filter.", (*Shell).clear},
+		{"count", "[event|user|operation]", "Count the matching events, users or operations.", (*Shell).count},
+		{"find", "[n]", "Print the first n matching events.", (*Shell).find},
+		{"more", "[n]", "Print the next n matching events of the last find.", (*Shell).more},
+		{"group", "<user|operation|day|hour>", "Count the matching events and their size by group.", (*Shell).group},
+		{"stats", "", "Summarize the matching events.", (*Shell).stats},
+		{"history", "", "List the commands entered so far.", (*Shell).listHistory},
+		{"help", "", "List the commands.", (*Shell).help},
+		{"quit", "", "Leave the shell.", (*Shell).quit},
+	}
+}
+
+// Execute runs a single command line. It returns ErrQuit when the line asks to leave the shell.
+func (s *Shell) Execute(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	s.history = append(s.history, line)
+
+	name, args, _ := strings.Cut(line, " ")
+	if name == "exit" {
+		name = "quit"
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(s, strings.TrimSpace(args))
+		}
+	}
+	return fmt.Errorf("unknown command %q, type help for a list of commands", name)
+}
+
+// Run reads command lines from e and executes them until the user quits or e returns io.EOF.
+// Errors of individual commands are printed rather than returned.
+func (s *Shell) Run(e *Editor) error {
+	e.Complete = s.Complete
+	for {
+		line, err := e.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e.AddHistory(line)
+
+		err = s.Execute(line)
+		if errors.Is(err, ErrQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %s\n", err)
+		}
+	}
+}
+
+// matching returns a reader over the records that satisfy every filter.
+func (s *Shell) matching() reader.Reader {
+	r := reader.NewSliceReader(s.records)
+	for _, f := range s.filters {
+		// The options were validated when the filter was added.
+		r, _ = logfind.NewFilterReader(r, f.opts...)
+	}
+	return r
+}
+
+func (s *Shell) filter(args string) error {
+	if args == "" {
+		return errors.New("usage: filter <lf flags>, e.g., filter -u jeff22 --on 2020-04-15")
+	}
+	q, err := query.ParseExpression(args)
+	if err != nil {
+		return err
+	}
+	opts, err := q.Options()
+	if err != nil {
+		return err
+	}
+	if _, err = logfind.NewFilterReader(nil, opts...); err != nil {
+		return err
+	}
+	s.filters = append(s.filters, filter{expr: args, opts: opts})
+	s.page = nil
+	return s.count("")
+}
+
+func (s *Shell) listFilters(string) error {
+	if len(s.filters) == 0 {
+		fmt.Fprintln(s.out, "no filters, every event matches")
+	}
+	for i, f := range s.filters {
+		fmt.Fprintf(s.out, "%d. %s\n", i+1, f.expr)
+	}
+	return nil
+}
+
+func (s *Shell) undo(string) error {
+	if len(s.filters) == 0 {
+		return errors.New("no filters to undo")
+	}
+	s.filters = s.filters[:len(s.filters)-1]
+	s.page = nil
+	return s.count("")
+}
+
+func (s *Shell) clear(string) error {
+	s.filters = nil
+	s.page = nil
+	return s.count("")
+}
+
+func (s *Shell) count(args string) error {
+	cc := logfind.Event
+	if args != "" {
+		cc = logfind.CountConcern(args)
+	}
+	switch cc {
+	case logfind.Event, logfind.User, logfind.Operation:
+	default:
+		return fmt.Errorf("cannot count %q, expected event, user or operation", args)
+	}
+
+	count, _, err := logfind.NewFinder(s.matching()).Find(logfind.WithCountConcern(cc))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "count: %d\n", count)
+	return nil
+}
+
+func (s *Shell) find(args string) error {
+	s.page = s.matching()
+	return s.more(args)
+}
+
+func (s *Shell) more(args string) error {
+	if s.page == nil {
+		return errors.New("nothing to show, use find first")
+	}
+
+	n := defaultPageSize
+	if args != "" {
+		var err error
+		if n, err = strconv.Atoi(args); err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of events %q", args)
+		}
+	}
+
+	w := writer.NewTextWriter(s.out)
+	for i := 0; i < n; i++ {
+		e, err := s.page.Read()
+		if err == io.EOF {
+			s.page = nil
+			if err = w.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintln(s.out, "(end)")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = w.Write(e); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (s *Shell) group(args string) error {
+	ranked, err := stats.Group(s.matching(), args)
+	if errors.Is(err, stats.ErrGroupingInvalid) {
+		return fmt.Errorf("cannot group by %q, expected user, operation, day or hour", args)
+	}
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tevents\tsize\n", args)
+	for _, g := range ranked {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", g.Name, g.Events, g.Size)
+	}
+	return tw.Flush()
+}
+
+func (s *Shell) stats(string) error {
+	summary, err := stats.Summarize(s.matching())
+	if err != nil {
+		return err
+	}
+	return summary.Fprint(s.out, 10)
+}
+
+func (s *Shell) listHistory(string) error {
+	for i, line := range s.history {
+		fmt.Fprintf(s.out, "%4d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func (s *Shell) help(string) error {
+	tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "%s %s\t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	return tw.Flush()
+}
+
+func (s *Shell) quit(string) error {
+	return ErrQuit
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package shell
+
+import (
+	"bytes"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestShell() (*Shell, *bytes.Buffer) {
+	var out bytes.Buffer
+	return New([]reader.Record{
+		{Time: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), User: "sarah94", Op: "download", Bytes: 34 * reader.KB},
+		{Time: time.Date(2020, 04, 12, 22, 35, 06, 0, time.UTC), User: "Maia86", Op: "download", Bytes: 75 * reader.KB},
+		{Time: time.Date(2020, 04, 15, 03, 06, 46, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 45 * reader.KB},
+		{Time: time.Date(2020, 04, 15, 23, 18, 26, 0, time.UTC), User: "jeff22", Op: "upload", Bytes: 75 * reader.KB},
+	}, &out), &out
+}
+
+func TestShell_Execute(t *testing.T) {
+	t.Run("filters incrementally", func(t *testing.T) {
+		s, out := newTestShell()
+		assert.NoError(t, s.Execute("count user"))
+		assert.NoError(t, s.Execute("filter -op upload"))
+		assert.NoError(t, s.Execute("filter --on 2020-04-15 -u jeff22"))
+		assert.NoError(t, s.Execute("filters"))
+		assert.NoError(t, s.Execute("undo"))
+		assert.NoError(t, s.Execute("clear"))
+		assert.Equal(t, "count: 3\ncount: 2\ncount: 2\n1. -op upload\n2. --on 2020-04-15 -u jeff22\ncount: 2\ncount: 4\n", out.String())
+	})
+
+	t.Run("pages through events", func(t *testing.T) {
+		s, out := newTestShell()
+		assert.NoError(t, s.Execute("find 3"))
+		assert.NoError(t, s.Execute("more"))
+		assert.Equal(t, `Sun Apr 12 22:10:38 UTC 2020 sarah94 download 34kB
+Sun Apr 12 22:35:06 UTC 2020 Maia86 download 75kB
+Wed Apr 15 03:06:46 UTC 2020 jeff22 upload 45kB
+Wed Apr 15 23:18:26 UTC 2020 jeff22 upload 75kB
+(end)
+`, out.String())
+		assert.Error(t, s.Execute("more"))
+	})
+
+	t.Run("groups events", func(t *testing.T) {
+		s, out := newTestShell()
+		assert.NoError(t, s.Execute("group day"))
+		assert.Equal(t, "day         events  size\n2020-04-12  2       109kB\n2020-04-15  2       120kB\n", out.String())
+	})
+
+	t.Run("records history", func(t *testing.T) {
+		s, out := newTestShell()
+		assert.NoError(t, s.Execute("count"))
+		assert.NoError(t, s.Execute("history"))
+		assert.Equal(t, "count: 4\n   1  count\n   2  history\n", out.String())
+	})
+
+	t.Run("quits", func(t *testing.T) {
+		s, _ := newTestShell()
+		assert.ErrorIs(t, s.Execute("quit"), ErrQuit)
+		assert.ErrorIs(t, s.Execute("exit"), ErrQuit)
+	})
+
+	for _, line := range []string{"frobnicate", "filter", "filter --colour=red", "filter -u regex:(", "count things", "group week", "find lots", "undo"} {
+		t.Run("fails on "+line, func(t *testing.T) {
+			s, _ := newTestShell()
+			assert.Error(t, s.Execute(line))
+		})
+	}
+}
+
+func TestShell_Run(t *testing.T) {
+	s, out := newTestShell()
+	e := NewEditor(strings.NewReader("filter -u jeff22\nbogus\ncount\nquit\ncount\n"), out)
+	assert.NoError(t, s.Run(e))
+	assert.Equal(t, "count: 2\nerror: unknown command \"bogus\", type help for a list of commands\ncount: 2\n", out.String())
+}
+
+func TestShell_Complete(t *testing.T) {
+	tests := []struct {
+		before     string
+		word       string
+		candidates []string
+	}{
+		{before: "", word: "", candidates: []string{"clear", "count", "filter", "filters", "find", "group", "help", "history", "more", "quit", "stats", "undo"}},
+		{before: "fi", word: "fi", candidates: []string{"filter", "filters", "find"}},
+		{before: "group ", word: "", candidates: []string{"day", "hour", "operation", "user"}},
+		{before: "count u", word: "u", candidates: []string{"user"}},
+		{before: "filter -u ", word: "", candidates: []string{"Maia86", "jeff22", "sarah94"}},
+		{before: "filter -u j", word: "j", candidates: []string{"jeff22"}},
+		{before: "filter --operation=d", word: "--operation=d", candidates: []string{"--operation=download"}},
+		{before: "filter --use", word: "--use", candidates: []string{"--username", "--username-file"}},
+		{before: "filter -o", word: "-o", candidates: []string{"-on", "-op"}},
+		{before: "filter jeff", word: "jeff", candidates: nil},
+		{before: "stats x", word: "x", candidates: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.before, func(t *testing.T) {
+			s, _ := newTestShell()
+			word, candidates := s.Complete(tt.before)
+			assert.Equal(t, tt.word, word)
+			assert.Equal(t, tt.candidates, candidates)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package shell
+
+import "github.com/kyleishie/logfind/pkg/logfind"
+
+// ErrRawModeUnsupported is returned by MakeRaw on platforms where raw mode has not been implemented.
+const ErrRawModeUnsupported = logfind.Error("raw terminal mode unsupported")
+
+// MakeRaw is only implemented on Linux. Elsewhere it fails, so the Editor falls back to reading whole lines.
+func MakeRaw(fd int) (restore func() error, err error) {
+	return nil, ErrRawModeUnsupported
+}
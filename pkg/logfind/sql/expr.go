@@ -0,0 +1,408 @@
+package sql
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/query"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is the value of a column or expression: nil, a string, int64, float64, bool, time.Time or reader.Size.
+type Value interface{}
+
+// FormatValue formats v for display. Timestamps are formatted as RFC 3339 and sizes as a number of bytes.
+func FormatValue(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case reader.Size:
+		return strconv.FormatInt(int64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Columns are the columns of the log table.
+var Columns = []string{reader.FieldTimestamp, reader.FieldUsername, reader.FieldOperation, reader.FieldSize}
+
+// env resolves the columns and aggregates of an expression.
+type env interface {
+	column(name string) Value
+	aggregate(a *aggregate) (Value, error)
+}
+
+type expr interface {
+	eval(env env) (Value, error)
+	children() []expr
+	// String returns the canonical text of the expression, used to name result columns
+	// and to match expressions against the GROUP BY clause.
+	String() string
+}
+
+type literal struct {
+	value Value
+	text  string
+}
+
+func (l *literal) eval(env) (Value, error) { return l.value, nil }
+func (l *literal) children() []expr        { return nil }
+func (l *literal) String() string          { return l.text }
+
+type columnRef struct {
+	name string
+}
+
+func (c *columnRef) eval(env env) (Value, error) { return env.column(c.name), nil }
+func (c *columnRef) children() []expr            { return nil }
+func (c *columnRef) String() string              { return c.name }
+
+// call is a scalar function call.
+type call struct {
+	fn  string
+	arg expr
+}
+
+// scalarFuncs are the scalar functions that may be called.
+var scalarFuncs = map[string]func(Value) (Value, error){
+	"DATE": func(v Value) (Value, error) {
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("DATE expects a timestamp, got %s", FormatValue(v))
+		}
+		return t.Format("2006-01-02"), nil
+	},
+	"HOUR": func(v Value) (Value, error) {
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("HOUR expects a timestamp, got %s", FormatValue(v))
+		}
+		return int64(t.Hour()), nil
+	},
+	"LOWER": func(v Value) (Value, error) {
+		return strings.ToLower(FormatValue(v)), nil
+	},
+	"UPPER": func(v Value) (Value, error) {
+		return strings.ToUpper(FormatValue(v)), nil
+	},
+}
+
+func (c *call) eval(env env) (Value, error) {
+	v, err := c.arg.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return scalarFuncs[c.fn](v)
+}
+
+func (c *call) children() []expr { return []expr{c.arg} }
+func (c *call) String() string   { return c.fn + "(" + c.arg.String() + ")" }
+
+// aggregate is an aggregate function call. Its arg is nil for COUNT(*).
+type aggregate struct {
+	fn       string
+	arg      expr
+	distinct bool
+}
+
+var aggregateFuncs = map[string]bool{"COUNT": true, "SUM": true, "MIN": true, "MAX": true, "AVG": true}
+
+func (a *aggregate) eval(env env) (Value, error) { return env.aggregate(a) }
+func (a *aggregate) children() []expr            { return nil }
+
+func (a *aggregate) String() string {
+	switch {
+	case a.arg == nil:
+		return a.fn + "(*)"
+	case a.distinct:
+		return a.fn + "(DISTINCT " + a.arg.String() + ")"
+	default:
+		return a.fn + "(" + a.arg.String() + ")"
+	}
+}
+
+type not struct {
+	x expr
+}
+
+func (n *not) eval(env env) (Value, error) {
+	b, err := evalBool(n.x, env)
+	return !b, err
+}
+
+func (n *not) children() []expr { return []expr{n.x} }
+func (n *not) String() string   { return "NOT " + n.x.String() }
+
+// logical is AND or OR.
+type logical struct {
+	op   string
+	l, r expr
+}
+
+func (l *logical) eval(env env) (Value, error) {
+	left, err := evalBool(l.l, env)
+	if err != nil {
+		return nil, err
+	}
+	if (l.op == "AND" && !left) || (l.op == "OR" && left) {
+		return left, nil
+	}
+	return evalBool(l.r, env)
+}
+
+func (l *logical) children() []expr { return []expr{l.l, l.r} }
+func (l *logical) String() string   { return "(" + l.l.String() + " " + l.op + " " + l.r.String() + ")" }
+
+type comparison struct {
+	op   string
+	l, r expr
+}
+
+func (c *comparison) eval(env env) (Value, error) {
+	left, err := c.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.r.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+	n, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch c.op {
+	case "=":
+		return n == 0, nil
+	case "!=", "<>":
+		return n != 0, nil
+	case "<":
+		return n < 0, nil
+	case "<=":
+		return n <= 0, nil
+	case ">":
+		return n > 0, nil
+	default:
+		return n >= 0, nil
+	}
+}
+
+func (c *comparison) children() []expr { return []expr{c.l, c.r} }
+func (c *comparison) String() string   { return c.l.String() + " " + c.op + " " + c.r.String() }
+
+type like struct {
+	x       expr
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+}
+
+// newLike compiles pattern, in which % matches any run of characters and _ a single character.
+func newLike(x expr, pattern string, negate bool) *like {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return &like{x: x, pattern: pattern, re: regexp.MustCompile(b.String()), negate: negate}
+}
+
+func (l *like) eval(env env) (Value, error) {
+	v, err := l.x.eval(env)
+	if err != nil || v == nil {
+		return false, err
+	}
+	return l.re.MatchString(FormatValue(v)) != l.negate, nil
+}
+
+func (l *like) children() []expr { return []expr{l.x} }
+
+func (l *like) String() string {
+	op := " LIKE "
+	if l.negate {
+		op = " NOT LIKE "
+	}
+	return l.x.String() + op + quote(l.pattern)
+}
+
+type in struct {
+	x      expr
+	list   []expr
+	negate bool
+}
+
+func (i *in) eval(env env) (Value, error) {
+	v, err := i.x.eval(env)
+	if err != nil || v == nil {
+		return false, err
+	}
+	for _, e := range i.list {
+		item, err := e.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := compare(v, item); err != nil {
+			return nil, err
+		} else if n == 0 {
+			return !i.negate, nil
+		}
+	}
+	return i.negate, nil
+}
+
+func (i *in) children() []expr { return append([]expr{i.x}, i.list...) }
+
+func (i *in) String() string {
+	items := make([]string, len(i.list))
+	for n, e := range i.list {
+		items[n] = e.String()
+	}
+	op := " IN ("
+	if i.negate {
+		op = " NOT IN ("
+	}
+	return i.x.String() + op + strings.Join(items, ", ") + ")"
+}
+
+type between struct {
+	x, lo, hi expr
+	negate    bool
+}
+
+func (b *between) eval(env env) (Value, error) {
+	lower, err := (&comparison{op: ">=", l: b.x, r: b.lo}).eval(env)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := (&comparison{op: "<=", l: b.x, r: b.hi}).eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return (lower.(bool) && upper.(bool)) != b.negate, nil
+}
+
+func (b *between) children() []expr { return []expr{b.x, b.lo, b.hi} }
+
+func (b *between) String() string {
+	op := " BETWEEN "
+	if b.negate {
+		op = " NOT BETWEEN "
+	}
+	return b.x.String() + op + b.lo.String() + " AND " + b.hi.String()
+}
+
+func evalBool(e expr, env env) (bool, error) {
+	v, err := e.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s is not a condition", e)
+	}
+	return b, nil
+}
+
+// compare orders a and b, converting strings to timestamps or sizes when compared with one.
+func compare(a, b Value) (int, error) {
+	var err error
+	if a, b, err = coerce(a, b); err != nil {
+		return 0, err
+	}
+	if b, a, err = coerce(b, a); err != nil {
+		return 0, err
+	}
+
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			switch {
+			case a.Before(b):
+				return -1, nil
+			case a.After(b):
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	case bool:
+		if b, ok := b.(bool); ok && a == b {
+			return 0, nil
+		} else if ok {
+			return 1, nil
+		}
+	case int64, float64, reader.Size:
+		x, _ := number(a)
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", FormatValue(a), FormatValue(b))
+}
+
+// coerce converts b to the type of a when a is a timestamp or size and b is a string.
+func coerce(a, b Value) (Value, Value, error) {
+	s, ok := b.(string)
+	if !ok {
+		return a, b, nil
+	}
+	switch a.(type) {
+	case time.Time:
+		t, err := query.ParseTimestamp(s, time.UTC)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timestamp %q", s)
+		}
+		return a, t, nil
+	case reader.Size:
+		size, err := reader.ParseSize(s, reader.Byte)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid size %q", s)
+		}
+		return a, size, nil
+	}
+	return a, b, nil
+}
+
+func number(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case reader.Size:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
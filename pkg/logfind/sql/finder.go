@@ -0,0 +1,43 @@
+package sql
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"strings"
+)
+
+type finder struct {
+	r    reader.Reader
+	stmt *Statement
+}
+
+// NewFinder returns a logfind.Finder that runs the SELECT statement query over the events of r.
+// Find reports the number of result rows and each row as its values separated by tabs, see FormatValue.
+func NewFinder(r reader.Reader, query string) (logfind.Finder, error) {
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return &finder{r: r, stmt: stmt}, nil
+}
+
+// Find runs the statement over the events that satisfy opts, which apply before the WHERE clause.
+func (f *finder) Find(opts ...logfind.FinderOptionFunc) (count int, events []string, err error) {
+	fr, err := logfind.NewFilterReader(f.r, opts...)
+	if err != nil {
+		return
+	}
+	result, err := f.stmt.Execute(fr)
+	if err != nil {
+		return
+	}
+
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			values[i] = FormatValue(v)
+		}
+		events = append(events, strings.Join(values, "\t"))
+	}
+	return len(events), events, nil
+}
@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenIdent is an identifier or keyword, e.g., username or SELECT.
+	tokenIdent
+	tokenString
+	// tokenNumber is a number, optionally followed by a size unit, e.g., 10 or 50kB.
+	tokenNumber
+	// tokenOp is an operator or punctuation, e.g., <=, ( or *.
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// is reports whether t is the keyword or operator s, ignoring case.
+func (t token) is(s string) bool {
+	return (t.kind == tokenIdent || t.kind == tokenOp) && strings.EqualFold(t.value, s)
+}
+
+// operators are sorted so that longer operators are tried before their prefixes.
+var operators = []string{"<>", "<=", ">=", "!=", "=", "<", ">", "(", ")", ",", "*", ";"}
+
+// lex splits query into tokens.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			// Strings are quoted with single quotes, which are escaped by doubling them.
+			var b strings.Builder
+			end := i + 1
+			for {
+				if end >= len(query) {
+					return nil, syntaxError(i, "unterminated string")
+				}
+				if query[end] == '\'' {
+					if end+1 < len(query) && query[end+1] == '\'' {
+						b.WriteByte('\'')
+						end += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(query[end])
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: i})
+			i = end + 1
+		case isDigit(c):
+			end := i
+			for end < len(query) && (isDigit(query[end]) || query[end] == '.') {
+				end++
+			}
+			for end < len(query) && isLetter(query[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: query[i:end], pos: i})
+			i = end
+		case isLetter(c) || c == '_':
+			end := i
+			for end < len(query) && (isLetter(query[end]) || isDigit(query[end]) || query[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: query[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(query[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, syntaxError(i, fmt.Sprintf("unexpected %q", c))
+			}
+			tokens = append(tokens, token{kind: tokenOp, value: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func syntaxError(pos int, msg string) error {
+	return fmt.Errorf("%w at position %d: %s", ErrSyntax, pos, msg)
+}
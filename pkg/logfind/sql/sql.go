@@ -0,0 +1,757 @@
+// Package sql queries log streams with a subset of SQL. A log stream is a table, named in the FROM clause, whose
+// columns are timestamp, username, operation and size, e.g.,
+//
+//	SELECT username, COUNT(*), SUM(size) FROM log
+//	WHERE operation = 'upload' AND timestamp >= '2020-04-15'
+//	GROUP BY username ORDER BY 2 DESC LIMIT 10
+//
+// Sizes are in bytes. Size literals may carry a unit, e.g., size > 50kB, and strings compared with timestamps or
+// sizes are parsed as such. Conditions support =, != or <>, <, <=, >, >=, [NOT] LIKE, [NOT] IN, [NOT] BETWEEN,
+// AND, OR and NOT. The aggregates COUNT, SUM, MIN, MAX and AVG and the functions DATE, HOUR, LOWER and UPPER
+// are available.
+package sql
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrSyntax = logfind.Error("syntax error")
+)
+
+// Statement is a parsed SELECT statement.
+type Statement struct {
+	// Table is the name given in the FROM clause. It is informational, since the table is the stream being queried.
+	Table string
+
+	columns []resultColumn
+	where   expr
+	groupBy []expr
+	having  expr
+	orderBy []orderItem
+	limit   int
+	offset  int
+
+	// grouped is set when rows are grouped, i.e., there is a GROUP BY clause or an aggregate.
+	grouped bool
+}
+
+type resultColumn struct {
+	expr expr
+	name string
+}
+
+type orderItem struct {
+	expr expr
+	// index is the result column ordered by, or -1 to evaluate expr.
+	index int
+	desc  bool
+}
+
+// Result holds the rows of a Statement.
+type Result struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+// Parse parses a SELECT statement.
+func Parse(query string) (*Statement, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.statement()
+}
+
+// Execute runs s over the events of r. Any error of r, other than io.EOF, stops the execution,
+// so wrap r with logfind.NewFilterReader to skip malformed records.
+func (s *Statement) Execute(r reader.Reader) (*Result, error) {
+	var records []reader.Record
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := reader.NewRecord(e)
+		if err != nil {
+			return nil, err
+		}
+		if s.where != nil {
+			match, err := evalBool(s.where, rowEnv(rec))
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+		}
+		records = append(records, rec)
+	}
+
+	groups, err := s.group(records)
+	if err != nil {
+		return nil, err
+	}
+
+	type row struct {
+		values []Value
+		env    *groupEnv
+	}
+	var rows []row
+	for _, g := range groups {
+		if s.having != nil {
+			match, err := evalBool(s.having, g)
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+		}
+		values := make([]Value, len(s.columns))
+		for i, col := range s.columns {
+			if values[i], err = col.expr.eval(g); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row{values: values, env: g})
+	}
+
+	if len(s.orderBy) > 0 {
+		var sortErr error
+		key := func(r row, item orderItem) Value {
+			if item.index >= 0 {
+				return r.values[item.index]
+			}
+			v, err := item.expr.eval(r.env)
+			if err != nil && sortErr == nil {
+				sortErr = err
+			}
+			return v
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, item := range s.orderBy {
+				n, err := compareNullsFirst(key(rows[i], item), key(rows[j], item))
+				if err != nil && sortErr == nil {
+					sortErr = err
+				}
+				if n != 0 {
+					return (n < 0) != item.desc
+				}
+			}
+			return false
+		})
+		if sortErr != nil {
+			return nil, sortErr
+		}
+	}
+
+	if s.offset >= len(rows) {
+		rows = nil
+	} else {
+		rows = rows[s.offset:]
+	}
+	if s.limit >= 0 && s.limit < len(rows) {
+		rows = rows[:s.limit]
+	}
+
+	result := &Result{Rows: make([][]Value, len(rows))}
+	for _, col := range s.columns {
+		result.Columns = append(result.Columns, col.name)
+	}
+	for i, r := range rows {
+		result.Rows[i] = r.values
+	}
+	return result, nil
+}
+
+// group splits records into the groups of s. Ungrouped statements have a group per record, and grouped statements
+// without GROUP BY a single group, even when there are no records.
+func (s *Statement) group(records []reader.Record) ([]*groupEnv, error) {
+	if !s.grouped {
+		groups := make([]*groupEnv, len(records))
+		for i := range records {
+			groups[i] = &groupEnv{records: records[i : i+1]}
+		}
+		return groups, nil
+	}
+	if len(s.groupBy) == 0 {
+		return []*groupEnv{{records: records}}, nil
+	}
+
+	var groups []*groupEnv
+	index := make(map[string]*groupEnv)
+	for _, rec := range records {
+		var key strings.Builder
+		for _, e := range s.groupBy {
+			v, err := e.eval(rowEnv(rec))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&key, "%T:%s\x00", v, FormatValue(v))
+		}
+		g, ok := index[key.String()]
+		if !ok {
+			g = &groupEnv{}
+			index[key.String()] = g
+			groups = append(groups, g)
+		}
+		g.records = append(g.records, rec)
+	}
+	return groups, nil
+}
+
+// compareNullsFirst compares like compare but orders NULL before any other value.
+func compareNullsFirst(a, b Value) (int, error) {
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return -1, nil
+	case b == nil:
+		return 1, nil
+	}
+	return compare(a, b)
+}
+
+// rowEnv resolves columns from a single record, outside of any group.
+type rowEnv reader.Record
+
+func (r rowEnv) column(name string) Value {
+	switch name {
+	case reader.FieldTimestamp:
+		return r.Time
+	case reader.FieldUsername:
+		return r.User
+	case reader.FieldOperation:
+		return r.Op
+	default:
+		return r.Bytes
+	}
+}
+
+func (r rowEnv) aggregate(a *aggregate) (Value, error) {
+	return nil, fmt.Errorf("aggregate %s is not allowed here", a)
+}
+
+// groupEnv resolves columns from the first record of a group, whose grouped columns all records share,
+// and aggregates over every record of the group.
+type groupEnv struct {
+	records []reader.Record
+}
+
+func (g *groupEnv) column(name string) Value {
+	if len(g.records) == 0 {
+		return nil
+	}
+	return rowEnv(g.records[0]).column(name)
+}
+
+func (g *groupEnv) aggregate(a *aggregate) (Value, error) {
+	if a.arg == nil {
+		return int64(len(g.records)), nil
+	}
+
+	var values []Value
+	seen := make(map[string]bool)
+	for _, rec := range g.records {
+		v, err := a.arg.eval(rowEnv(rec))
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if a.distinct {
+			key := fmt.Sprintf("%T:%s", v, FormatValue(v))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		values = append(values, v)
+	}
+
+	switch a.fn {
+	case "COUNT":
+		return int64(len(values)), nil
+	case "MIN", "MAX":
+		var best Value
+		for _, v := range values {
+			if best == nil {
+				best = v
+				continue
+			}
+			n, err := compare(v, best)
+			if err != nil {
+				return nil, err
+			}
+			if (a.fn == "MIN" && n < 0) || (a.fn == "MAX" && n > 0) {
+				best = v
+			}
+		}
+		return best, nil
+	}
+
+	// SUM and AVG
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var sum float64
+	for _, v := range values {
+		n, ok := number(v)
+		if !ok {
+			return nil, fmt.Errorf("%s expects numbers, got %s", a.fn, FormatValue(v))
+		}
+		sum += n
+	}
+	if a.fn == "AVG" {
+		sum /= float64(len(values))
+	}
+	switch values[0].(type) {
+	case reader.Size:
+		return reader.Size(sum), nil
+	case int64:
+		if a.fn == "SUM" {
+			return int64(sum), nil
+		}
+	}
+	return sum, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+
+	// aggregates is set while parsing clauses that may hold aggregates.
+	aggregates bool
+	// sawAggregate is set once an aggregate has been parsed.
+	sawAggregate bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token when it is the keyword or operator s.
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.unexpected(fmt.Sprintf("expected %s", s))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(msg string) error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return syntaxError(t.pos, msg+", got end of query")
+	}
+	return syntaxError(t.pos, fmt.Sprintf("%s, got %q", msg, t.value))
+}
+
+func (p *parser) statement() (s *Statement, err error) {
+	s = &Statement{limit: -1}
+	if err = p.expect("SELECT"); err != nil {
+		return
+	}
+
+	p.aggregates = true
+	if p.accept("*") {
+		for _, name := range Columns {
+			s.columns = append(s.columns, resultColumn{expr: &columnRef{name: name}, name: name})
+		}
+	} else {
+		for {
+			var col resultColumn
+			if col.expr, err = p.expr(); err != nil {
+				return
+			}
+			col.name = col.expr.String()
+			if p.accept("AS") {
+				alias := p.next()
+				if alias.kind != tokenIdent && alias.kind != tokenString {
+					return nil, syntaxError(alias.pos, "expected an alias")
+				}
+				col.name = alias.value
+			}
+			s.columns = append(s.columns, col)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if err = p.expect("FROM"); err != nil {
+		return
+	}
+	table := p.next()
+	if table.kind != tokenIdent {
+		return nil, syntaxError(table.pos, "expected a table name")
+	}
+	s.Table = table.value
+
+	p.aggregates = false
+	if p.accept("WHERE") {
+		if s.where, err = p.expr(); err != nil {
+			return
+		}
+	}
+	if p.accept("GROUP") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		for {
+			var e expr
+			if e, err = p.expr(); err != nil {
+				return
+			}
+			s.groupBy = append(s.groupBy, e)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	p.aggregates = true
+	if p.accept("HAVING") {
+		if s.having, err = p.expr(); err != nil {
+			return
+		}
+	}
+	if p.accept("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		for {
+			var item orderItem
+			if item, err = p.orderItem(s); err != nil {
+				return
+			}
+			s.orderBy = append(s.orderBy, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("LIMIT") {
+		if s.limit, err = p.count(); err != nil {
+			return
+		}
+		if p.accept("OFFSET") {
+			if s.offset, err = p.count(); err != nil {
+				return
+			}
+		}
+	}
+	p.accept(";")
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected("expected end of query")
+	}
+
+	s.grouped = len(s.groupBy) > 0 || p.sawAggregate
+	if s.grouped {
+		exprs := []expr{s.having}
+		for _, col := range s.columns {
+			exprs = append(exprs, col.expr)
+		}
+		for _, item := range s.orderBy {
+			exprs = append(exprs, item.expr)
+		}
+		for _, e := range exprs {
+			if err = checkGrouped(e, s.groupBy); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// orderItem parses an ORDER BY item, which is a result column given by position, name or expression,
+// or another expression.
+func (p *parser) orderItem(s *Statement) (item orderItem, err error) {
+	item.index = -1
+	if t := p.peek(); t.kind == tokenNumber {
+		p.next()
+		n, err := strconv.Atoi(t.value)
+		if err != nil || n < 1 || n > len(s.columns) {
+			return item, syntaxError(t.pos, fmt.Sprintf("ORDER BY position %s is not in the select list", t.value))
+		}
+		item.index = n - 1
+	} else if i := aliasIndex(s, t); i >= 0 && !p.tokens[p.i+1].is("(") {
+		p.next()
+		item.index = i
+	} else {
+		if item.expr, err = p.expr(); err != nil {
+			return
+		}
+		for i, col := range s.columns {
+			if col.name == item.expr.String() || col.expr.String() == item.expr.String() {
+				item.index = i
+				break
+			}
+		}
+	}
+	if p.accept("DESC") {
+		item.desc = true
+	} else {
+		p.accept("ASC")
+	}
+	return
+}
+
+// aliasIndex returns the index of the result column aliased t, or -1.
+func aliasIndex(s *Statement, t token) int {
+	if t.kind != tokenIdent {
+		return -1
+	}
+	for i, col := range s.columns {
+		if strings.EqualFold(col.name, t.value) {
+			return i
+		}
+	}
+	return -1
+}
+
+// count parses a non-negative integer.
+func (p *parser) count() (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.value)
+	if t.kind != tokenNumber || err != nil || n < 0 {
+		return 0, syntaxError(t.pos, "expected a non-negative integer")
+	}
+	return n, nil
+}
+
+func (p *parser) expr() (expr, error) {
+	return p.or()
+}
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	for err == nil && p.accept("OR") {
+		var r expr
+		if r, err = p.and(); err == nil {
+			l = &logical{op: "OR", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.not()
+	for err == nil && p.accept("AND") {
+		var r expr
+		if r, err = p.not(); err == nil {
+			l = &logical{op: "AND", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) not() (expr, error) {
+	if p.accept("NOT") {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	}
+	return p.predicate()
+}
+
+func (p *parser) predicate() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind == tokenOp {
+		switch t.value {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.next()
+			r, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return &comparison{op: t.value, l: x, r: r}, nil
+		}
+	}
+
+	negate := p.accept("NOT")
+	switch {
+	case p.accept("LIKE"):
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, syntaxError(pattern.pos, "expected a pattern")
+		}
+		return newLike(x, pattern.value, negate), nil
+	case p.accept("IN"):
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+		i := &in{x: x, negate: negate}
+		for {
+			item, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			i.list = append(i.list, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return i, p.expect(")")
+	case p.accept("BETWEEN"):
+		lo, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &between{x: x, lo: lo, hi: hi, negate: negate}, nil
+	case negate:
+		return nil, p.unexpected("expected LIKE, IN or BETWEEN")
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return &literal{value: t.value, text: quote(t.value)}, nil
+	case tokenNumber:
+		return numberLiteral(t)
+	case tokenOp:
+		if t.value == "(" {
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	case tokenIdent:
+		name := strings.ToUpper(t.value)
+		if p.peek().is("(") {
+			p.next()
+			return p.call(t, name)
+		}
+		switch name {
+		case "TRUE", "FALSE":
+			return &literal{value: name == "TRUE", text: name}, nil
+		case "NULL":
+			return &literal{value: nil, text: name}, nil
+		}
+		for _, col := range Columns {
+			if strings.EqualFold(t.value, col) {
+				return &columnRef{name: col}, nil
+			}
+		}
+		return nil, syntaxError(t.pos, fmt.Sprintf("unknown column %q, expected one of %s", t.value, strings.Join(Columns, ", ")))
+	}
+	// next does not advance past the end of the query, so there is nothing to step back over then.
+	if t.kind != tokenEOF {
+		p.i--
+	}
+	return nil, p.unexpected("expected an expression")
+}
+
+// call parses the arguments of the function name, whose opening parenthesis has been consumed.
+func (p *parser) call(t token, name string) (expr, error) {
+	if aggregateFuncs[name] {
+		if !p.aggregates {
+			return nil, syntaxError(t.pos, fmt.Sprintf("aggregate %s is not allowed here", name))
+		}
+		p.sawAggregate = true
+		a := &aggregate{fn: name}
+		if name == "COUNT" && p.accept("*") {
+			return a, p.expect(")")
+		}
+		a.distinct = p.accept("DISTINCT")
+		// Aggregates may not be nested.
+		p.aggregates = false
+		arg, err := p.expr()
+		p.aggregates = true
+		if err != nil {
+			return nil, err
+		}
+		a.arg = arg
+		return a, p.expect(")")
+	}
+
+	if _, ok := scalarFuncs[name]; !ok {
+		return nil, syntaxError(t.pos, fmt.Sprintf("unknown function %s", t.value))
+	}
+	arg, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &call{fn: name, arg: arg}, p.expect(")")
+}
+
+// numberLiteral parses an integer, a decimal or a size such as 50kB.
+func numberLiteral(t token) (expr, error) {
+	if n, err := strconv.ParseInt(t.value, 10, 64); err == nil {
+		return &literal{value: n, text: t.value}, nil
+	}
+	if f, err := strconv.ParseFloat(t.value, 64); err == nil {
+		return &literal{value: f, text: t.value}, nil
+	}
+	size, err := reader.ParseSize(t.value, reader.Byte)
+	if err != nil {
+		return nil, syntaxError(t.pos, fmt.Sprintf("invalid number %q", t.value))
+	}
+	return &literal{value: size, text: t.value}, nil
+}
+
+// checkGrouped verifies that every column e refers to outside of an aggregate is grouped by.
+func checkGrouped(e expr, groupBy []expr) error {
+	if e == nil {
+		return nil
+	}
+	for _, g := range groupBy {
+		if e.String() == g.String() {
+			return nil
+		}
+	}
+	if c, ok := e.(*columnRef); ok {
+		return fmt.Errorf("column %s must appear in the GROUP BY clause or be used in an aggregate", c.name)
+	}
+	for _, child := range e.children() {
+		if err := checkGrouped(child, groupBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+Wed Apr 15 03:06:46 UTC 2020,jeff22,upload,45
+Wed Apr 15 13:00:00 UTC 2020,Maia86,upload,20
+Wed Apr 15 23:18:26 UTC 2020,jeff22,upload,75
+Thu Apr 16 01:00:00 UTC 2020,jeff22,upload,10
+`
+
+func execute(t *testing.T, query string) *Result {
+	stmt, err := Parse(query)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	result, err := stmt.Execute(csv.NewReader(strings.NewReader(input)))
+	assert.NoError(t, err)
+	return result
+}
+
+func TestStatement_Execute(t *testing.T) {
+	t.Run("groups and orders", func(t *testing.T) {
+		result := execute(t, `SELECT username, COUNT(*), SUM(size) FROM log WHERE operation='upload' AND timestamp >= '2020-04-15' GROUP BY username ORDER BY 2 DESC LIMIT 10`)
+		assert.Equal(t, &Result{
+			Columns: []string{"username", "COUNT(*)", "SUM(size)"},
+			Rows: [][]Value{
+				{"jeff22", int64(3), 130 * reader.KB},
+				{"Maia86", int64(1), 20 * reader.KB},
+			},
+		}, result)
+	})
+
+	t.Run("selects every column", func(t *testing.T) {
+		result := execute(t, `select * from log where size > 50kB order by timestamp desc`)
+		assert.Equal(t, &Result{
+			Columns: []string{"timestamp", "username", "operation", "size"},
+			Rows: [][]Value{
+				{time.Date(2020, 04, 15, 23, 18, 26, 0, time.UTC), "jeff22", "upload", 75 * reader.KB},
+				{time.Date(2020, 04, 12, 22, 35, 06, 0, time.UTC), "Maia86", "download", 75 * reader.KB},
+			},
+		}, result)
+	})
+
+	t.Run("aggregates without groups", func(t *testing.T) {
+		result := execute(t, `SELECT COUNT(DISTINCT username) AS users, MIN(timestamp), MAX(size), AVG(size) FROM log`)
+		assert.Equal(t, []string{"users", "MIN(timestamp)", "MAX(size)", "AVG(size)"}, result.Columns)
+		assert.Equal(t, [][]Value{{int64(3), time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), 75 * reader.KB, reader.Size(43166)}}, result.Rows)
+
+		result = execute(t, `SELECT COUNT(*), SUM(size) FROM log WHERE username = 'nobody'`)
+		assert.Equal(t, [][]Value{{int64(0), nil}}, result.Rows)
+	})
+
+	t.Run("groups by function", func(t *testing.T) {
+		result := execute(t, `SELECT DATE(timestamp) AS day, COUNT(*) AS n FROM log GROUP BY DATE(timestamp) HAVING COUNT(*) > 1 ORDER BY n DESC, day`)
+		assert.Equal(t, [][]Value{{"2020-04-15", int64(3)}, {"2020-04-12", int64(2)}}, result.Rows)
+	})
+
+	tests := []struct {
+		where string
+		count int
+	}{
+		{where: `username = 'jeff22'`, count: 3},
+		{where: `username <> 'jeff22'`, count: 3},
+		{where: `username LIKE 'ma%'`, count: 0},
+		{where: `LOWER(username) LIKE 'ma%'`, count: 2},
+		{where: `username NOT LIKE '%2_'`, count: 3},
+		{where: `username IN ('sarah94', 'Maia86')`, count: 3},
+		{where: `operation NOT IN ('upload')`, count: 2},
+		{where: `size BETWEEN 20000 AND '45kB'`, count: 3},
+		{where: `size NOT BETWEEN 20kB AND 45kB`, count: 3},
+		{where: `timestamp < '2020-04-15' OR (operation = 'upload' AND NOT size > 30kB)`, count: 4},
+		{where: `HOUR(timestamp) = 13`, count: 1},
+		{where: `size = 75000`, count: 2},
+		{where: `size >= 74999.5`, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			result := execute(t, "SELECT COUNT(*) FROM log WHERE "+tt.where)
+			assert.Equal(t, [][]Value{{int64(tt.count)}}, result.Rows)
+		})
+	}
+
+	t.Run("offsets", func(t *testing.T) {
+		result := execute(t, `SELECT username FROM log ORDER BY timestamp LIMIT 2 OFFSET 1;`)
+		assert.Equal(t, [][]Value{{"Maia86"}, {"jeff22"}}, result.Rows)
+	})
+
+	t.Run("fails on invalid comparison", func(t *testing.T) {
+		stmt, err := Parse(`SELECT * FROM log WHERE timestamp > 'soon'`)
+		assert.NoError(t, err)
+		_, err = stmt.Execute(csv.NewReader(strings.NewReader(input)))
+		assert.EqualError(t, err, `invalid timestamp "soon"`)
+	})
+}
+
+func TestParse(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`SELECT`,
+		`SELECT * log`,
+		`SELECT bytes FROM log`,
+		`SELECT * FROM log WHERE`,
+		`SELECT * FROM log WHERE username = 'jeff22`,
+		`SELECT * FROM log WHERE COUNT(*) > 1`,
+		`SELECT * FROM log WHERE username NOT = 'x'`,
+		`SELECT COUNT(SUM(size)) FROM log`,
+		`SELECT username, COUNT(*) FROM log`,
+		`SELECT username FROM log GROUP BY operation`,
+		`SELECT * FROM log ORDER BY 5`,
+		`SELECT * FROM log LIMIT -1`,
+		`SELECT * FROM log LIMIT 1 extra`,
+		`SELECT FOO(size) FROM log`,
+		`SELECT * FROM log WHERE size > 5zB`,
+		`SELECT * FROM log WHERE username ~ 'x'`,
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := Parse(query)
+			assert.Error(t, err)
+		})
+	}
+
+	for query, want := range map[string]string{
+		`SELECT`:                              "got end of query",
+		`SELECT username FROM log ORDER BY`:   "got end of query",
+		`SELECT * FROM log WHERE size >`:      "got end of query",
+		`SELECT * FROM log WHERE size IN (1,`: "got end of query",
+		`SELECT * FROM log WHERE size > )`:    `got ")"`,
+		`SELECT * FROM log WHERE (`:           "got end of query",
+	} {
+		t.Run("reports the end of "+query, func(t *testing.T) {
+			_, err := Parse(query)
+			assert.ErrorIs(t, err, ErrSyntax)
+			assert.ErrorContains(t, err, want)
+		})
+	}
+}
+
+func TestFinder(t *testing.T) {
+	f, err := NewFinder(csv.NewReader(strings.NewReader(input)), `SELECT operation, SUM(size) AS bytes FROM log GROUP BY operation ORDER BY bytes DESC`)
+	assert.NoError(t, err)
+	count, events, err := f.Find()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"upload\t150000", "download\t109000"}, events)
+
+	_, err = NewFinder(nil, `DELETE FROM log`)
+	assert.ErrorIs(t, err, ErrSyntax)
+}
@@ -0,0 +1,281 @@
+package stats
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	ErrBucketInvalid    = logfind.Error("invalid bucket, expected a positive duration")
+	ErrThresholdInvalid = logfind.Error("invalid threshold, expected a positive number")
+)
+
+// Metrics of a user's activity in a time bucket checked by Anomalies.
+const (
+	// MetricEvents is the number of events.
+	MetricEvents = "events"
+	// MetricUploaded and MetricDownloaded are the combined sizes of the upload and download events.
+	MetricUploaded   = "uploaded"
+	MetricDownloaded = "downloaded"
+	// MetricHour is the hour of the day, flagged when the user has not been active within hourTolerance of it before.
+	MetricHour = "hour"
+)
+
+// hourTolerance is the number of hours around an hour of the day in which earlier activity makes activity at that
+// hour usual, so that a user who works until 17:00 is not flagged for staying until 18:00.
+const hourTolerance = 2
+
+// madScale and meanADScale make the median and mean absolute deviations of normally distributed values estimates of
+// their standard deviation.
+const (
+	madScale    = 1.4826
+	meanADScale = 1.2533
+)
+
+// minScales are the smallest deviations a score is relative to, so that a user whose activity never varied
+// is not flagged for the slightest change.
+var minScales = map[string]float64{
+	MetricEvents:     1,
+	MetricUploaded:   float64(reader.KB),
+	MetricDownloaded: float64(reader.KB),
+}
+
+// Anomaly is a time bucket in which the activity of a user departs from their baseline.
+type Anomaly struct {
+	User   string
+	Bucket time.Time
+	Metric string
+	// Value is the value of the metric in the bucket, in bytes for sizes.
+	Value float64
+	// Baseline is the median of the metric over the user's earlier active buckets.
+	// For MetricHour it is the number of earlier active buckets.
+	Baseline float64
+	// Score is the number of robust standard deviations Value lies above Baseline. It is 0 for MetricHour.
+	Score float64
+}
+
+// String describes a in words, e.g., "uploaded 500kB, 20x the usual 25kB".
+func (a Anomaly) String() string {
+	format := func(v float64) string {
+		if a.Metric == MetricEvents {
+			return fmt.Sprintf("%.4g", v)
+		}
+		return reader.Size(v).String()
+	}
+	switch {
+	case a.Metric == MetricHour:
+		return fmt.Sprintf("active at %02d:00 for the first time in %.0f active buckets", int(a.Value), a.Baseline)
+	case a.Baseline == 0:
+		return fmt.Sprintf("%s %s, usually 0", a.Metric, format(a.Value))
+	default:
+		return fmt.Sprintf("%s %s, %.3gx the usual %s", a.Metric, format(a.Value), a.Value/a.Baseline, format(a.Baseline))
+	}
+}
+
+type anomalyOptions struct {
+	bucket     time.Duration
+	threshold  float64
+	minHistory int
+	window     int
+	loc        *time.Location
+}
+
+// AnomalyOptionFunc customizes Anomalies.
+type AnomalyOptionFunc func(*anomalyOptions)
+
+// WithBucket sets the time buckets over which activity is totalled. The default is an hour.
+func WithBucket(bucket time.Duration) AnomalyOptionFunc {
+	return func(o *anomalyOptions) {
+		o.bucket = bucket
+	}
+}
+
+// WithThreshold sets the score at which a bucket is reported. The default is 4.
+func WithThreshold(threshold float64) AnomalyOptionFunc {
+	return func(o *anomalyOptions) {
+		o.threshold = threshold
+	}
+}
+
+// WithMinHistory sets the number of active buckets a user needs before their activity is scored. The default is 5.
+func WithMinHistory(n int) AnomalyOptionFunc {
+	return func(o *anomalyOptions) {
+		o.minHistory = n
+	}
+}
+
+// WithWindow limits the baseline to the latest n active buckets of a user. By default every earlier bucket counts.
+func WithWindow(n int) AnomalyOptionFunc {
+	return func(o *anomalyOptions) {
+		o.window = n
+	}
+}
+
+// WithLocation sets the time zone of buckets and hours of the day. The default is UTC.
+func WithLocation(loc *time.Location) AnomalyOptionFunc {
+	return func(o *anomalyOptions) {
+		o.loc = loc
+	}
+}
+
+// bucketTotals is the activity of a user in a time bucket.
+type bucketTotals struct {
+	start   time.Time
+	metrics map[string]float64
+	hours   map[int]bool
+}
+
+// Anomalies reads every event of r, totals the activity of each user per time bucket and reports the buckets in which
+// a user is far more active than usual, or active at an unusual hour of the day for the first time.
+//
+// The baseline of a user is the median and median absolute deviation of each metric over their earlier buckets with
+// events, so quiet periods do not count as usual activity. A bucket is reported once its score, the distance above
+// the median in robust standard deviations, reaches the threshold. Anomalies are ordered by bucket, user and metric.
+func Anomalies(r reader.Reader, opts ...AnomalyOptionFunc) ([]Anomaly, error) {
+	o := anomalyOptions{
+		bucket:     time.Hour,
+		threshold:  4,
+		minHistory: 5,
+		loc:        time.UTC,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.bucket <= 0 {
+		return nil, ErrBucketInvalid
+	}
+	if o.threshold <= 0 {
+		return nil, ErrThresholdInvalid
+	}
+
+	records, err := reader.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]map[int64]*bucketTotals)
+	for _, rec := range records {
+		t := rec.Time.In(o.loc)
+		start := truncate(t, o.bucket)
+		if users[rec.User] == nil {
+			users[rec.User] = make(map[int64]*bucketTotals)
+		}
+		b := users[rec.User][start.UnixNano()]
+		if b == nil {
+			b = &bucketTotals{start: start, metrics: make(map[string]float64), hours: make(map[int]bool)}
+			users[rec.User][start.UnixNano()] = b
+		}
+		b.metrics[MetricEvents]++
+		switch rec.Op {
+		case OperationUpload:
+			b.metrics[MetricUploaded] += float64(rec.Bytes)
+		case OperationDownload:
+			b.metrics[MetricDownloaded] += float64(rec.Bytes)
+		}
+		b.hours[t.Hour()] = true
+	}
+
+	var anomalies []Anomaly
+	for user, byStart := range users {
+		buckets := make([]*bucketTotals, 0, len(byStart))
+		for _, b := range byStart {
+			buckets = append(buckets, b)
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			return buckets[i].start.Before(buckets[j].start)
+		})
+		anomalies = append(anomalies, userAnomalies(user, buckets, o)...)
+	}
+
+	metricOrder := map[string]int{MetricEvents: 0, MetricUploaded: 1, MetricDownloaded: 2, MetricHour: 3}
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if !a.Bucket.Equal(b.Bucket) {
+			return a.Bucket.Before(b.Bucket)
+		}
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		if a.Metric != b.Metric {
+			return metricOrder[a.Metric] < metricOrder[b.Metric]
+		}
+		return a.Value < b.Value
+	})
+	return anomalies, nil
+}
+
+// userAnomalies scores the chronologically ordered buckets of user against the buckets before them.
+func userAnomalies(user string, buckets []*bucketTotals, o anomalyOptions) (anomalies []Anomaly) {
+	seenHours := make(map[int]bool)
+	for i, b := range buckets {
+		if i >= o.minHistory && i > 0 {
+			history := buckets[:i]
+			if o.window > 0 && len(history) > o.window {
+				history = history[len(history)-o.window:]
+			}
+			for _, metric := range []string{MetricEvents, MetricUploaded, MetricDownloaded} {
+				values := make([]float64, len(history))
+				for j, h := range history {
+					values[j] = h.metrics[metric]
+				}
+				median, scale := baseline(values, minScales[metric])
+				if score := (b.metrics[metric] - median) / scale; score >= o.threshold {
+					anomalies = append(anomalies, Anomaly{User: user, Bucket: b.start, Metric: metric, Value: b.metrics[metric], Baseline: median, Score: score})
+				}
+			}
+			for hour := range b.hours {
+				if !nearHours(seenHours, hour) {
+					anomalies = append(anomalies, Anomaly{User: user, Bucket: b.start, Metric: MetricHour, Value: float64(hour), Baseline: float64(i)})
+				}
+			}
+		}
+		for hour := range b.hours {
+			seenHours[hour] = true
+		}
+	}
+	return
+}
+
+// nearHours reports whether hours holds an hour of the day within hourTolerance of hour.
+func nearHours(hours map[int]bool, hour int) bool {
+	for d := -hourTolerance; d <= hourTolerance; d++ {
+		if hours[(hour+d+24)%24] {
+			return true
+		}
+	}
+	return false
+}
+
+// baseline returns the median of values and their median absolute deviation scaled to estimate a standard deviation.
+// When most values equal the median, which leaves the median absolute deviation 0, the mean absolute deviation is
+// used instead, and minScale when that is smaller still.
+func baseline(values []float64, minScale float64) (median, scale float64) {
+	median = Distribute(values).Median
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	d := Distribute(deviations)
+	scale = madScale * d.Median
+	if scale == 0 {
+		scale = meanADScale * d.Mean
+	}
+	if scale < minScale {
+		scale = minScale
+	}
+	return
+}
+
+// truncate rounds t down to a multiple of bucket since midnight of its day, or since the Unix epoch for buckets
+// longer than a day, so that hourly and daily buckets follow the clock of t's location.
+func truncate(t time.Time, bucket time.Duration) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if bucket <= 24*time.Hour {
+		return day.Add(t.Sub(day) / bucket * bucket)
+	}
+	return t.Truncate(bucket)
+}
@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// steadyRecords returns an upload of 10kB by jeff22 every hour from 10:00 to 15:00 followed by extra.
+func steadyRecords(extra ...reader.Record) []reader.Record {
+	var records []reader.Record
+	for hour := 10; hour < 16; hour++ {
+		records = append(records, reader.Record{Time: at(hour, 15), User: "jeff22", Op: OperationUpload, Bytes: 10 * reader.KB})
+	}
+	return append(records, extra...)
+}
+
+func TestAnomalies(t *testing.T) {
+	t.Run("flags volume far above the baseline", func(t *testing.T) {
+		records := steadyRecords(reader.Record{Time: at(16, 30), User: "jeff22", Op: OperationUpload, Bytes: 200 * reader.KB})
+		anomalies, err := Anomalies(reader.NewSliceReader(records))
+		assert.NoError(t, err)
+		assert.Equal(t, []Anomaly{
+			{User: "jeff22", Bucket: at(16, 0), Metric: MetricUploaded, Value: 200000, Baseline: 10000, Score: 190},
+		}, anomalies)
+		assert.Equal(t, "uploaded 200kB, 20x the usual 10kB", anomalies[0].String())
+	})
+
+	t.Run("flags the first activity at an hour", func(t *testing.T) {
+		records := steadyRecords(reader.Record{Time: at(23, 0).Add(4 * time.Hour), User: "jeff22", Op: OperationUpload, Bytes: 10 * reader.KB})
+		anomalies, err := Anomalies(reader.NewSliceReader(records))
+		assert.NoError(t, err)
+		assert.Equal(t, []Anomaly{
+			{User: "jeff22", Bucket: at(23, 0).Add(4 * time.Hour), Metric: MetricHour, Value: 3, Baseline: 6},
+		}, anomalies)
+		assert.Equal(t, "active at 03:00 for the first time in 6 active buckets", anomalies[0].String())
+	})
+
+	t.Run("ignores usual activity and short histories", func(t *testing.T) {
+		records := steadyRecords(
+			reader.Record{Time: at(16, 30), User: "jeff22", Op: OperationUpload, Bytes: 12 * reader.KB},
+			reader.Record{Time: at(16, 30), User: "sarah94", Op: OperationDownload, Bytes: 500 * reader.KB},
+		)
+		anomalies, err := Anomalies(reader.NewSliceReader(records))
+		assert.NoError(t, err)
+		assert.Empty(t, anomalies)
+	})
+
+	t.Run("respects bucket, threshold and min history", func(t *testing.T) {
+		records := steadyRecords(reader.Record{Time: at(16, 30), User: "jeff22", Op: OperationUpload, Bytes: 13 * reader.KB})
+		anomalies, err := Anomalies(reader.NewSliceReader(records), WithThreshold(2))
+		assert.NoError(t, err)
+		assert.Len(t, anomalies, 1)
+
+		anomalies, err = Anomalies(reader.NewSliceReader(records), WithThreshold(2), WithMinHistory(10))
+		assert.NoError(t, err)
+		assert.Empty(t, anomalies)
+
+		anomalies, err = Anomalies(reader.NewSliceReader(records), WithThreshold(2), WithBucket(24*time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, anomalies)
+	})
+
+	t.Run("fails on invalid options", func(t *testing.T) {
+		_, err := Anomalies(reader.NewSliceReader(nil), WithBucket(0))
+		assert.ErrorIs(t, err, ErrBucketInvalid)
+		_, err = Anomalies(reader.NewSliceReader(nil), WithThreshold(-1))
+		assert.ErrorIs(t, err, ErrThresholdInvalid)
+	})
+}
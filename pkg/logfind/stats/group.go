@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"sort"
+)
+
+const (
+	ErrGroupingInvalid = logfind.Error("invalid grouping, expected user, operation, day or hour")
+)
+
+// groupings maps the names accepted by Group to the func deriving the group of an event.
+var groupings = map[string]func(rec reader.Record) string{
+	"user":      func(rec reader.Record) string { return rec.User },
+	"operation": func(rec reader.Record) string { return rec.Op },
+	"day":       func(rec reader.Record) string { return rec.Time.Format("2006-01-02") },
+	"hour":      func(rec reader.Record) string { return rec.Time.Format("2006-01-02 15:00") },
+}
+
+// Group reads every event of r and totals them by user, operation, day or hour.
+// Users and operations are ordered like Rank, days and hours chronologically.
+func Group(r reader.Reader, by string) ([]Ranked, error) {
+	key, ok := groupings[by]
+	if !ok {
+		return nil, ErrGroupingInvalid
+	}
+
+	groups := make(map[string]*Totals)
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := reader.NewRecord(e)
+		if err != nil {
+			return nil, err
+		}
+		k := key(rec)
+		if groups[k] == nil {
+			groups[k] = &Totals{}
+		}
+		groups[k].add(rec.Bytes)
+	}
+
+	ranked := Rank(groups)
+	if by == "day" || by == "hour" {
+		sort.Slice(ranked, func(i, j int) bool {
+			return ranked[i].Name < ranked[j].Name
+		})
+	}
+	return ranked, nil
+}
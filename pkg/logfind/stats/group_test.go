@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("groups by user", func(t *testing.T) {
+		ranked, err := Group(csv.NewReader(strings.NewReader(input)), "user")
+		assert.NoError(t, err)
+		assert.Equal(t, []Ranked{
+			{Name: "Maia86", Totals: Totals{Events: 2, Size: 84 * reader.KB}},
+			{Name: "jeff22", Totals: Totals{Events: 1, Size: 25 * reader.KB}},
+			{Name: "sarah94", Totals: Totals{Events: 1, Size: 34 * reader.KB}},
+		}, ranked)
+	})
+
+	t.Run("groups by hour chronologically", func(t *testing.T) {
+		ranked, err := Group(csv.NewReader(strings.NewReader(input)), "hour")
+		assert.NoError(t, err)
+		assert.Equal(t, []Ranked{
+			{Name: "2020-04-12 21:00", Totals: Totals{Events: 1, Size: 25 * reader.KB}},
+			{Name: "2020-04-12 22:00", Totals: Totals{Events: 3, Size: 118 * reader.KB}},
+		}, ranked)
+	})
+
+	t.Run("fails on unknown grouping", func(t *testing.T) {
+		_, err := Group(csv.NewReader(strings.NewReader(input)), "week")
+		assert.ErrorIs(t, err, ErrGroupingInvalid)
+	})
+
+	t.Run("fails on malformed event", func(t *testing.T) {
+		_, err := Group(csv.NewReader(strings.NewReader(input+"Sun Apr 12 23:00:00 UTC 2020,jeff22,upload,big\n")), "day")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,194 @@
+package stats
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	ErrGapInvalid = logfind.Error("invalid gap, expected a positive duration")
+)
+
+// Operations totalled separately by Session.
+const (
+	OperationUpload   = "upload"
+	OperationDownload = "download"
+)
+
+// Session is a run of events of one user in which no two consecutive events are further apart than a gap.
+type Session struct {
+	User   string
+	Start  time.Time
+	End    time.Time
+	Events int
+	// Uploaded and Downloaded are the combined sizes of the upload and download events.
+	Uploaded   reader.Size
+	Downloaded reader.Size
+}
+
+// Duration returns the time between the first and the last event of s.
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+func (s *Session) add(rec reader.Record) {
+	s.End = rec.Time
+	s.Events++
+	switch rec.Op {
+	case OperationUpload:
+		s.Uploaded += rec.Bytes
+	case OperationDownload:
+		s.Downloaded += rec.Bytes
+	}
+}
+
+// Sessions reads every event of r and splits the events of each user into sessions, a session ending once the
+// user has been inactive for longer than gap. The events need not be ordered. Sessions are ordered by start,
+// breaking ties by user.
+func Sessions(r reader.Reader, gap time.Duration) ([]Session, error) {
+	if gap <= 0 {
+		return nil, ErrGapInvalid
+	}
+
+	records, err := reader.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	var sessions []Session
+	// open maps each user to the index of their latest session.
+	open := make(map[string]int)
+	for _, rec := range records {
+		if i, ok := open[rec.User]; ok && rec.Time.Sub(sessions[i].End) <= gap {
+			sessions[i].add(rec)
+			continue
+		}
+		open[rec.User] = len(sessions)
+		sessions = append(sessions, Session{User: rec.User, Start: rec.Time})
+		sessions[len(sessions)-1].add(rec)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].Start.Equal(sessions[j].Start) {
+			return sessions[i].Start.Before(sessions[j].Start)
+		}
+		return sessions[i].User < sessions[j].User
+	})
+	return sessions, nil
+}
+
+// Distribution describes a set of values by their extremes, mean and percentiles.
+type Distribution struct {
+	Min    float64
+	Median float64
+	P90    float64
+	Max    float64
+	Mean   float64
+}
+
+// Distribute returns the Distribution of values, using nearest-rank percentiles. It returns the zero Distribution
+// when values is empty.
+func Distribute(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return Distribution{
+		Min:    sorted[0],
+		Median: percentile(0.5),
+		P90:    percentile(0.9),
+		Max:    sorted[len(sorted)-1],
+		Mean:   sum / float64(len(sorted)),
+	}
+}
+
+// SessionSummary describes a set of sessions.
+type SessionSummary struct {
+	Sessions int
+	Users    int
+	// Duration is in seconds.
+	Duration Distribution
+	Events   Distribution
+	// Uploaded and Downloaded are in bytes.
+	Uploaded   Distribution
+	Downloaded Distribution
+	// PerUser is the distribution of the number of sessions of each user.
+	PerUser Distribution
+}
+
+// SummarizeSessions returns the SessionSummary of sessions.
+func SummarizeSessions(sessions []Session) SessionSummary {
+	var durations, events, uploaded, downloaded []float64
+	perUser := make(map[string]int)
+	for _, s := range sessions {
+		durations = append(durations, s.Duration().Seconds())
+		events = append(events, float64(s.Events))
+		uploaded = append(uploaded, float64(s.Uploaded))
+		downloaded = append(downloaded, float64(s.Downloaded))
+		perUser[s.User]++
+	}
+	var counts []float64
+	for _, n := range perUser {
+		counts = append(counts, float64(n))
+	}
+
+	return SessionSummary{
+		Sessions:   len(sessions),
+		Users:      len(perUser),
+		Duration:   Distribute(durations),
+		Events:     Distribute(events),
+		Uploaded:   Distribute(uploaded),
+		Downloaded: Distribute(downloaded),
+		PerUser:    Distribute(counts),
+	}
+}
+
+// Fprint writes s to w as an aligned table.
+func (s SessionSummary) Fprint(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "sessions:\t%d\n", s.Sessions)
+	fmt.Fprintf(tw, "users:\t%d\n", s.Users)
+	if s.Sessions == 0 {
+		return tw.Flush()
+	}
+
+	duration := func(seconds float64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	}
+	size := func(bytes float64) string {
+		return reader.Size(bytes).String()
+	}
+	count := func(n float64) string {
+		return fmt.Sprintf("%.4g", n)
+	}
+
+	fmt.Fprintf(tw, "\n\tmin\tmedian\tp90\tmax\tmean\n")
+	fprintDistribution(tw, "sessions per user", s.PerUser, count)
+	fprintDistribution(tw, "duration", s.Duration, duration)
+	fprintDistribution(tw, "events", s.Events, count)
+	fprintDistribution(tw, "uploaded", s.Uploaded, size)
+	fprintDistribution(tw, "downloaded", s.Downloaded, size)
+	return tw.Flush()
+}
+
+func fprintDistribution(w io.Writer, name string, d Distribution, format func(float64) string) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, format(d.Min), format(d.Median), format(d.P90), format(d.Max), format(d.Mean))
+}
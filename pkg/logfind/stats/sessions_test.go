@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"bytes"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sessionInput = `timestamp,username,operation,size
+Sun Apr 12 22:10:00 UTC 2020,jeff22,upload,10
+Sun Apr 12 22:00:00 UTC 2020,jeff22,download,20
+Sun Apr 12 22:05:00 UTC 2020,sarah94,upload,5
+Sun Apr 12 22:40:00 UTC 2020,jeff22,upload,30
+Sun Apr 12 23:20:00 UTC 2020,jeff22,download,40
+`
+
+func at(hour, min int) time.Time {
+	return time.Date(2020, 4, 12, hour, min, 0, 0, time.UTC)
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("splits sessions after the gap", func(t *testing.T) {
+		sessions, err := Sessions(csv.NewReader(strings.NewReader(sessionInput)), 30*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []Session{
+			{User: "jeff22", Start: at(22, 0), End: at(22, 40), Events: 3, Uploaded: 40 * reader.KB, Downloaded: 20 * reader.KB},
+			{User: "sarah94", Start: at(22, 5), End: at(22, 5), Events: 1, Uploaded: 5 * reader.KB},
+			{User: "jeff22", Start: at(23, 20), End: at(23, 20), Events: 1, Downloaded: 40 * reader.KB},
+		}, sessions)
+		assert.Equal(t, 40*time.Minute, sessions[0].Duration())
+	})
+
+	t.Run("respects gap", func(t *testing.T) {
+		sessions, err := Sessions(csv.NewReader(strings.NewReader(sessionInput)), 10*time.Minute)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 4)
+	})
+
+	t.Run("fails on invalid gap", func(t *testing.T) {
+		_, err := Sessions(csv.NewReader(strings.NewReader(sessionInput)), 0)
+		assert.ErrorIs(t, err, ErrGapInvalid)
+	})
+
+	t.Run("fails on malformed event", func(t *testing.T) {
+		_, err := Sessions(csv.NewReader(strings.NewReader(sessionInput+"Sun Apr 12 23:30:00 UTC 2020,jeff22,upload,big\n")), time.Hour)
+		assert.Error(t, err)
+	})
+}
+
+func TestDistribute(t *testing.T) {
+	t.Run("uses nearest rank percentiles", func(t *testing.T) {
+		assert.Equal(t, Distribution{Min: 1, Median: 5, P90: 9, Max: 10, Mean: 5.5}, Distribute([]float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+	})
+
+	t.Run("is zero without values", func(t *testing.T) {
+		assert.Equal(t, Distribution{}, Distribute(nil))
+	})
+}
+
+func TestSummarizeSessions(t *testing.T) {
+	sessions, err := Sessions(csv.NewReader(strings.NewReader(sessionInput)), 30*time.Minute)
+	assert.NoError(t, err)
+
+	s := SummarizeSessions(sessions)
+	assert.Equal(t, 3, s.Sessions)
+	assert.Equal(t, 2, s.Users)
+	assert.Equal(t, Distribution{Min: 0, Median: 0, P90: 2400, Max: 2400, Mean: 800}, s.Duration)
+	assert.Equal(t, Distribution{Min: 1, Median: 1, P90: 2, Max: 2, Mean: 1.5}, s.PerUser)
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.Fprint(&buf))
+	assert.Contains(t, buf.String(), "sessions:  3\n")
+	assert.Regexp(t, `duration\s+0s\s+0s\s+40m0s\s+40m0s\s+13m20s`, buf.String())
+}
@@ -0,0 +1,167 @@
+// Package stats summarizes the events of a log stream.
+package stats
+
+import (
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Totals accumulates the number and combined size of a group of events.
+type Totals struct {
+	Events int
+	Size   reader.Size
+}
+
+func (t *Totals) add(size reader.Size) {
+	t.Events++
+	t.Size += size
+}
+
+// Summary describes the events of a log stream.
+type Summary struct {
+	Totals
+
+	// First and Last are the earliest and latest event timestamps.
+	First time.Time
+	Last  time.Time
+
+	// MinSize and MaxSize are the smallest and largest event sizes.
+	MinSize reader.Size
+	MaxSize reader.Size
+
+	// Users and Operations hold the Totals of each distinct username and operation.
+	Users      map[string]*Totals
+	Operations map[string]*Totals
+}
+
+// MeanSize returns the average event size.
+func (s *Summary) MeanSize() reader.Size {
+	if s.Events == 0 {
+		return 0
+	}
+	return s.Size / reader.Size(s.Events)
+}
+
+// Ranked pairs a group name, e.g., a username, with its Totals.
+type Ranked struct {
+	Name string
+	Totals
+}
+
+// Rank orders groups by their number of events, largest first, breaking ties by name.
+func Rank(groups map[string]*Totals) []Ranked {
+	ranked := make([]Ranked, 0, len(groups))
+	for name, totals := range groups {
+		ranked = append(ranked, Ranked{Name: name, Totals: *totals})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Events != ranked[j].Events {
+			return ranked[i].Events > ranked[j].Events
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked
+}
+
+// Summarize reads every event of r and summarizes them. Any error other than io.EOF stops the summary,
+// so wrap r with logfind.NewFilterReader to filter events or skip malformed records.
+func Summarize(r reader.Reader) (*Summary, error) {
+	s := &Summary{
+		Users:      make(map[string]*Totals),
+		Operations: make(map[string]*Totals),
+	}
+
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = s.add(e); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Summary) add(e reader.Event) error {
+	timestamp, err := e.Timestamp()
+	if err != nil {
+		return err
+	}
+	username, err := e.Username()
+	if err != nil {
+		return err
+	}
+	operation, err := e.Operation()
+	if err != nil {
+		return err
+	}
+	size, err := e.Size()
+	if err != nil {
+		return err
+	}
+
+	if s.Events == 0 {
+		s.First, s.Last = timestamp, timestamp
+		s.MinSize, s.MaxSize = size, size
+	}
+	if timestamp.Before(s.First) {
+		s.First = timestamp
+	}
+	if timestamp.After(s.Last) {
+		s.Last = timestamp
+	}
+	if size < s.MinSize {
+		s.MinSize = size
+	}
+	if size > s.MaxSize {
+		s.MaxSize = size
+	}
+	s.Totals.add(size)
+
+	if s.Users[username] == nil {
+		s.Users[username] = &Totals{}
+	}
+	s.Users[username].add(size)
+
+	if s.Operations[operation] == nil {
+		s.Operations[operation] = &Totals{}
+	}
+	s.Operations[operation].add(size)
+	return nil
+}
+
+// Fprint writes s to w as an aligned table listing at most top users.
+func (s *Summary) Fprint(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "events:\t%d\n", s.Events)
+	if s.Events == 0 {
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "first:\t%s\n", s.First.Format(time.UnixDate))
+	fmt.Fprintf(tw, "last:\t%s\n", s.Last.Format(time.UnixDate))
+	fmt.Fprintf(tw, "users:\t%d\n", len(s.Users))
+	fmt.Fprintf(tw, "size:\ttotal %s, min %s, mean %s, max %s\n", s.Size, s.MinSize, s.MeanSize(), s.MaxSize)
+
+	fmt.Fprintf(tw, "\noperation\tevents\tsize\n")
+	for _, op := range Rank(s.Operations) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", op.Name, op.Events, op.Size)
+	}
+
+	fmt.Fprintf(tw, "\nuser\tevents\tsize\n")
+	for i, user := range Rank(s.Users) {
+		if i == top {
+			fmt.Fprintf(tw, "(%d more)\n", len(s.Users)-top)
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", user.Name, user.Events, user.Size)
+	}
+	return tw.Flush()
+}
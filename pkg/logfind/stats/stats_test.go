@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"bytes"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+Sun Apr 12 22:49:47 UTC 2020,Maia86,upload,9
+Sun Apr 12 21:23:52 UTC 2020,jeff22,download,25
+`
+
+func TestSummarize(t *testing.T) {
+	t.Run("summarizes events", func(t *testing.T) {
+		s, err := Summarize(csv.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, Totals{Events: 4, Size: 143 * reader.KB}, s.Totals)
+		assert.Equal(t, time.Date(2020, 04, 12, 21, 23, 52, 0, time.UTC), s.First)
+		assert.Equal(t, time.Date(2020, 04, 12, 22, 49, 47, 0, time.UTC), s.Last)
+		assert.Equal(t, 9*reader.KB, s.MinSize)
+		assert.Equal(t, 75*reader.KB, s.MaxSize)
+		assert.Equal(t, reader.Size(35750), s.MeanSize())
+		assert.Equal(t, map[string]*Totals{
+			"sarah94": {Events: 1, Size: 34 * reader.KB},
+			"Maia86":  {Events: 2, Size: 84 * reader.KB},
+			"jeff22":  {Events: 1, Size: 25 * reader.KB},
+		}, s.Users)
+		assert.Equal(t, map[string]*Totals{
+			"download": {Events: 3, Size: 134 * reader.KB},
+			"upload":   {Events: 1, Size: 9 * reader.KB},
+		}, s.Operations)
+	})
+
+	t.Run("fails on malformed records", func(t *testing.T) {
+		s, err := Summarize(csv.NewReader(strings.NewReader("yesterday,sarah94,download,34\n")))
+		assert.Error(t, err)
+		assert.Nil(t, s)
+	})
+}
+
+func TestRank(t *testing.T) {
+	ranked := Rank(map[string]*Totals{
+		"sarah94": {Events: 1, Size: 34},
+		"Maia86":  {Events: 2, Size: 84},
+		"jeff22":  {Events: 1, Size: 25},
+	})
+	assert.Equal(t, []Ranked{
+		{Name: "Maia86", Totals: Totals{Events: 2, Size: 84}},
+		{Name: "jeff22", Totals: Totals{Events: 1, Size: 25}},
+		{Name: "sarah94", Totals: Totals{Events: 1, Size: 34}},
+	}, ranked)
+}
+
+func TestSummary_Fprint(t *testing.T) {
+	s, err := Summarize(csv.NewReader(strings.NewReader(input)))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, s.Fprint(&buf, 2))
+	assert.Equal(t, `events:  4
+first:   Sun Apr 12 21:23:52 UTC 2020
+last:    Sun Apr 12 22:49:47 UTC 2020
+users:   3
+size:    total 143kB, min 9kB, mean 35.75kB, max 75kB
+
+operation  events  size
+download   3       134kB
+upload     1       9kB
+
+user    events  size
+Maia86  2       84kB
+jeff22  1       25kB
+(1 more)
+`, buf.String())
+}
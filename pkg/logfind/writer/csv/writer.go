@@ -0,0 +1,87 @@
+package csv
+
+import (
+	"encoding/csv"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"io"
+	"strconv"
+	"time"
+)
+
+type writer struct {
+	csvWriter   *csv.Writer
+	sizeUnit    lfReader.Size
+	wroteHeader bool
+}
+
+// WriterOptionFunc customizes a writer created by NewWriter.
+type WriterOptionFunc func(*writer)
+
+// WithSizeUnit sets the unit in which the size column is written. The default is lfReader.KB.
+// Sizes that are not a whole number of units are written as decimals, e.g., 1.5 for 1500 bytes in kB.
+func WithSizeUnit(unit lfReader.Size) WriterOptionFunc {
+	return func(w *writer) {
+		w.sizeUnit = unit
+	}
+}
+
+// NewWriter returns a lfWriter.Writer that writes events to w in the format read by the csv reader,
+// i.e., a header followed by timestamp, username, operation and size columns.
+func NewWriter(w io.Writer, opts ...WriterOptionFunc) lfWriter.Writer {
+	wr := &writer{
+		csvWriter: csv.NewWriter(w),
+		sizeUnit:  lfReader.KB,
+	}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	return wr
+}
+
+func (w *writer) Write(event lfReader.Event) error {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		err := w.csvWriter.Write([]string{lfReader.FieldTimestamp, lfReader.FieldUsername, lfReader.FieldOperation, lfReader.FieldSize})
+		if err != nil {
+			return err
+		}
+	}
+
+	timestamp, err := event.Timestamp()
+	if err != nil {
+		return err
+	}
+	username, err := event.Username()
+	if err != nil {
+		return err
+	}
+	operation, err := event.Operation()
+	if err != nil {
+		return err
+	}
+	size, err := event.Size()
+	if err != nil {
+		return err
+	}
+
+	return w.csvWriter.Write([]string{
+		timestamp.Format(time.UnixDate),
+		username,
+		operation,
+		w.formatSize(size),
+	})
+}
+
+// formatSize writes size in the size unit, exactly when it is a whole number of units.
+func (w *writer) formatSize(size lfReader.Size) string {
+	if size%w.sizeUnit == 0 {
+		return strconv.FormatInt(int64(size/w.sizeUnit), 10)
+	}
+	return strconv.FormatFloat(float64(size)/float64(w.sizeUnit), 'f', -1, 64)
+}
+
+func (w *writer) Flush() error {
+	w.csvWriter.Flush()
+	return w.csvWriter.Error()
+}
@@ -0,0 +1,45 @@
+package csv
+
+import (
+	"bytes"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	csvReader "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,Maia86,download,75
+`
+
+func TestNewWriter(t *testing.T) {
+	t.Run("round trips the csv reader", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := lfWriter.Copy(NewWriter(&buf), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, input, buf.String())
+	})
+
+	t.Run("respects size unit", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := lfWriter.Copy(NewWriter(&buf, WithSizeUnit(lfReader.Byte)), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "sarah94,download,34000\n")
+	})
+	t.Run("writes sizes that are not whole units as decimals", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := lfWriter.Copy(NewWriter(&buf, WithSizeUnit(lfReader.KiB)), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "sarah94,download,33.203125\n")
+
+		in := strings.Replace(input, ",34\n", ",0.4\n", 1)
+		buf.Reset()
+		_, err = lfWriter.Copy(NewWriter(&buf), csvReader.NewReader(strings.NewReader(in)))
+		assert.NoError(t, err)
+		assert.Equal(t, in, buf.String())
+	})
+}
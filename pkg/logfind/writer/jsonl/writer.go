@@ -0,0 +1,63 @@
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"io"
+	"time"
+)
+
+// Event is the JSON representation of a lfReader.Event written on each line.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Username  string    `json:"username"`
+	Operation string    `json:"operation"`
+	// Size is in bytes.
+	Size int64 `json:"size"`
+	// Labels are the extra fields of events that have them, see lfReader.Labeler.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type writer struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewWriter returns a lfWriter.Writer that writes one JSON object per line to w. See Event for the format.
+func NewWriter(w io.Writer) lfWriter.Writer {
+	bw := bufio.NewWriter(w)
+	return &writer{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+func (w *writer) Write(event lfReader.Event) (err error) {
+	var e Event
+	if e.Timestamp, err = event.Timestamp(); err != nil {
+		return
+	}
+	if e.Username, err = event.Username(); err != nil {
+		return
+	}
+	if e.Operation, err = event.Operation(); err != nil {
+		return
+	}
+	size, err := event.Size()
+	if err != nil {
+		return
+	}
+	e.Size = int64(size)
+	if l, ok := event.(lfReader.Labeler); ok {
+		if labels := l.Labels(); len(labels) > 0 {
+			e.Labels = labels
+		}
+	}
+	return w.enc.Encode(e)
+}
+
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,33 @@
+package jsonl
+
+import (
+	"bytes"
+	csvReader "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	logfmtReader "github.com/kyleishie/logfind/pkg/logfind/reader/logfmt"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewWriter(t *testing.T) {
+	t.Run("writes one object per line", func(t *testing.T) {
+		input := "Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34\nSun Apr 12 22:35:06 UTC 2020,Maia86,upload,75\n"
+		var buf bytes.Buffer
+		n, err := lfWriter.Copy(NewWriter(&buf), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, `{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","operation":"download","size":34000}
+{"timestamp":"2020-04-12T22:35:06Z","username":"Maia86","operation":"upload","size":75000}
+`, buf.String())
+	})
+
+	t.Run("writes labels", func(t *testing.T) {
+		input := "ts=2020-04-12T22:10:38Z username=sarah94 operation=download size=34000 host=web1\n"
+		var buf bytes.Buffer
+		_, err := lfWriter.Copy(NewWriter(&buf), logfmtReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"timestamp":"2020-04-12T22:10:38Z","username":"sarah94","operation":"download","size":34000,"labels":{"host":"web1"}}
+`, buf.String())
+	})
+}
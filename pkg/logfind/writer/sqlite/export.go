@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"os/exec"
+	"strings"
+)
+
+const (
+	ErrSQLite3Missing = lfReader.Error("sqlite3 is not installed")
+)
+
+// Export inserts the events of r into the SQLite database at path, creating it if needed, by piping the script of
+// NewWriter into the sqlite3 command-line tool, which must be on the PATH. Should r fail, the events of the open
+// transaction are dropped, but the batches committed before are inserted.
+func Export(path string, r lfReader.Reader, opts ...WriterOptionFunc) (int, error) {
+	sqlite3, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrSQLite3Missing, err)
+	}
+
+	cmd := exec.Command(sqlite3, "-bail", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err = cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	w := newWriter(stdin, opts...)
+	n, copyErr := lfWriter.Copy(w, r)
+	if copyErr != nil {
+		// Copy does not flush when r fails, which would leave committed batches behind in the buffer.
+		w.w.Flush()
+	}
+	stdin.Close()
+	if err = cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return n, fmt.Errorf("sqlite3: %w: %s", err, msg)
+		}
+		return n, fmt.Errorf("sqlite3: %w", err)
+	}
+	return n, copyErr
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"errors"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	csvReader "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	"github.com/stretchr/testify/assert"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	t.Run("fails when sqlite3 is not installed", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		_, err := Export(filepath.Join(t.TempDir(), "out.db"), csvReader.NewReader(strings.NewReader(input)))
+		assert.ErrorIs(t, err, ErrSQLite3Missing)
+	})
+
+	sqlite3, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+
+	t.Run("inserts into the database", func(t *testing.T) {
+		db := filepath.Join(t.TempDir(), "out.db")
+		n, err := Export(db, csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		// Exporting again appends to the existing table.
+		n, err = Export(db, csvReader.NewReader(strings.NewReader(input)), WithIndexes())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		out, err := exec.Command(sqlite3, db, "SELECT username, COUNT(*), SUM(size) FROM events GROUP BY username ORDER BY username").CombinedOutput()
+		assert.NoError(t, err)
+		assert.Equal(t, "O'Brien|2|150000\nsarah94|2|68000\n", string(out))
+	})
+
+	t.Run("inserts the batches committed before the reader fails", func(t *testing.T) {
+		db := filepath.Join(t.TempDir(), "out.db")
+		in := input + "Sun Apr 12 22:49:47 UTC 2020,Maia86,upload,9\n"
+		n, err := Export(db, &failingReader{r: csvReader.NewReader(strings.NewReader(in)), after: 3}, WithBatchSize(2))
+		assert.ErrorIs(t, err, errRead)
+		assert.Equal(t, 3, n)
+
+		out, err := exec.Command(sqlite3, db, "SELECT username FROM events ORDER BY timestamp").CombinedOutput()
+		assert.NoError(t, err)
+		assert.Equal(t, "sarah94\nO'Brien\n", string(out))
+	})
+
+	t.Run("reports errors of sqlite3", func(t *testing.T) {
+		_, err := Export(t.TempDir(), csvReader.NewReader(strings.NewReader(input)))
+		assert.ErrorContains(t, err, "sqlite3: ")
+	})
+}
+
+var errRead = errors.New("read failed")
+
+// failingReader fails after reading a number of events from r.
+type failingReader struct {
+	r     lfReader.Reader
+	after int
+}
+
+func (f *failingReader) Read() (lfReader.Event, error) {
+	if f.after == 0 {
+		return nil, errRead
+	}
+	f.after--
+	return f.r.Read()
+}
@@ -0,0 +1,167 @@
+// Package sqlite writes events as a SQLite script that creates a typed table and inserts the events into it,
+// e.g., to be run with `sqlite3 out.db < events.sql`.
+package sqlite
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	lfReader "github.com/kyleishie/logfind/pkg/logfind/reader"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"io"
+	"strings"
+	"time"
+)
+
+// TimestampFormat is how the timestamp column is stored.
+type TimestampFormat string
+
+const (
+	// TimestampISO stores timestamps as ISO 8601 text in UTC, e.g., 2020-04-15T03:06:46Z, which SQLite's date and
+	// time functions understand.
+	TimestampISO = TimestampFormat("iso")
+	// TimestampUnix stores timestamps as integer seconds since the Unix epoch.
+	TimestampUnix = TimestampFormat("unix")
+)
+
+// DefaultBatchSize is the number of events inserted per transaction unless changed with WithBatchSize.
+const DefaultBatchSize = 10000
+
+type writer struct {
+	w *bufio.Writer
+	// tx holds the statements of the open transaction until it is committed, so that w only ever holds whole ones.
+	tx bytes.Buffer
+
+	table     string
+	timestamp TimestampFormat
+	indexes   bool
+	batchSize int
+
+	created bool
+	// pending is the number of events inserted by the open transaction.
+	pending int
+}
+
+// WriterOptionFunc customizes a writer created by NewWriter.
+type WriterOptionFunc func(*writer)
+
+// WithTable sets the name of the table. The default is events.
+func WithTable(name string) WriterOptionFunc {
+	return func(w *writer) {
+		w.table = name
+	}
+}
+
+// WithTimestampFormat sets how the timestamp column is stored. The default is TimestampISO.
+func WithTimestampFormat(format TimestampFormat) WriterOptionFunc {
+	return func(w *writer) {
+		w.timestamp = format
+	}
+}
+
+// WithIndexes creates indexes on the username and timestamp columns once the events are inserted.
+func WithIndexes() WriterOptionFunc {
+	return func(w *writer) {
+		w.indexes = true
+	}
+}
+
+// WithBatchSize sets the number of events inserted per transaction.
+func WithBatchSize(n int) WriterOptionFunc {
+	return func(w *writer) {
+		w.batchSize = n
+	}
+}
+
+// NewWriter returns a lfWriter.Writer that writes a SQLite script to w. The table is created, if it does not exist,
+// with timestamp, username, operation and size columns, the size being an integer number of bytes. Events are
+// inserted in transactions of a batch of events, and Flush commits the open transaction and creates any indexes.
+func NewWriter(w io.Writer, opts ...WriterOptionFunc) lfWriter.Writer {
+	return newWriter(w, opts...)
+}
+
+func newWriter(w io.Writer, opts ...WriterOptionFunc) *writer {
+	wr := &writer{
+		w:         bufio.NewWriter(w),
+		table:     "events",
+		timestamp: TimestampISO,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	return wr
+}
+
+func (w *writer) Write(event lfReader.Event) error {
+	rec, err := lfReader.NewRecord(event)
+	if err != nil {
+		return err
+	}
+
+	w.create()
+	if w.pending == 0 {
+		w.tx.WriteString("BEGIN;\n")
+	}
+	fmt.Fprintf(&w.tx, "INSERT INTO %s VALUES (%s, %s, %s, %d);\n", identifier(w.table), w.timestampValue(rec.Time), literal(rec.User), literal(rec.Op), int64(rec.Bytes))
+	w.pending++
+	if w.pending >= w.batchSize {
+		w.commit()
+	}
+	return nil
+}
+
+func (w *writer) Flush() error {
+	w.create()
+	w.commit()
+	if w.indexes {
+		fmt.Fprintf(w.w, "CREATE INDEX IF NOT EXISTS %s ON %s (%s);\n", identifier(w.table+"_"+lfReader.FieldUsername), identifier(w.table), lfReader.FieldUsername)
+		fmt.Fprintf(w.w, "CREATE INDEX IF NOT EXISTS %s ON %s (%s);\n", identifier(w.table+"_"+lfReader.FieldTimestamp), identifier(w.table), lfReader.FieldTimestamp)
+	}
+	return w.w.Flush()
+}
+
+// create writes the CREATE TABLE statement unless it has been written.
+func (w *writer) create() {
+	if w.created {
+		return
+	}
+	w.created = true
+
+	timestampType := "TEXT"
+	if w.timestamp == TimestampUnix {
+		timestampType = "INTEGER"
+	}
+	fmt.Fprintf(w.w, "CREATE TABLE IF NOT EXISTS %s (\n", identifier(w.table))
+	fmt.Fprintf(w.w, "  %s %s NOT NULL,\n", lfReader.FieldTimestamp, timestampType)
+	fmt.Fprintf(w.w, "  %s TEXT NOT NULL,\n", lfReader.FieldUsername)
+	fmt.Fprintf(w.w, "  %s TEXT NOT NULL,\n", lfReader.FieldOperation)
+	fmt.Fprintf(w.w, "  %s INTEGER NOT NULL\n);\n", lfReader.FieldSize)
+}
+
+// commit ends the open transaction, if any, and moves it to w.
+func (w *writer) commit() {
+	if w.pending > 0 {
+		w.tx.WriteString("COMMIT;\n")
+		w.w.Write(w.tx.Bytes())
+		w.tx.Reset()
+		w.pending = 0
+	}
+}
+
+func (w *writer) timestampValue(t time.Time) string {
+	if w.timestamp == TimestampUnix {
+		return fmt.Sprint(t.Unix())
+	}
+	return literal(t.UTC().Format(time.RFC3339Nano))
+}
+
+// literal quotes s as a SQL string.
+func literal(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// identifier quotes name as a SQL identifier.
+func identifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
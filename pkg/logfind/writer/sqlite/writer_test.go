@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"bytes"
+	csvReader "github.com/kyleishie/logfind/pkg/logfind/reader/csv"
+	lfWriter "github.com/kyleishie/logfind/pkg/logfind/writer"
+	"github.com/stretchr/testify/assert"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const input = `timestamp,username,operation,size
+Sun Apr 12 22:10:38 UTC 2020,sarah94,download,34
+Sun Apr 12 22:35:06 UTC 2020,O'Brien,upload,75
+`
+
+func TestNewWriter(t *testing.T) {
+	t.Run("creates the table and inserts in a transaction", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := lfWriter.Copy(NewWriter(&buf), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, `CREATE TABLE IF NOT EXISTS "events" (
+  timestamp TEXT NOT NULL,
+  username TEXT NOT NULL,
+  operation TEXT NOT NULL,
+  size INTEGER NOT NULL
+);
+BEGIN;
+INSERT INTO "events" VALUES ('2020-04-12T22:10:38Z', 'sarah94', 'download', 34000);
+INSERT INTO "events" VALUES ('2020-04-12T22:35:06Z', 'O''Brien', 'upload', 75000);
+COMMIT;
+`, buf.String())
+	})
+
+	t.Run("creates the table when there are no events", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, NewWriter(&buf).Flush())
+		assert.True(t, strings.HasPrefix(buf.String(), `CREATE TABLE IF NOT EXISTS "events"`))
+		assert.NotContains(t, buf.String(), "BEGIN")
+	})
+
+	t.Run("respects batch size", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := lfWriter.Copy(NewWriter(&buf, WithBatchSize(1)), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(buf.String(), "BEGIN;\n"))
+		assert.Equal(t, 2, strings.Count(buf.String(), "COMMIT;\n"))
+	})
+
+	t.Run("respects table, timestamp format and indexes", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, WithTable("logs"), WithTimestampFormat(TimestampUnix), WithIndexes())
+		_, err := lfWriter.Copy(w, csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "timestamp INTEGER NOT NULL")
+		assert.Contains(t, buf.String(), `INSERT INTO "logs" VALUES (1586729438, 'sarah94', 'download', 34000);`)
+		assert.True(t, strings.HasSuffix(buf.String(), `COMMIT;
+CREATE INDEX IF NOT EXISTS "logs_username" ON "logs" (username);
+CREATE INDEX IF NOT EXISTS "logs_timestamp" ON "logs" (timestamp);
+`))
+	})
+
+	t.Run("runs in sqlite3", func(t *testing.T) {
+		sqlite3, err := exec.LookPath("sqlite3")
+		if err != nil {
+			t.Skip("sqlite3 is not installed")
+		}
+
+		var buf bytes.Buffer
+		_, err = lfWriter.Copy(NewWriter(&buf, WithIndexes()), csvReader.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+
+		db := filepath.Join(t.TempDir(), "out.db")
+		cmd := exec.Command(sqlite3, "-bail", db)
+		cmd.Stdin = &buf
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+
+		out, err = exec.Command(sqlite3, db, "SELECT username, SUM(size) FROM events WHERE timestamp >= '2020-04-12T22:30:00Z' GROUP BY username").CombinedOutput()
+		assert.NoError(t, err)
+		assert.Equal(t, "O'Brien|75000\n", string(out))
+	})
+}
@@ -0,0 +1,74 @@
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"io"
+	"time"
+)
+
+// Writer represents an object that can serialize events to an output stream.
+type Writer interface {
+	Write(event reader.Event) error
+	// Flush writes any buffered data to the underlying output stream.
+	Flush() error
+}
+
+// Copy writes every event of r to w until r returns io.EOF, then flushes w.
+// It returns the number of events written and the first error encountered.
+func Copy(w Writer, r reader.Reader) (n int, err error) {
+	for {
+		var e reader.Event
+		e, err = r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		if err = w.Write(e); err != nil {
+			return
+		}
+		n++
+	}
+	err = w.Flush()
+	return
+}
+
+type textWriter struct {
+	w *bufio.Writer
+}
+
+// NewTextWriter returns a Writer that writes one event per line in the same format as the events returned by
+// logfind.Finder, e.g., "Sun Apr 12 22:10:38 UTC 2020 sarah94 download 34kB".
+func NewTextWriter(w io.Writer) Writer {
+	return &textWriter{
+		w: bufio.NewWriter(w),
+	}
+}
+
+func (t *textWriter) Write(event reader.Event) error {
+	timestamp, err := event.Timestamp()
+	if err != nil {
+		return err
+	}
+	username, err := event.Username()
+	if err != nil {
+		return err
+	}
+	operation, err := event.Operation()
+	if err != nil {
+		return err
+	}
+	size, err := event.Size()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(t.w, "%s %s %s %s\n", timestamp.Format(time.UnixDate), username, operation, size)
+	return err
+}
+
+func (t *textWriter) Flush() error {
+	return t.w.Flush()
+}
@@ -0,0 +1,47 @@
+package writer
+
+import (
+	"bytes"
+	"github.com/kyleishie/logfind/pkg/logfind/reader"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	timestamp time.Time
+	username  string
+	operation string
+	size      reader.Size
+}
+
+func (e testEvent) Timestamp() (time.Time, error) { return e.timestamp, nil }
+func (e testEvent) Username() (string, error)     { return e.username, nil }
+func (e testEvent) Operation() (string, error)    { return e.operation, nil }
+func (e testEvent) Size() (reader.Size, error)    { return e.size, nil }
+
+type testReader []testEvent
+
+func (r *testReader) Read() (reader.Event, error) {
+	if len(*r) == 0 {
+		return nil, io.EOF
+	}
+	e := (*r)[0]
+	*r = (*r)[1:]
+	return e, nil
+}
+
+func TestCopy(t *testing.T) {
+	t.Run("writes every event as text", func(t *testing.T) {
+		r := &testReader{
+			{timestamp: time.Date(2020, 04, 12, 22, 10, 38, 0, time.UTC), username: "sarah94", operation: "download", size: 34 * reader.KB},
+			{timestamp: time.Date(2020, 04, 12, 22, 35, 06, 0, time.UTC), username: "Maia86", operation: "upload", size: 1500 * reader.KB},
+		}
+		var buf bytes.Buffer
+		n, err := Copy(NewTextWriter(&buf), r)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, "Sun Apr 12 22:10:38 UTC 2020 sarah94 download 34kB\nSun Apr 12 22:35:06 UTC 2020 Maia86 upload 1.5MB\n", buf.String())
+	})
+}